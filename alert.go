@@ -0,0 +1,91 @@
+package sitemapsplitter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+)
+
+// CountAlert guards against publishing output whose URL count differs
+// sharply from the previous run, as happens when an upstream export breaks
+type CountAlert struct {
+	MaxChange float64 // Largest tolerated change in percent of the previous count
+	Webhook   string  // URL receiving a JSON POST when the change is exceeded, empty for none
+	Continue  bool    // Publish anyway after notifying instead of failing the run
+}
+
+// CountAlertEvent is the JSON body posted to the webhook of a CountAlert
+type CountAlertEvent struct {
+	Input    string  `json:"input"`
+	Previous int     `json:"previous"`
+	Current  int     `json:"current"`
+	Change   float64 `json:"change_percent"`
+	Limit    float64 `json:"limit_percent"`
+}
+
+// checkCount compares the number of URLs about to be published with the
+// previous run's count and raises the configured alert when the change is
+// too large. Runs without a previous count are not checked.
+func (s *SitemapSplitter) checkCount(prev *runState, count int) error {
+	if s.countAlert == nil {
+		return nil
+	}
+	previous := prev.Count
+	if previous == 0 {
+		// State files written before the count was recorded
+		previous = len(prev.URLs)
+	}
+	if previous == 0 {
+		return nil
+	}
+
+	change := math.Abs(float64(count-previous)) / float64(previous) * 100
+	if change <= s.countAlert.MaxChange {
+		return nil
+	}
+	s.result.CountAlerted = true
+	s.logger.Warn("URL count changed sharply", "phase", "filter", "previous", previous, "current", count, "change_percent", change)
+
+	event := CountAlertEvent{
+		Input:    s.path,
+		Previous: previous,
+		Current:  count,
+		Change:   change,
+		Limit:    s.countAlert.MaxChange,
+	}
+	if s.countAlert.Webhook != "" && !s.dryRun {
+		if err := s.postAlert(event); err != nil {
+			return err
+		}
+	}
+	if s.countAlert.Continue {
+		return nil
+	}
+	return fmt.Errorf("URL count changed by %.1f%% (from %d to %d), exceeding the limit of %.1f%%", change, previous, count, s.countAlert.MaxChange)
+}
+
+// postAlert sends event to the alert webhook
+func (s *SitemapSplitter) postAlert(event CountAlertEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("error encoding alert: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.countAlert.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error sending alert: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.doRequest(req)
+	if err != nil {
+		return fmt.Errorf("error sending alert: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("error sending alert to %s: %s", s.countAlert.Webhook, resp.Status)
+	}
+	return nil
+}
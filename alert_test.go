@@ -0,0 +1,115 @@
+package sitemapsplitter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCountAlertWithDuplicates(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.xml")
+	sitemap := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` +
+		`<url><loc>https://example.com/a</loc></url>` +
+		`<url><loc>https://example.com/a</loc></url>` +
+		`<url><loc>https://example.com/b</loc></url></urlset>`
+	if err := os.WriteFile(input, []byte(sitemap), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewSitemapSplitter(input, 10, WithOutputDir(filepath.Join(dir, "out")),
+		WithStateFile(filepath.Join(dir, "state.json")), WithCountAlert(CountAlert{MaxChange: 10}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for run := 1; run <= 2; run++ {
+		if err := s.Split(); err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+		if r := s.LastResult(); r.CountAlerted || r.URLs != 3 {
+			t.Errorf("run %d: alerted %v with %d URLs, want no alert with 3", run, r.CountAlerted, r.URLs)
+		}
+	}
+}
+
+func TestCountAlert(t *testing.T) {
+	var (
+		posts  []CountAlertEvent
+		status = http.StatusNoContent
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("webhook got %s with content type %q, want a JSON POST", r.Method, r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		var event CountAlertEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Errorf("webhook body %s: %v", body, err)
+		}
+		posts = append(posts, event)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		alert      CountAlert
+		status     int
+		wantErr    bool
+		keepsFirst bool // Whether the first run's output is still published
+	}{
+		{"within limit", CountAlert{MaxChange: 60, Webhook: srv.URL}, http.StatusNoContent, false, false},
+		{"breach fails the run", CountAlert{MaxChange: 20}, http.StatusNoContent, true, true},
+		{"breach with continue", CountAlert{MaxChange: 20, Continue: true}, http.StatusNoContent, false, false},
+		{"webhook", CountAlert{MaxChange: 20, Webhook: srv.URL}, http.StatusNoContent, true, true},
+		{"webhook with continue", CountAlert{MaxChange: 20, Webhook: srv.URL, Continue: true}, http.StatusNoContent, false, false},
+		{"failing webhook", CountAlert{MaxChange: 20, Webhook: srv.URL, Continue: true}, http.StatusInternalServerError, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			posts, status = nil, tt.status
+			dir := t.TempDir()
+			input, out := filepath.Join(dir, "in.xml"), filepath.Join(dir, "out")
+			s, err := NewSitemapSplitter(input, 50, WithOutputDir(out),
+				WithStateFile(filepath.Join(dir, "state.json")), WithCountAlert(tt.alert))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			locs := make([]string, 10)
+			for i := range locs {
+				locs[i] = fmt.Sprintf("https://example.com/%d", i)
+			}
+			writeSitemap(t, input, locs...)
+			if err := s.Split(); err != nil {
+				t.Fatal(err)
+			}
+			writeSitemap(t, input, locs[:5]...)
+			err = s.Split()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("second run error %v, want error %v", err, tt.wantErr)
+			}
+
+			breach := tt.alert.MaxChange < 50
+			if got := s.LastResult().CountAlerted; got != breach {
+				t.Errorf("alerted %v, want %v", got, breach)
+			}
+			if got := fileContains(filepath.Join(out, "in-1.xml"), locs[9]); got != tt.keepsFirst {
+				t.Errorf("first run's output still published %v, want %v", got, tt.keepsFirst)
+			}
+
+			var want []CountAlertEvent
+			if breach && tt.alert.Webhook != "" {
+				want = []CountAlertEvent{{Input: input, Previous: 10, Current: 5, Change: 50, Limit: 20}}
+			}
+			if fmt.Sprint(posts) != fmt.Sprint(want) {
+				t.Errorf("webhook received %+v, want %+v", posts, want)
+			}
+		})
+	}
+}
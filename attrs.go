@@ -0,0 +1,73 @@
+package sitemapsplitter
+
+import "encoding/xml"
+
+// xmlNamespace is the namespace bound to the reserved xml prefix, as in
+// xml:lang
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+// nsScope maps namespace URIs to the prefixes the input declared for them
+type nsScope map[string]string
+
+// declare returns sc extended by the namespace declarations among attrs,
+// leaving sc itself untouched
+func (sc nsScope) declare(attrs []xml.Attr) nsScope {
+	var ext nsScope
+	for _, attr := range attrs {
+		if attr.Name.Space != "xmlns" {
+			continue
+		}
+		if ext == nil {
+			ext = make(nsScope, len(sc)+1)
+			for uri, prefix := range sc {
+				ext[uri] = prefix
+			}
+		}
+		ext[attr.Value] = attr.Name.Local
+	}
+	if ext == nil {
+		return sc
+	}
+	return ext
+}
+
+// prefixAttrs returns the attributes of an input element ready to be written
+// back as found. Namespace declarations are dropped, attributes of a
+// namespace in sc are named prefix:local after a declaration of the prefix on
+// the same element, and xml: attributes keep their reserved prefix.
+// Attributes of namespaces missing from sc keep their resolved name, so that
+// a wider scope can still be applied later; if none is, the encoder declares
+// a prefix of its own for them.
+func prefixAttrs(attrs []xml.Attr, sc nsScope) []xml.Attr {
+	var out []xml.Attr
+	for _, attr := range attrs {
+		switch {
+		case attr.Name.Space == "xmlns", attr.Name.Space == "" && attr.Name.Local == "xmlns":
+			continue
+		case attr.Name.Space == xmlNamespace:
+			attr.Name = xml.Name{Local: "xml:" + attr.Name.Local}
+		case attr.Name.Space != "":
+			if prefix, ok := sc[attr.Name.Space]; ok && declarePrefix(&out, attrs, prefix, attr.Name.Space) {
+				attr.Name = xml.Name{Local: prefix + ":" + attr.Name.Local}
+			}
+		}
+		out = append(out, attr)
+	}
+	return out
+}
+
+// declarePrefix makes sure the element being built in out declares prefix
+// as uri, reporting false if out or the element's original attrs already
+// bind prefix to another namespace
+func declarePrefix(out *[]xml.Attr, attrs []xml.Attr, prefix, uri string) bool {
+	decl := xml.Name{Local: "xmlns:" + prefix}
+	for _, list := range [][]xml.Attr{*out, attrs} {
+		for _, attr := range list {
+			if attr.Name == decl {
+				return attr.Value == uri
+			}
+		}
+	}
+	*out = append(*out, xml.Attr{Name: decl, Value: uri})
+	return true
+}
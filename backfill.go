@@ -0,0 +1,129 @@
+package sitemapsplitter
+
+import (
+	"net/url"
+	"path"
+	"time"
+)
+
+// backfillKind identifies the source a BackfillRule takes lastmod values from
+type backfillKind int
+
+const (
+	backfillSiblings backfillKind = iota
+	backfillFunc
+	backfillDefault
+	backfillSourceModTime
+)
+
+// BackfillRule supplies lastmod values for URLs that have none. Rules are
+// created with BackfillFromSiblings, BackfillFromFunc and BackfillDefault.
+type BackfillRule struct {
+	kind   backfillKind
+	lookup func(loc string) (string, bool)
+	value  time.Time
+}
+
+// BackfillFromSiblings takes the newest lastmod of the URLs sharing the same
+// parent path, e.g. /blog/b inherits from /blog/a
+func BackfillFromSiblings() BackfillRule {
+	return BackfillRule{kind: backfillSiblings}
+}
+
+// BackfillFromFunc asks fn for the lastmod of a loc. fn reports false when it
+// has no value, letting the next rule try.
+func BackfillFromFunc(fn func(loc string) (string, bool)) BackfillRule {
+	return BackfillRule{kind: backfillFunc, lookup: fn}
+}
+
+// BackfillFromSourceModTime uses the modification time of the file a URL was
+// read from (or of its archive member), which keeps freshness signals roughly
+// honest instead of omitting lastmod or faking the current time
+func BackfillFromSourceModTime() BackfillRule {
+	return BackfillRule{kind: backfillSourceModTime}
+}
+
+// BackfillDefault uses t for every URL still lacking a lastmod
+func BackfillDefault(t time.Time) BackfillRule {
+	return BackfillRule{kind: backfillDefault, value: t}
+}
+
+// backfillLastMod fills missing lastmod values using the configured rules,
+// trying them in order for each URL
+func (s *SitemapSplitter) backfillLastMod(urls []URL) {
+	if len(s.backfillRules) == 0 {
+		return
+	}
+
+	var siblings map[string]string
+	for _, rule := range s.backfillRules {
+		if rule.kind == backfillSiblings {
+			siblings = s.newestByParent(urls)
+			break
+		}
+	}
+
+	for i := range urls {
+		if urls[i].LastMod != "" {
+			continue
+		}
+		for _, rule := range s.backfillRules {
+			if value, ok := s.applyBackfill(rule, urls[i], siblings); ok {
+				urls[i].LastMod = value
+				s.result.LastModBackfilled++
+				break
+			}
+		}
+	}
+}
+
+// applyBackfill returns the lastmod rule supplies for u
+func (s *SitemapSplitter) applyBackfill(rule BackfillRule, u URL, siblings map[string]string) (string, bool) {
+	switch rule.kind {
+	case backfillSiblings:
+		value, ok := siblings[parentPath(u.Loc)]
+		return value, ok
+	case backfillFunc:
+		value, ok := rule.lookup(u.Loc)
+		return value, ok && value != ""
+	case backfillDefault:
+		return s.formatTime(rule.value.In(s.location)), true
+	case backfillSourceModTime:
+		if u.sourceModTime.IsZero() {
+			return "", false
+		}
+		return s.formatTime(u.sourceModTime.In(s.location)), true
+	}
+	return "", false
+}
+
+// newestByParent maps each parent path to the newest lastmod among its URLs
+func (s *SitemapSplitter) newestByParent(urls []URL) map[string]string {
+	newest := make(map[string]string)
+	times := make(map[string]time.Time)
+	for _, u := range urls {
+		if u.LastMod == "" {
+			continue
+		}
+		t, ok := parseLastMod(u.LastMod, s.location)
+		if !ok {
+			continue
+		}
+		parent := parentPath(u.Loc)
+		if prev, seen := times[parent]; !seen || t.After(prev) {
+			times[parent] = t
+			newest[parent] = u.LastMod
+		}
+	}
+	return newest
+}
+
+// parentPath returns loc's origin and parent directory, the key that groups
+// sibling URLs
+func parentPath(loc string) string {
+	parsed, err := url.Parse(loc)
+	if err != nil {
+		return ""
+	}
+	return parsed.Scheme + "://" + parsed.Host + path.Dir(parsed.Path)
+}
@@ -0,0 +1,183 @@
+package sitemapsplitter
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLastModBackfill(t *testing.T) {
+	input := func() []URL {
+		return []URL{
+			{Loc: "https://example.com/blog/a", LastMod: "2024-05-01"},
+			{Loc: "https://example.com/blog/b", LastMod: "2024-06-01"},
+			{Loc: "https://example.com/blog/c"},
+			{Loc: "https://example.com/shop/d"},
+			{Loc: "https://example.com/shop/e", sourceModTime: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)},
+		}
+	}
+	lookup := BackfillFromFunc(func(loc string) (string, bool) {
+		if loc == "https://example.com/shop/d" {
+			return "2024-04-01", true
+		}
+		return "", false
+	})
+	fallback := BackfillDefault(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name  string
+		rules []BackfillRule
+		want  []string // Lastmod of the URLs after backfill
+	}{
+		{"no rules", nil, []string{"2024-05-01", "2024-06-01", "", "", ""}},
+		{"siblings", []BackfillRule{BackfillFromSiblings()}, []string{"2024-05-01", "2024-06-01", "2024-06-01", "", ""}},
+		{"func", []BackfillRule{lookup}, []string{"2024-05-01", "2024-06-01", "", "2024-04-01", ""}},
+		{"source mod time", []BackfillRule{BackfillFromSourceModTime()}, []string{"2024-05-01", "2024-06-01", "", "", "2024-03-01"}},
+		{"default", []BackfillRule{fallback}, []string{"2024-05-01", "2024-06-01", "2024-01-01", "2024-01-01", "2024-01-01"}},
+		{"first rule wins", []BackfillRule{lookup, BackfillFromSiblings(), fallback}, []string{"2024-05-01", "2024-06-01", "2024-06-01", "2024-04-01", "2024-01-01"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSitemapSplitter("in.xml", 50000, WithLastModBackfill(tt.rules...), WithLastModFormat(LastModDate))
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.reset()
+			urls := input()
+			s.backfillLastMod(urls)
+
+			var got []string
+			filled := 0
+			for i, u := range urls {
+				got = append(got, u.LastMod)
+				if u.LastMod != input()[i].LastMod {
+					filled++
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("lastmods %q, want %q", got, tt.want)
+			}
+			if s.result.LastModBackfilled != filled {
+				t.Errorf("result reports %d backfilled, want %d", s.result.LastModBackfilled, filled)
+			}
+		})
+	}
+}
+
+func TestBackfillFromSourceModTime(t *testing.T) {
+	modTime := time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC)
+	tests := []struct {
+		name  string
+		input func(t *testing.T, dir string) string // Writes the input, returning its path
+		want  []string                              // Lastmod of the output, in order
+	}{
+		{"file", func(t *testing.T, dir string) string {
+			path := filepath.Join(dir, "in.xml")
+			in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc></url><url><loc>https://example.com/b</loc><lastmod>2024-06-01</lastmod></url></urlset>`
+			if err := os.WriteFile(path, []byte(in), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(path, modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+			return path
+		}, []string{"2024-02-03", "2024-06-01"}},
+		{"archive members", func(t *testing.T, dir string) string {
+			path := filepath.Join(dir, "in.zip")
+			f, err := os.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			zw := zip.NewWriter(f)
+			for i, name := range []string{"a.xml", "b.xml"} {
+				w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Modified: modTime.AddDate(0, 0, i)})
+				if err != nil {
+					t.Fatal(err)
+				}
+				io.WriteString(w, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/`+name+`</loc></url></urlset>`)
+			}
+			if err := zw.Close(); err != nil {
+				t.Fatal(err)
+			}
+			f.Close()
+			return path
+		}, []string{"2024-02-03", "2024-02-04"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			s, err := NewSitemapSplitter(tt.input(t, dir), 50000, WithOutputDir(dir), WithLastModFormat(LastModDate),
+				WithLastModBackfill(BackfillFromSourceModTime()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Split(); err != nil {
+				t.Fatal(err)
+			}
+			out, err := os.ReadFile(filepath.Join(dir, "in-1.xml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var urlset URLSet
+			if err := xml.Unmarshal(out, &urlset); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, u := range urlset.URLs {
+				got = append(got, u.LastMod)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("lastmods %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("reader", func(t *testing.T) {
+		in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc></url></urlset>`
+		out := splitString(t, in, WithLastModBackfill(BackfillFromSourceModTime()))
+		if strings.Contains(out, "<lastmod>") {
+			t.Errorf("reader input without a modification time got a lastmod:\n%s", out)
+		}
+	})
+}
+
+func TestIndexLastModNewestInChunk(t *testing.T) {
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` +
+		`<url><loc>https://example.com/a</loc><lastmod>2024-06-01</lastmod></url>` +
+		`<url><loc>https://example.com/b</loc><lastmod>2024-02-01</lastmod></url>` +
+		`<url><loc>https://example.com/c</loc></url></urlset>`
+	tests := []struct {
+		name  string
+		rules []BackfillRule
+		want  string // Lastmod of the index entry
+	}{
+		{"newest entry", nil, "2024-06-01"},
+		{"backfilled entry", []BackfillRule{BackfillDefault(time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC))}, "2024-09-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := NewMemorySink()
+			s, err := NewSitemapSplitter("in.xml", 50000, WithSink(sink), WithLastModFormat(LastModDate), WithLastModBackfill(tt.rules...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+				t.Fatal(err)
+			}
+			data, _ := sink.File("sitemap-index.xml")
+			var index SitemapIndex
+			if err := xml.Unmarshal(data, &index); err != nil {
+				t.Fatal(err)
+			}
+			if got := index.Sitemaps[0].LastMod; got != tt.want {
+				t.Errorf("index lastmod %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
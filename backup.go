@@ -0,0 +1,180 @@
+package sitemapsplitter
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// backupOutput copies the output of the previous run, the sitemap index in
+// the output directory with every sitemap it lists and the delta sitemap,
+// into a new timestamped directory below the backup directory, then prunes
+// backups beyond the retention count. It returns the names of the files
+// backed up.
+func (s *SitemapSplitter) backupOutput() ([]string, error) {
+	fs := s.sink.(*FileSink)
+	names, err := s.previousOutputs(fs)
+	if err != nil {
+		return nil, fmt.Errorf("error reading previous sitemap index: %v", err)
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	stamp := time.Now().UTC().Format(stampLayout)
+	dir := filepath.Join(s.backupDir, stamp)
+	for _, name := range names {
+		dst := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return nil, fmt.Errorf("error creating backup directory: %v", err)
+		}
+		if err := copyFile(fs.path(name), dst); err != nil {
+			return nil, fmt.Errorf("error backing up %s: %v", name, err)
+		}
+	}
+	s.logger.Info("backed up previous output", "phase", "write", "backup", dir, "files", len(names))
+	return names, pruneDirs(s.backupDir, s.backupKeep)
+}
+
+// isWithin reports whether dir is parent or a directory below it
+func isWithin(dir, parent string) (bool, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return false, err
+	}
+	if parent, err = filepath.Abs(parent); err != nil {
+		return false, err
+	}
+	rel, err := filepath.Rel(parent, dir)
+	if err != nil {
+		return false, nil
+	}
+	return rel == "." || rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)), nil
+}
+
+// copyFile copies the file src to dst, keeping its permissions
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// removeReplaced removes the files of the previous output that the run that
+// just succeeded did not overwrite, such as chunks beyond its last one
+func (s *SitemapSplitter) removeReplaced(previous []string) error {
+	fs := s.sink.(*FileSink)
+	removed := 0
+	for _, name := range previous {
+		if _, ok := s.written[name]; ok {
+			continue
+		}
+		if err := os.Remove(fs.path(name)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error removing previous %s: %v", name, err)
+		}
+		removed++
+	}
+	s.logger.Debug("removed previous output", "phase", "write", "files", removed)
+	return nil
+}
+
+// previousOutputs returns the names of the files in the output directory
+// that make up the previous run's output. The sitemaps are found by matching
+// the trailing path segments of every index loc against existing files, as
+// the locs may carry a base URL or template the filesystem knows nothing of.
+func (s *SitemapSplitter) previousOutputs(fs *FileSink) ([]string, error) {
+	var names []string
+	exists := func(name string) bool {
+		info, err := os.Stat(fs.path(name))
+		return err == nil && info.Mode().IsRegular()
+	}
+	add := func(name string) {
+		if exists(name) {
+			names = append(names, name)
+		}
+	}
+
+	index := s.sinkName(s.indexFile())
+	for _, name := range s.extraNames() {
+		add(s.sinkName(name))
+	}
+	if !exists(index) {
+		return names, nil
+	}
+
+	locs, err := s.readIndexLocs(fs.path(index))
+	if err != nil {
+		return nil, err
+	}
+	for _, loc := range locs {
+		u, err := url.Parse(loc)
+		if err != nil {
+			continue
+		}
+		segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+		for i := range segments {
+			name := path.Join(segments[i:]...)
+			plain := strings.TrimSuffix(name, ".gz")
+			if !exists(s.sinkName(plain)) && !exists(s.sinkName(plain+".gz")) {
+				continue
+			}
+			// Every variant of the chunk belongs to the set, not only the
+			// one the index points at
+			add(s.sinkName(plain))
+			add(s.sinkName(plain + ".gz"))
+			break
+		}
+	}
+	return append(names, index), nil
+}
+
+// readIndexLocs returns the locs of the sitemap index file at p, which may
+// be gzipped and encrypted like the output
+func (s *SitemapSplitter) readIndexLocs(p string) ([]string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if s.encryption != nil {
+		if r, err = NewDecryptReader(r, s.encryptKey); err != nil {
+			return nil, err
+		}
+	}
+	if r, err = decompress(r); err != nil {
+		return nil, err
+	}
+
+	var index SitemapIndex
+	if err := xml.NewDecoder(r).Decode(&index); err != nil {
+		return nil, err
+	}
+	locs := make([]string, len(index.Sitemaps))
+	for i, entry := range index.Sitemaps {
+		locs[i] = entry.Loc
+	}
+	return locs, nil
+}
@@ -0,0 +1,118 @@
+package sitemapsplitter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeSitemap writes a urlset of locs to path
+func writeSitemap(t *testing.T, path string, locs ...string) {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	for _, loc := range locs {
+		b.WriteString("<url><loc>" + loc + "</loc></url>")
+	}
+	b.WriteString("</urlset>")
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fileContains reports whether the file at path exists and contains s
+func fileContains(path, s string) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && strings.Contains(string(data), s)
+}
+
+func TestBackupKeepsOutputOfFailedRun(t *testing.T) {
+	dir := t.TempDir()
+	input, out := filepath.Join(dir, "in.xml"), filepath.Join(dir, "out")
+	s, err := NewSitemapSplitter(input, 1, WithOutputDir(out), WithBackup(filepath.Join(dir, "backups"), 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeSitemap(t, input, "https://example.com/a", "https://example.com/b", "https://example.com/c")
+	if err := s.Split(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The second chunk cannot be written over a directory
+	writeSitemap(t, input, "https://example.com/d", "https://example.com/e", "https://example.com/f")
+	blocked := filepath.Join(out, "in-2.xml")
+	if err := os.Remove(blocked); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(blocked, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Split(); err == nil {
+		t.Fatal("run writing over a directory succeeded")
+	}
+	if !fileContains(filepath.Join(out, "sitemap-index.xml"), "in-3.xml") {
+		t.Error("failed run removed the previous sitemap index")
+	}
+	if !fileContains(filepath.Join(out, "in-3.xml"), "https://example.com/c") {
+		t.Error("failed run removed a previous chunk")
+	}
+
+	if err := os.Remove(blocked); err != nil {
+		t.Fatal(err)
+	}
+	writeSitemap(t, input, "https://example.com/x", "https://example.com/y")
+	if err := s.Split(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "in-3.xml")); !os.IsNotExist(err) {
+		t.Errorf("chunk beyond the last one of the new output was kept: %v", err)
+	}
+	if !fileContains(filepath.Join(out, "in-1.xml"), "https://example.com/x") {
+		t.Error("new output not written")
+	}
+}
+
+func TestBackupPruneKeepsForeignDirs(t *testing.T) {
+	dir := t.TempDir()
+	input, out, backups := filepath.Join(dir, "in.xml"), filepath.Join(dir, "out"), filepath.Join(dir, "backups")
+	foreign := filepath.Join(backups, "2019-archive")
+	if err := os.MkdirAll(foreign, 0755); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewSitemapSplitter(input, 1, WithOutputDir(out), WithBackup(backups, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		writeSitemap(t, input, "https://example.com/a", "https://example.com/b")
+		if err := s.Split(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(foreign); err != nil {
+		t.Errorf("pruning removed a directory that is not a backup: %v", err)
+	}
+	names, err := stampedDirs(backups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 {
+		t.Errorf("got %d backups, want 2", len(names))
+	}
+}
+
+func TestBackupDirInsideOutput(t *testing.T) {
+	dir := t.TempDir()
+	input, out := filepath.Join(dir, "in.xml"), filepath.Join(dir, "out")
+	for _, backups := range []string{out, filepath.Join(out, "backups")} {
+		if _, err := NewSitemapSplitter(input, 1, WithOutputDir(out), WithBackup(backups, 2)); err == nil {
+			t.Errorf("backup directory %s accepted for output directory %s", backups, out)
+		}
+	}
+	if _, err := NewSitemapSplitter(input, 1, WithOutputDir(out), WithBackup(filepath.Join(dir, "out-backups"), 2)); err != nil {
+		t.Errorf("backup directory beside the output rejected: %v", err)
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// completionCommand creates the completion subcommand, which prints a shell
+// completion script for the commands and flags of the CLI
+func completionCommand() *command {
+	c := newCommand("completion", "bash|zsh|fish", "print a shell completion script")
+	c.args = []string{"bash", "zsh", "fish"}
+	c.run = func(args []string) int {
+		c.flags.Parse(args)
+
+		if c.flags.NArg() != 1 {
+			c.flags.Usage()
+			return 2
+		}
+		switch c.flags.Arg(0) {
+		case "bash":
+			writeBashCompletion(os.Stdout, commands())
+		case "zsh":
+			writeZshCompletion(os.Stdout, commands())
+		case "fish":
+			writeFishCompletion(os.Stdout, commands())
+		default:
+			fmt.Fprintf(os.Stderr, "sitemap-splitter: unknown shell %q, want bash, zsh or fish\n", c.flags.Arg(0))
+			return 2
+		}
+		return 0
+	}
+	return c
+}
+
+// docsCommand creates the docs subcommand, which prints a man page
+func docsCommand() *command {
+	c := newCommand("docs", "man", "print the manual page")
+	c.args = []string{"man"}
+	c.run = func(args []string) int {
+		c.flags.Parse(args)
+
+		if c.flags.NArg() != 1 || c.flags.Arg(0) != "man" {
+			c.flags.Usage()
+			return 2
+		}
+		writeManPage(os.Stdout, commands())
+		return 0
+	}
+	return c
+}
+
+// flagList returns the flags of fs in lexical order
+func flagList(fs *flag.FlagSet) []*flag.Flag {
+	var list []*flag.Flag
+	fs.VisitAll(func(f *flag.Flag) {
+		list = append(list, f)
+	})
+	return list
+}
+
+// isBoolFlag reports whether f is given without a value
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// subcommandNames returns the names of all commands but the default one
+func subcommandNames(cmds []*command) []string {
+	var names []string
+	for _, c := range cmds[1:] {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+// writeBashCompletion writes a bash completion script; flags that take a
+// value fall back to file name completion
+func writeBashCompletion(w io.Writer, cmds []*command) {
+	var valueFlags []string
+	seen := make(map[string]bool)
+	for _, c := range cmds {
+		for _, f := range flagList(c.flags) {
+			if !isBoolFlag(f) && !seen[f.Name] {
+				seen[f.Name] = true
+				valueFlags = append(valueFlags, "-"+f.Name)
+			}
+		}
+	}
+	flagWords := func(c *command) string {
+		var words []string
+		for _, f := range flagList(c.flags) {
+			words = append(words, "-"+f.Name)
+		}
+		return strings.Join(words, " ")
+	}
+
+	fmt.Fprintf(w, "# bash completion for sitemap-splitter\n")
+	fmt.Fprintf(w, "_sitemap_splitter() {\n")
+	fmt.Fprintf(w, "\tlocal cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]} words\n")
+	if len(valueFlags) > 0 {
+		fmt.Fprintf(w, "\tcase $prev in\n\t%s) return ;;\n\tesac\n", strings.Join(valueFlags, "|"))
+	}
+	fmt.Fprintf(w, "\tcase ${COMP_WORDS[1]} in\n")
+	for _, c := range cmds[1:] {
+		if len(c.args) > 0 {
+			fmt.Fprintf(w, "\t%s) words=%q ;;\n", c.name, strings.Join(c.args, " "))
+		} else {
+			fmt.Fprintf(w, "\t%s)\n\t\t[[ $cur == -* ]] || return\n\t\twords=%q\n\t\t;;\n", c.name, flagWords(c))
+		}
+	}
+	fmt.Fprintf(w, "\t*)\n")
+	fmt.Fprintf(w, "\t\tif [ \"$COMP_CWORD\" -eq 1 ] && [[ $cur != -* ]]; then\n")
+	fmt.Fprintf(w, "\t\t\twords=%q\n", strings.Join(subcommandNames(cmds), " "))
+	fmt.Fprintf(w, "\t\telse\n\t\t\t[[ $cur == -* ]] || return\n")
+	fmt.Fprintf(w, "\t\t\twords=%q\n\t\tfi\n\t\t;;\n", flagWords(cmds[0]))
+	fmt.Fprintf(w, "\tesac\n")
+	fmt.Fprintf(w, "\tCOMPREPLY=($(compgen -W \"$words\" -- \"$cur\"))\n")
+	fmt.Fprintf(w, "}\n")
+	fmt.Fprintf(w, "complete -o default -F _sitemap_splitter sitemap-splitter\n")
+}
+
+// writeZshCompletion writes a zsh completion script
+func writeZshCompletion(w io.Writer, cmds []*command) {
+	// zshSpecs writes the _arguments specs for the flags of c
+	zshSpecs := func(c *command, indent string) {
+		for _, f := range flagList(c.flags) {
+			desc := strings.NewReplacer(`[`, `\[`, `]`, `\]`, `:`, `\:`).Replace(shellQuote(f.Usage))
+			if isBoolFlag(f) {
+				fmt.Fprintf(w, "%s'-%s[%s]' \\\n", indent, f.Name, desc)
+			} else {
+				fmt.Fprintf(w, "%s'-%s[%s]:value:_files' \\\n", indent, f.Name, desc)
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "#compdef sitemap-splitter\n\n")
+	fmt.Fprintf(w, "_sitemap_splitter() {\n")
+	fmt.Fprintf(w, "\tcase $words[2] in\n")
+	for _, c := range cmds[1:] {
+		fmt.Fprintf(w, "\t%s)\n", c.name)
+		if len(c.args) > 0 {
+			fmt.Fprintf(w, "\t\t(( CURRENT == 3 )) && compadd -- %s\n", strings.Join(c.args, " "))
+		} else {
+			fmt.Fprintf(w, "\t\tshift words\n\t\t(( CURRENT-- ))\n\t\t_arguments \\\n")
+			zshSpecs(c, "\t\t\t")
+			fmt.Fprintf(w, "\t\t\t'*:file:_files'\n")
+		}
+		fmt.Fprintf(w, "\t\t;;\n")
+	}
+	fmt.Fprintf(w, "\t*)\n")
+	fmt.Fprintf(w, "\t\tif (( CURRENT == 2 )) && [[ $PREFIX != -* ]]; then\n")
+	fmt.Fprintf(w, "\t\t\tcompadd -- %s\n\t\tfi\n", strings.Join(subcommandNames(cmds), " "))
+	fmt.Fprintf(w, "\t\t_arguments \\\n")
+	zshSpecs(cmds[0], "\t\t\t")
+	fmt.Fprintf(w, "\t\t\t'*:sitemap:_files'\n")
+	fmt.Fprintf(w, "\t\t;;\n")
+	fmt.Fprintf(w, "\tesac\n")
+	fmt.Fprintf(w, "}\n\n")
+	fmt.Fprintf(w, "_sitemap_splitter \"$@\"\n")
+}
+
+// writeFishCompletion writes a fish completion script
+func writeFishCompletion(w io.Writer, cmds []*command) {
+	fishFlags := func(c *command, cond string) {
+		for _, f := range flagList(c.flags) {
+			req := ""
+			if !isBoolFlag(f) {
+				req = " -r"
+			}
+			fmt.Fprintf(w, "complete -c sitemap-splitter -n '%s' -o %s%s -d '%s'\n", cond, f.Name, req, fishQuote(f.Usage))
+		}
+	}
+
+	fmt.Fprintf(w, "# fish completion for sitemap-splitter\n")
+	for _, c := range cmds[1:] {
+		fmt.Fprintf(w, "complete -c sitemap-splitter -n __fish_use_subcommand -a %s -d '%s'\n", c.name, fishQuote(c.summary))
+	}
+	fishFlags(cmds[0], "not __fish_seen_subcommand_from "+strings.Join(subcommandNames(cmds), " "))
+	for _, c := range cmds[1:] {
+		cond := "__fish_seen_subcommand_from " + c.name
+		if len(c.args) > 0 {
+			fmt.Fprintf(w, "complete -c sitemap-splitter -n '%s' -f -a '%s'\n", cond, strings.Join(c.args, " "))
+		}
+		fishFlags(c, cond)
+	}
+}
+
+// writeManPage writes a roff man page listing every command and its flags
+func writeManPage(w io.Writer, cmds []*command) {
+	manFlags := func(c *command) {
+		for _, f := range flagList(c.flags) {
+			name, usage := flag.UnquoteUsage(f)
+			if isBoolFlag(f) {
+				fmt.Fprintf(w, ".TP\n.B \\-%s\n", roffEscape(f.Name))
+			} else {
+				fmt.Fprintf(w, ".TP\n.BI \\-%s \" %s\"\n", roffEscape(f.Name), roffEscape(name))
+			}
+			switch f.DefValue {
+			case "", "0", "false":
+			default:
+				usage += fmt.Sprintf(" (default %s)", f.DefValue)
+			}
+			fmt.Fprintf(w, "%s\n", roffLine(usage))
+		}
+	}
+
+	fmt.Fprintf(w, ".TH SITEMAP-SPLITTER 1\n")
+	fmt.Fprintf(w, ".SH NAME\nsitemap-splitter \\- %s\n", roffEscape(cmds[0].summary))
+	fmt.Fprintf(w, ".SH SYNOPSIS\n")
+	for i, c := range cmds {
+		if i > 0 {
+			fmt.Fprintf(w, ".br\n")
+		}
+		fmt.Fprintf(w, ".B %s\n%s\n", strings.TrimSpace("sitemap-splitter "+c.name), roffLine(c.synopsis))
+	}
+	fmt.Fprintf(w, ".SH OPTIONS\n")
+	manFlags(cmds[0])
+	fmt.Fprintf(w, ".SH COMMANDS\n")
+	for _, c := range cmds[1:] {
+		fmt.Fprintf(w, ".SS %s\n%s\n", c.name, roffLine(c.summary))
+		manFlags(c)
+	}
+}
+
+// shellQuote escapes s for use inside single quotes in bash and zsh
+func shellQuote(s string) string {
+	return strings.ReplaceAll(s, `'`, `'\''`)
+}
+
+// fishQuote escapes s for use inside single quotes in fish
+func fishQuote(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s)
+}
+
+// roffEscape escapes backslashes and hyphens for roff
+func roffEscape(s string) string {
+	return strings.NewReplacer(`\`, `\e`, `-`, `\-`).Replace(s)
+}
+
+// roffLine escapes s as a text line that roff must not read as a request
+func roffLine(s string) string {
+	s = roffEscape(s)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCompletionListsFlags(t *testing.T) {
+	cmds := commands()
+	tests := []struct {
+		shell string
+		write func(io.Writer, []*command)
+		entry func(c *command, flag string) string // Text naming flag of c
+	}{
+		{"bash", writeBashCompletion, func(_ *command, flag string) string { return "-" + flag }},
+		{"zsh", writeZshCompletion, func(_ *command, flag string) string { return "'-" + flag + "[" }},
+		{"fish", writeFishCompletion, func(c *command, flag string) string {
+			if c.name == "" {
+				return "' -o " + flag + " "
+			}
+			return "__fish_seen_subcommand_from " + c.name + "' -o " + flag + " "
+		}},
+		{"man", writeManPage, func(_ *command, flag string) string { return `\-` + roffEscape(flag) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			var b strings.Builder
+			tt.write(&b, cmds)
+			script := b.String()
+
+			for _, c := range cmds {
+				if c.name != "" && !strings.Contains(script, c.name) {
+					t.Errorf("command %s missing", c.name)
+				}
+				for _, f := range flagList(c.flags) {
+					if !strings.Contains(script, tt.entry(c, f.Name)) {
+						t.Errorf("flag -%s of command %q missing", f.Name, c.name)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCompletionCoversSplitFlags(t *testing.T) {
+	// Flags defined in addSplitFlags reach completion through the split and
+	// preview commands alike
+	var names []string
+	for _, f := range flagList(splitCommand().flags) {
+		names = append(names, f.Name)
+	}
+	for _, want := range []string{"lastmod-after", "lastmod-before", "lastmod-days", "rate-limit", "include", "exclude"} {
+		if !slices.Contains(names, want) {
+			t.Errorf("split command lacks -%s", want)
+		}
+	}
+	for _, f := range flagList(splitCommand().flags) {
+		if previewCommand().flags.Lookup(f.Name) == nil {
+			t.Errorf("preview command lacks split flag -%s", f.Name)
+		}
+	}
+}
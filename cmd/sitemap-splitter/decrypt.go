@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	sitemapsplitter "github.com/choirulanwar/sitemap-splitter"
+)
+
+// decryptCommand creates the decrypt subcommand, which restores the plaintext
+// of files written with -key-file next to them or into -out
+func decryptCommand() *command {
+	c := newCommand("decrypt", "-key-file <file> [-out <dir>] <file.enc>...", "restore the plaintext of files encrypted with -key-file")
+	flags := c.flags
+	keyFile := flags.String("key-file", "", "file holding the hex-encoded AES key the files were encrypted with")
+	outputDir := flags.String("out", "", "directory to write the decrypted files to (default: next to each file)")
+	c.run = func(args []string) int {
+		flags.Parse(args)
+
+		if *keyFile == "" || flags.NArg() == 0 {
+			flags.Usage()
+			return 2
+		}
+		key, err := readKey(*keyFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "sitemap-splitter: %v\n", err)
+			return 2
+		}
+
+		for _, name := range flags.Args() {
+			if !strings.HasSuffix(name, sitemapsplitter.EncryptedSuffix) {
+				fmt.Fprintf(os.Stderr, "sitemap-splitter: %s does not end in %s\n", name, sitemapsplitter.EncryptedSuffix)
+				return 1
+			}
+			target := strings.TrimSuffix(name, sitemapsplitter.EncryptedSuffix)
+			if *outputDir != "" {
+				target = filepath.Join(*outputDir, filepath.Base(target))
+			}
+			if err := decryptFile(name, target, key); err != nil {
+				fmt.Fprintf(os.Stderr, "sitemap-splitter: %v\n", err)
+				return 1
+			}
+		}
+		return 0
+	}
+	return c
+}
+
+// decryptFile writes the plaintext of the encrypted file name to target,
+// removing target again if decryption fails midway
+func decryptFile(name, target string, key []byte) error {
+	in, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	r, err := sitemapsplitter.NewDecryptReader(in, key)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		os.Remove(target)
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return out.Close()
+}
+
+// readKey reads a hex-encoded key from path
+func readKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading key file: %v", err)
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding key file: %v", err)
+	}
+	return key, nil
+}
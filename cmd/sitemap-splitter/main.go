@@ -0,0 +1,341 @@
+// Command sitemap-splitter splits a large sitemap into smaller sitemaps and
+// writes a sitemap index pointing at them.
+//
+// Usage:
+//
+//	sitemap-splitter [flags] <sitemap.xml | https://example.com/sitemap.xml>
+//	sitemap-splitter preview -chunk <n> | -url <loc> [flags] <sitemap>
+//	sitemap-splitter decrypt -key-file <file> [-out <dir>] <file.enc>...
+//	sitemap-splitter rollback -out <dir> -backup-dir <dir>
+//	sitemap-splitter completion bash|zsh|fish
+//	sitemap-splitter docs man
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	sitemapsplitter "github.com/choirulanwar/sitemap-splitter"
+)
+
+// command is a subcommand of the CLI; completion scripts and the man page
+// are generated from its flags
+type command struct {
+	name     string   // empty for the default split command
+	synopsis string   // arguments after the name, e.g. "-out <dir>"
+	summary  string   // one-line description
+	args     []string // fixed words accepted as arguments, for completion
+	flags    *flag.FlagSet
+	run      func(args []string) int
+}
+
+// newCommand creates a command whose flag set prints its synopsis on -h
+func newCommand(name, synopsis, summary string) *command {
+	c := &command{name: name, synopsis: synopsis, summary: summary}
+	if name == "" {
+		c.flags = flag.NewFlagSet("sitemap-splitter", flag.ExitOnError)
+	} else {
+		c.flags = flag.NewFlagSet(name, flag.ExitOnError)
+	}
+	c.flags.Usage = func() {
+		fmt.Fprintf(c.flags.Output(), "Usage: %s %s\n", os.Args[0], c.usage())
+		if len(flagList(c.flags)) > 0 {
+			fmt.Fprintf(c.flags.Output(), "\nFlags:\n")
+			c.flags.PrintDefaults()
+		}
+	}
+	return c
+}
+
+// usage returns the name and synopsis of the command
+func (c *command) usage() string {
+	return strings.TrimSpace(c.name + " " + c.synopsis)
+}
+
+// commands returns the commands of the CLI, the default split command first
+func commands() []*command {
+	return []*command{splitCommand(), previewCommand(), decryptCommand(), rollbackCommand(), completionCommand(), docsCommand()}
+}
+
+func main() {
+	cmds := commands()
+	if len(os.Args) > 1 {
+		for _, c := range cmds[1:] {
+			if os.Args[1] == c.name {
+				os.Exit(c.run(os.Args[2:]))
+			}
+		}
+	}
+	os.Exit(cmds[0].run(os.Args[1:]))
+}
+
+// splitCommand creates the default command, which splits a sitemap
+func splitCommand() *command {
+	c := newCommand("", "[flags] <sitemap>", "split a sitemap into smaller sitemaps and a sitemap index")
+	flags := c.flags
+	newSplitter := addSplitFlags(flags)
+	c.run = func(args []string) int {
+		flags.Parse(args)
+		splitter, path, logger, ok := newSplitter()
+		if !ok {
+			return 2
+		}
+		started := time.Now()
+		if err := splitter.Split(); err != nil {
+			logger.Error("split failed", "input", path, "error", err, "duration", time.Since(started))
+			return 1
+		}
+
+		result := splitter.LastResult()
+		if result.DryRun {
+			files := result.Files
+			if result.Index != nil {
+				files = append(files, *result.Index)
+			}
+			for _, f := range files {
+				logger.Info("would write", "name", f.Name, "urls", f.URLs, "bytes", f.Bytes)
+			}
+		}
+		for _, w := range result.Warnings {
+			if w.Code == sitemapsplitter.WarnHreflangInvalid || w.Code == sitemapsplitter.WarnHreflangNoReturn {
+				logger.Warn("broken hreflang alternate", "code", w.Code, "loc", w.Loc)
+			}
+		}
+		logger.Info("split finished",
+			"input", path,
+			"up_to_date", result.UpToDate,
+			"dry_run", result.DryRun,
+			"urls", result.URLs,
+			"files", len(result.Files),
+			"dropped", result.Dropped(),
+			"unchanged_files", result.UnchangedFiles,
+			"duration", time.Since(started),
+		)
+		return 0
+	}
+	return c
+}
+
+// addSplitFlags defines the flags that configure a split on flags, shared by
+// the split and preview commands. After parsing, the returned function
+// creates the splitter for the sitemap argument and the logger, or prints the
+// problem and reports false.
+func addSplitFlags(flags *flag.FlagSet) func() (*sitemapsplitter.SitemapSplitter, string, *slog.Logger, bool) {
+	input := flags.String("input", "", "sitemap file or http(s) URL to split (may also be given as an argument)")
+	limit := flags.Int("limit", 50000, "maximum number of URLs per sitemap file")
+	outputDir := flags.String("out", "", "directory or registered remote destination such as s3:bucket/prefix to write the split sitemaps to (default: next to the input)")
+	baseURL := flags.String("base-url", "", "URL prefix of the index entries, e.g. https://example.com/sitemaps/")
+	backupDir := flags.String("backup-dir", "", "directory to copy the previous output into before writing, for rollback")
+	keepBackups := flags.Int("keep-backups", 5, "number of backups to keep in -backup-dir")
+	dryRun := flags.Bool("dry-run", false, "plan the split and report the files it would write without writing anything")
+	noIndex := flags.Bool("no-index", false, "write only the split sitemaps, without a sitemap index")
+	gzipOutput := flags.Bool("gzip", false, "write gzip-compressed sitemaps and index")
+	format := flags.String("format", "xml", "format of the split sitemaps, xml or text (one URL per line)")
+	keyFile := flags.String("key-file", "", "file holding a hex-encoded AES key to encrypt the output with")
+	normalize := flags.Bool("normalize", false, "normalize locs: encode illegal characters, lowercase scheme and host, drop default ports and duplicate slashes")
+	checkHreflang := flags.Bool("check-hreflang", false, "warn about malformed hreflang codes and alternates that do not link back")
+	dateNames := flags.Bool("date-names", false, "embed the run date in chunk names, e.g. sitemap-2024-06-01-3.xml")
+	newsSitemap := flags.String("news-sitemap", "", "also write a sitemap of this name holding only URLs with news published in the last 48 hours")
+	dedupe := flags.Bool("dedupe", false, "drop repeated locs, keeping the entry with the newest lastmod and merging in extensions and attributes only the others have")
+	excludeFile := flags.String("exclude-file", "", "file of URLs to drop from the output, one per line")
+	allowFile := flags.String("allow-file", "", "file of URL prefixes to publish, one per line; all other URLs are dropped")
+	purgeURL := flags.String("purge-url", "", "template of a CDN purge request URL POSTed for every stored file, e.g. https://cdn.example/purge?file={{.BaseURL}}{{.Name}}")
+	purgeHeaders := make(map[string]string)
+	flags.Func("purge-header", "header of the purge requests as Name: value, e.g. an API token; repeatable", func(v string) error {
+		name, value, ok := strings.Cut(v, ":")
+		if !ok {
+			return fmt.Errorf("want Name: value")
+		}
+		purgeHeaders[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		return nil
+	})
+	var includes, excludes []string
+	flags.Func("include", "publish only URLs matching this glob (* for any run of characters) or re:regexp; repeatable", func(p string) error {
+		includes = append(includes, p)
+		return nil
+	})
+	flags.Func("exclude", "drop URLs matching this glob or re:regexp, e.g. /staging/; repeatable", func(p string) error {
+		excludes = append(excludes, p)
+		return nil
+	})
+	// Dates are midnight local time, like date-only lastmod values
+	var modAfter, modBefore time.Time
+	flags.Func("lastmod-after", "keep only URLs last modified on or after this date (2006-01-02)", func(v string) (err error) {
+		modAfter, err = time.ParseInLocation("2006-01-02", v, time.Local)
+		return err
+	})
+	flags.Func("lastmod-before", "keep only URLs last modified before this date (2006-01-02), which is excluded", func(v string) (err error) {
+		modBefore, err = time.ParseInLocation("2006-01-02", v, time.Local)
+		return err
+	})
+	modDays := flags.Int("lastmod-days", 0, "keep only URLs last modified within this many days, 0 to keep all")
+	rateLimit := flags.Float64("rate-limit", 0, "maximum HTTP requests per second across all hosts, 0 for no limit")
+	childConcurrency := flags.Int("child-concurrency", 0, "child sitemaps of an index input to read at once, 0 to read them in turn")
+	hostConcurrency := flags.Int("host-concurrency", 0, "HTTP requests to the same host in flight at once, 0 for no limit")
+	var timeouts sitemapsplitter.PhaseTimeouts
+	flags.Func("phase-timeout", "time limit of one phase as phase=duration, e.g. fetch=30s; phases are fetch, parse, filter, plan, write and upload; repeatable", func(v string) error {
+		phase, value, ok := strings.Cut(v, "=")
+		if !ok {
+			return fmt.Errorf("want phase=duration")
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		fields := map[string]*time.Duration{
+			"fetch":  &timeouts.Fetch,
+			"parse":  &timeouts.Parse,
+			"filter": &timeouts.Filter,
+			"plan":   &timeouts.Plan,
+			"write":  &timeouts.Write,
+			"upload": &timeouts.Upload,
+		}
+		field, ok := fields[phase]
+		if !ok {
+			return fmt.Errorf("unknown phase %q", phase)
+		}
+		*field = d
+		return nil
+	})
+	profile := flags.String("profile", "", fmt.Sprintf("preset of limits and validation settings, one of %s", profileNames()))
+	logFormat := flags.String("log-format", "text", "format of the progress log on stderr, text or json")
+	logLevel := flags.String("log-level", "info", "minimum level of logged events: debug, info, warn or error")
+	return func() (*sitemapsplitter.SitemapSplitter, string, *slog.Logger, bool) {
+		path := *input
+		if path == "" && flags.NArg() == 1 {
+			path = flags.Arg(0)
+		}
+		if path == "" || flags.NArg() > 1 || (*input != "" && flags.NArg() > 0) {
+			flags.Usage()
+			return nil, "", nil, false
+		}
+
+		logger, err := newLogger(*logFormat, *logLevel)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "sitemap-splitter: %v\n", err)
+			return nil, "", nil, false
+		}
+
+		opts := []sitemapsplitter.Option{sitemapsplitter.WithLogger(logger)}
+		if *profile != "" {
+			opts = append(opts, sitemapsplitter.WithProfile(sitemapsplitter.Profile(*profile)))
+		}
+		opts = append(opts,
+			sitemapsplitter.WithIndexBaseURL(*baseURL),
+			sitemapsplitter.WithGzipOutput(*gzipOutput),
+		)
+		switch *format {
+		case "xml":
+		case "text":
+			opts = append(opts, sitemapsplitter.WithOutputFormat(sitemapsplitter.FormatText))
+		default:
+			fmt.Fprintf(os.Stderr, "sitemap-splitter: invalid format %q, want xml or text\n", *format)
+			return nil, "", nil, false
+		}
+		if *keyFile != "" {
+			key, err := readKey(*keyFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "sitemap-splitter: %v\n", err)
+				return nil, "", nil, false
+			}
+			opts = append(opts, sitemapsplitter.WithEncryption(key))
+		}
+		if *outputDir != "" {
+			opts = append(opts, sitemapsplitter.WithDestination(*outputDir))
+		}
+		if *backupDir != "" {
+			opts = append(opts, sitemapsplitter.WithBackup(*backupDir, *keepBackups))
+		}
+		if *purgeURL != "" {
+			opts = append(opts, sitemapsplitter.WithCachePurge(sitemapsplitter.CachePurge{URL: *purgeURL, Headers: purgeHeaders}))
+		}
+		if *dryRun {
+			opts = append(opts, sitemapsplitter.WithDryRun(true))
+		}
+		if *noIndex {
+			opts = append(opts, sitemapsplitter.WithoutIndex())
+		}
+		if *normalize {
+			opts = append(opts, sitemapsplitter.WithURLNormalization())
+		}
+		if *checkHreflang {
+			opts = append(opts, sitemapsplitter.WithHreflangValidation())
+		}
+		if *dateNames {
+			opts = append(opts, sitemapsplitter.WithDateNaming())
+		}
+		if *newsSitemap != "" {
+			opts = append(opts, sitemapsplitter.WithNewsSitemap(*newsSitemap))
+		}
+		if *dedupe {
+			opts = append(opts, sitemapsplitter.WithDeduplicate(true))
+		}
+		if *excludeFile != "" {
+			opts = append(opts, sitemapsplitter.WithExcludeFile(*excludeFile))
+		}
+		if *allowFile != "" {
+			opts = append(opts, sitemapsplitter.WithAllowlistFile(*allowFile))
+		}
+		if len(includes) > 0 {
+			opts = append(opts, sitemapsplitter.WithInclude(includes...))
+		}
+		if len(excludes) > 0 {
+			opts = append(opts, sitemapsplitter.WithExclude(excludes...))
+		}
+		if !modAfter.IsZero() || !modBefore.IsZero() {
+			opts = append(opts, sitemapsplitter.WithLastModRange(modAfter, modBefore))
+		}
+		if *modDays != 0 {
+			opts = append(opts, sitemapsplitter.WithLastModWindow(time.Duration(*modDays)*24*time.Hour))
+		}
+		if *rateLimit != 0 {
+			opts = append(opts, sitemapsplitter.WithRateLimit(*rateLimit))
+		}
+		if *childConcurrency != 0 {
+			opts = append(opts, sitemapsplitter.WithChildConcurrency(*childConcurrency))
+		}
+		if *hostConcurrency != 0 {
+			opts = append(opts, sitemapsplitter.WithHostConcurrency(*hostConcurrency))
+		}
+		if timeouts != (sitemapsplitter.PhaseTimeouts{}) {
+			opts = append(opts, sitemapsplitter.WithPhaseTimeouts(timeouts))
+		}
+
+		splitter, err := sitemapsplitter.NewSitemapSplitter(path, *limit, opts...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "sitemap-splitter: %v\n", err)
+			return nil, "", nil, false
+		}
+		return splitter, path, logger, true
+	}
+}
+
+// newLogger creates the stderr logger selected by the log flags
+func newLogger(format, level string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(os.Stderr, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts)), nil
+	}
+	return nil, fmt.Errorf("invalid log format %q, want text or json", format)
+}
+
+// profileNames lists the known profiles for the usage message
+func profileNames() string {
+	var names []string
+	for _, p := range sitemapsplitter.Profiles() {
+		names = append(names, string(p))
+	}
+	return strings.Join(names, ", ")
+}
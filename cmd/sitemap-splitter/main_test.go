@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitCommand(t *testing.T) {
+	const sitemap = `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc></url><url><loc>https://example.com/b</loc></url></urlset>`
+	tests := []struct {
+		name  string
+		args  func(input, out string) []string
+		code  int
+		files []string // Files expected in the output directory
+	}{
+		{"argument", func(input, out string) []string { return []string{"-limit", "1", "-out", out, input} }, 0, []string{"in-1.xml", "in-2.xml", "sitemap-index.xml"}},
+		{"input flag", func(input, out string) []string { return []string{"-input", input, "-out", out} }, 0, []string{"in-1.xml", "sitemap-index.xml"}},
+		{"no index", func(input, out string) []string { return []string{"-no-index", "-out", out, input} }, 0, []string{"in-1.xml"}},
+		{"text format", func(input, out string) []string { return []string{"-format", "text", "-out", out, input} }, 0, []string{"in-1.txt", "sitemap-index.xml"}},
+		{"dry run", func(input, out string) []string { return []string{"-dry-run", "-out", out, input} }, 0, nil},
+		{"missing input", func(input, out string) []string { return []string{"-out", out} }, 2, nil},
+		{"two inputs", func(input, out string) []string { return []string{"-out", out, input, input} }, 2, nil},
+		{"invalid format", func(input, out string) []string { return []string{"-format", "csv", "-out", out, input} }, 2, nil},
+		{"local destination", func(input, out string) []string { return []string{"-out", "local:" + out, input} }, 0, []string{"in-1.xml", "sitemap-index.xml"}},
+		{"unregistered destination", func(input, out string) []string { return []string{"-out", "s3:bucket/prefix", input} }, 2, nil},
+		{"invalid option", func(input, out string) []string { return []string{"-limit", "0", "-out", out, input} }, 2, nil},
+		{"failed split", func(input, out string) []string { return []string{"-out", out, input + ".missing"} }, 1, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			input, out := filepath.Join(dir, "in.xml"), filepath.Join(dir, "out")
+			if err := os.WriteFile(input, []byte(sitemap), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			args := append([]string{"-log-level", "error"}, tt.args(input, out)...)
+			if code := splitCommand().run(args); code != tt.code {
+				t.Fatalf("exit code %d, want %d", code, tt.code)
+			}
+			entries, _ := os.ReadDir(out)
+			var files []string
+			for _, e := range entries {
+				files = append(files, e.Name())
+			}
+			if strings.Join(files, " ") != strings.Join(tt.files, " ") {
+				t.Errorf("wrote %v, want %v", files, tt.files)
+			}
+		})
+	}
+}
+
+// captureStdout runs run with os.Stdout redirected and returns what it
+// printed along with its exit code
+func captureStdout(t *testing.T, run func() int) (string, int) {
+	t.Helper()
+	f, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	stdout := os.Stdout
+	os.Stdout = f
+	code := run()
+	os.Stdout = stdout
+
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data), code
+}
+
+func TestPreviewCommand(t *testing.T) {
+	const sitemap = `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc></url><url><loc>https://example.com/b</loc></url><url><loc>https://example.com/c</loc></url></urlset>`
+	tests := []struct {
+		name string
+		args []string
+		code int
+		want string // Printed output
+	}{
+		{"chunk", []string{"-chunk", "2"}, 0, "https://example.com/c\n"},
+		{"url", []string{"-url", "https://example.com/c"}, 0, "2\tin-2.xml\n"},
+		{"chunk out of range", []string{"-chunk", "3"}, 1, ""},
+		{"unknown url", []string{"-url", "https://example.com/d"}, 1, ""},
+		{"chunk and url", []string{"-chunk", "1", "-url", "https://example.com/a"}, 2, ""},
+		{"neither", nil, 2, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			input, out := filepath.Join(dir, "in.xml"), filepath.Join(dir, "out")
+			if err := os.WriteFile(input, []byte(sitemap), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			args := append(append([]string{"-log-level", "error", "-limit", "2", "-out", out}, tt.args...), input)
+			got, code := captureStdout(t, func() int { return previewCommand().run(args) })
+			if code != tt.code {
+				t.Fatalf("exit code %d, want %d", code, tt.code)
+			}
+			if got != tt.want {
+				t.Errorf("printed %q, want %q", got, tt.want)
+			}
+			if _, err := os.Stat(out); !os.IsNotExist(err) {
+				t.Errorf("preview created the output directory")
+			}
+		})
+	}
+}
+
+func TestDecryptCommand(t *testing.T) {
+	const sitemap = `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc></url></urlset>`
+	dir := t.TempDir()
+	input, out := filepath.Join(dir, "in.xml"), filepath.Join(dir, "out")
+	key, wrongKey := filepath.Join(dir, "key"), filepath.Join(dir, "wrong-key")
+	for path, data := range map[string]string{
+		input:    sitemap,
+		key:      strings.Repeat("07", 32) + "\n",
+		wrongKey: strings.Repeat("08", 32),
+	} {
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if code := splitCommand().run([]string{"-log-level", "error", "-key-file", key, "-out", out, input}); code != 0 {
+		t.Fatalf("split exit code %d, want 0", code)
+	}
+	chunk := filepath.Join(out, "in-1.xml.enc")
+
+	tests := []struct {
+		name  string
+		args  func(plain string) []string
+		code  int
+		files []string // Files expected in the plaintext directory
+	}{
+		{"decrypt", func(plain string) []string {
+			return []string{"-key-file", key, "-out", plain, chunk, filepath.Join(out, "sitemap-index.xml.enc")}
+		}, 0, []string{"in-1.xml", "sitemap-index.xml"}},
+		{"wrong key", func(plain string) []string { return []string{"-key-file", wrongKey, "-out", plain, chunk} }, 1, nil},
+		{"not encrypted", func(plain string) []string { return []string{"-key-file", key, "-out", plain, input} }, 1, nil},
+		{"missing key file", func(plain string) []string { return []string{"-out", plain, chunk} }, 2, nil},
+		{"missing files", func(plain string) []string { return []string{"-key-file", key, "-out", plain} }, 2, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plain := t.TempDir()
+			if code := decryptCommand().run(tt.args(plain)); code != tt.code {
+				t.Fatalf("exit code %d, want %d", code, tt.code)
+			}
+			entries, _ := os.ReadDir(plain)
+			var files []string
+			for _, e := range entries {
+				files = append(files, e.Name())
+			}
+			if strings.Join(files, " ") != strings.Join(tt.files, " ") {
+				t.Fatalf("wrote %v, want %v", files, tt.files)
+			}
+			if tt.code == 0 {
+				data, err := os.ReadFile(filepath.Join(plain, "in-1.xml"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(string(data), "<loc>https://example.com/a</loc>") {
+					t.Errorf("decrypted chunk lacks its URL: %s", data)
+				}
+			}
+		})
+	}
+}
+
+func TestRollbackCommand(t *testing.T) {
+	dir := t.TempDir()
+	input, out, backups := filepath.Join(dir, "in.xml"), filepath.Join(dir, "out"), filepath.Join(dir, "backups")
+	split := func(locs ...string) {
+		t.Helper()
+		sitemap := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`
+		for _, loc := range locs {
+			sitemap += "<url><loc>" + loc + "</loc></url>"
+		}
+		if err := os.WriteFile(input, []byte(sitemap+"</urlset>"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if code := splitCommand().run([]string{"-log-level", "error", "-limit", "1", "-backup-dir", backups, "-out", out, input}); code != 0 {
+			t.Fatalf("split exit code %d, want 0", code)
+		}
+	}
+
+	if code := rollbackCommand().run([]string{"-out", out, "-backup-dir", backups}); code != 1 {
+		t.Fatalf("rollback without a backup exited %d, want 1", code)
+	}
+	if code := rollbackCommand().run([]string{"-out", out}); code != 2 {
+		t.Fatalf("rollback without -backup-dir exited %d, want 2", code)
+	}
+
+	split("https://example.com/a", "https://example.com/b")
+	split("https://example.com/c")
+	if data, _ := os.ReadFile(filepath.Join(out, "in-1.xml")); !strings.Contains(string(data), "https://example.com/c<") {
+		t.Fatalf("second split did not replace in-1.xml: %s", data)
+	}
+	if code := rollbackCommand().run([]string{"-out", out, "-backup-dir", backups}); code != 0 {
+		t.Fatalf("rollback exit code %d, want 0", code)
+	}
+	for name, loc := range map[string]string{"in-1.xml": "a", "in-2.xml": "b"} {
+		data, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), "<loc>https://example.com/"+loc+"</loc>") {
+			t.Errorf("%s after rollback: %s, want the first split's %s", name, data, loc)
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(out, "sitemap-index.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "in-2.xml") {
+		t.Errorf("index after rollback does not list in-2.xml: %s", data)
+	}
+}
+
+func TestLastModFlags(t *testing.T) {
+	// Dates on the command line are days in the local timezone, like
+	// date-only lastmod values, also east of UTC
+	local := time.Local
+	time.Local = time.FixedZone("UTC+9", 9*60*60)
+	t.Cleanup(func() { time.Local = local })
+
+	const sitemap = `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` +
+		`<url><loc>https://example.com/may-31</loc><lastmod>2024-05-31</lastmod></url>` +
+		`<url><loc>https://example.com/june-1</loc><lastmod>2024-06-01</lastmod></url>` +
+		`<url><loc>https://example.com/june-9</loc><lastmod>2024-06-09T23:30:00+09:00</lastmod></url>` +
+		`<url><loc>https://example.com/june-10</loc><lastmod>2024-06-10</lastmod></url>` +
+		`</urlset>`
+	dir := t.TempDir()
+	input, out := filepath.Join(dir, "in.xml"), filepath.Join(dir, "out")
+	if err := os.WriteFile(input, []byte(sitemap), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	args := []string{"-log-level", "error", "-lastmod-after", "2024-06-01", "-lastmod-before", "2024-06-10", "-out", out, input}
+	if code := splitCommand().run(args); code != 0 {
+		t.Fatalf("exit code %d, want 0", code)
+	}
+	data, err := os.ReadFile(filepath.Join(out, "in-1.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for loc, want := range map[string]bool{"may-31": false, "june-1": true, "june-9": true, "june-10": false} {
+		if got := strings.Contains(string(data), "https://example.com/"+loc+"<"); got != want {
+			t.Errorf("kept %s %v, want %v", loc, got, want)
+		}
+	}
+}
+
+func TestNewLogger(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		format, level string
+		json          bool // Whether records are written as JSON
+		minLevel      slog.Level
+		wantErr       bool
+	}{
+		{"text", "info", false, slog.LevelInfo, false},
+		{"json", "debug", true, slog.LevelDebug, false},
+		{"json", "WARN", true, slog.LevelWarn, false},
+		{"json", "verbose", false, 0, true},
+		{"logfmt", "info", false, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format+" "+tt.level, func(t *testing.T) {
+			logger, err := newLogger(tt.format, tt.level)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("invalid logger settings accepted")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := logger.Handler().(*slog.JSONHandler); ok != tt.json {
+				t.Errorf("JSON handler %v, want %v", ok, tt.json)
+			}
+			if !logger.Enabled(ctx, tt.minLevel) || logger.Enabled(ctx, tt.minLevel-1) {
+				t.Errorf("logger does not start at level %v", tt.minLevel)
+			}
+		})
+	}
+}
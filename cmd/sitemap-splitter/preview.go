@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// previewCommand creates the preview subcommand, which prints the URLs of one
+// chunk, or the chunk of one URL, as a split with the same flags would write
+// them
+func previewCommand() *command {
+	c := newCommand("preview", "-chunk <n> | -url <loc> [flags] <sitemap>", "print the URLs of a chunk or the chunk of a URL without writing files")
+	flags := c.flags
+	number := flags.Int("chunk", 0, "print the locs of this chunk, counting from 1 in index order")
+	loc := flags.String("url", "", "print the name of the chunk holding this loc")
+	newSplitter := addSplitFlags(flags)
+	c.run = func(args []string) int {
+		flags.Parse(args)
+
+		if (*number == 0) == (*loc == "") {
+			flags.Usage()
+			return 2
+		}
+		splitter, _, _, ok := newSplitter()
+		if !ok {
+			return 2
+		}
+		chunks, err := splitter.Preview()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "sitemap-splitter: %v\n", err)
+			return 1
+		}
+
+		if *number != 0 {
+			if *number < 0 || *number > len(chunks) {
+				fmt.Fprintf(os.Stderr, "sitemap-splitter: no chunk %d, the split has %d\n", *number, len(chunks))
+				return 1
+			}
+			for _, l := range chunks[*number-1].Locs {
+				fmt.Println(l)
+			}
+			return 0
+		}
+		for i, ch := range chunks {
+			for _, l := range ch.Locs {
+				if l == *loc {
+					fmt.Printf("%d\t%s\n", i+1, ch.Name)
+					return 0
+				}
+			}
+		}
+		fmt.Fprintf(os.Stderr, "sitemap-splitter: %s is not in any chunk\n", *loc)
+		return 1
+	}
+	return c
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	sitemapsplitter "github.com/choirulanwar/sitemap-splitter"
+)
+
+// rollbackCommand creates the rollback subcommand, which replaces the
+// sitemaps in -out with the most recent backup in -backup-dir
+func rollbackCommand() *command {
+	c := newCommand("rollback", "-out <dir> -backup-dir <dir>", "replace the published sitemaps with the most recent backup")
+	flags := c.flags
+	outputDir := flags.String("out", "", "directory holding the published sitemaps")
+	backupDir := flags.String("backup-dir", "", "directory the backups were written to with -backup-dir")
+	gzipOutput := flags.Bool("gzip", false, "the sitemaps and index were written gzip-compressed")
+	keyFile := flags.String("key-file", "", "file holding the hex-encoded AES key the output was encrypted with")
+	c.run = func(args []string) int {
+		flags.Parse(args)
+
+		if *outputDir == "" || *backupDir == "" || flags.NArg() > 0 {
+			flags.Usage()
+			return 2
+		}
+
+		opts := []sitemapsplitter.Option{
+			sitemapsplitter.WithOutputDir(*outputDir),
+			sitemapsplitter.WithBackup(*backupDir, 1),
+			sitemapsplitter.WithGzipOutput(*gzipOutput),
+		}
+		if *keyFile != "" {
+			key, err := readKey(*keyFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "sitemap-splitter: %v\n", err)
+				return 2
+			}
+			opts = append(opts, sitemapsplitter.WithEncryption(key))
+		}
+
+		// No input is read, so the output directory stands in for the path
+		splitter, err := sitemapsplitter.NewSitemapSplitter(*outputDir, 1, opts...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "sitemap-splitter: %v\n", err)
+			return 2
+		}
+		if err := splitter.Rollback(); err != nil {
+			fmt.Fprintf(os.Stderr, "sitemap-splitter: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	return c
+}
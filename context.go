@@ -0,0 +1,115 @@
+package sitemapsplitter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// PhaseTimeouts bounds the phases of a split on top of the context passed
+// to SplitContext, each measured from the start of the phase. Zero fields
+// leave a phase unbounded.
+type PhaseTimeouts struct {
+	Fetch  time.Duration // Each HTTP request, including reading its body
+	Parse  time.Duration // Reading and decoding the whole input
+	Filter time.Duration // Filtering and preparing the URLs read
+	Plan   time.Duration // Planning the chunks and checking the plan
+	Write  time.Duration // Encoding and storing the chunks, index and other outputs
+	Upload time.Duration // Handing each file to the sink
+}
+
+// SplitContext is like Split but stops once ctx is done. Cancellation is
+// checked between URLs and before every chunk and the index are written, and
+// aborts HTTP requests in flight. Chunks written before cancellation remain
+// in place, but the index, delta and state of the previous run are kept. The
+// returned error wraps ctx.Err().
+func (s *SitemapSplitter) SplitContext(ctx context.Context) error {
+	if ctx == nil {
+		return fmt.Errorf("context must not be nil")
+	}
+
+	s.ctx = ctx
+	defer func() { s.ctx = nil }()
+	var err error
+	if isRemote(s.path) {
+		err = s.splitRemote()
+	} else {
+		err = s.split()
+	}
+
+	// Errors caused by the cancellation, such as an aborted download, are
+	// reported as the cancellation itself
+	if err != nil {
+		if cerr := s.canceled(); cerr != nil {
+			err = cerr
+		}
+	}
+	s.observe(err)
+	return err
+}
+
+// canceled returns an error if the context of the run in progress is done
+func (s *SitemapSplitter) canceled() error {
+	if s.ctx == nil {
+		return nil
+	}
+	if s.ctx.Err() != nil {
+		return fmt.Errorf("split canceled: %w", context.Cause(s.ctx))
+	}
+	return nil
+}
+
+// phaseTimeout returns the cause a phase context ends with once timeout
+// has passed, wrapping context.DeadlineExceeded
+func phaseTimeout(phase string, timeout time.Duration) error {
+	return fmt.Errorf("%s phase exceeded its %v timeout: %w", phase, timeout, context.DeadlineExceeded)
+}
+
+// enterPhase makes a context derived from the current one with the given
+// timeout the context of the split until the returned func is called. A
+// zero timeout leaves the context alone.
+func (s *SitemapSplitter) enterPhase(phase string, timeout time.Duration) func() {
+	if timeout <= 0 {
+		return func() {}
+	}
+	parent := s.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithTimeoutCause(parent, timeout, phaseTimeout(phase, timeout))
+	prev := s.ctx
+	s.ctx = ctx
+	return func() {
+		cancel()
+		s.ctx = prev
+	}
+}
+
+// releaseBody is a response body that frees the host slot of its request
+// once closed
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+// Close closes the body and frees the host slot of its request
+func (b releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
+
+// cancelBody is a response body that releases the context of its request
+// when closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the body and releases the context of its request
+func (b cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
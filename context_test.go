@@ -0,0 +1,179 @@
+package sitemapsplitter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowReader returns at most one byte per read of r, after waiting delay
+type slowReader struct {
+	r     io.Reader
+	delay time.Duration
+}
+
+func (s slowReader) Read(p []byte) (int, error) {
+	time.Sleep(s.delay)
+	return s.r.Read(p[:min(len(p), 1)])
+}
+
+// slowSink waits delay before each write to the wrapped sink
+type slowSink struct {
+	Sink
+	delay time.Duration
+}
+
+func (s slowSink) Write(name string, r io.Reader) error {
+	time.Sleep(s.delay)
+	return s.Sink.Write(name, r)
+}
+
+func TestPhaseTimeouts(t *testing.T) {
+	const slow = 50 * time.Millisecond
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(slow)
+		fmt.Fprint(w, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc></url></urlset>`)
+	}))
+	defer srv.Close()
+	input := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc></url></urlset>`
+
+	tests := []struct {
+		name     string
+		remote   bool          // Fetch the input from the slow server
+		read     time.Duration // Delay of every read of local input
+		sink     time.Duration // Delay of every sink write
+		timeouts PhaseTimeouts
+		wantErr  string // Phase named by the error, empty for success
+	}{
+		{"fetch", true, 0, 0, PhaseTimeouts{Fetch: slow / 5}, "fetch phase"},
+		{"parse", false, time.Millisecond, 0, PhaseTimeouts{Parse: slow / 5}, "parse phase"},
+		{"upload", false, 0, slow, PhaseTimeouts{Upload: slow / 5}, "upload phase"},
+		{"write", false, 0, slow, PhaseTimeouts{Write: slow / 5}, "write phase"},
+		{"within budget", true, 0, 0, PhaseTimeouts{Fetch: time.Minute, Parse: time.Minute, Filter: time.Minute, Plan: time.Minute, Write: time.Minute, Upload: time.Minute}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "in.xml")
+			if tt.remote {
+				path = srv.URL + "/in.xml"
+			}
+			var sink Sink = NewMemorySink()
+			if tt.sink > 0 {
+				sink = slowSink{sink, tt.sink}
+			}
+			s, err := NewSitemapSplitter(path, 1, WithSink(sink), WithPhaseTimeouts(tt.timeouts))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.remote {
+				err = s.SplitContext(context.Background())
+			} else {
+				err = s.SplitFrom(slowReader{strings.NewReader(input), tt.read})
+			}
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got error %v, want one naming the %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPhaseTimeoutsStreaming(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeouts PhaseTimeouts
+		wantErr  bool
+	}{
+		{"fetch and upload", PhaseTimeouts{Fetch: time.Second, Upload: time.Second}, false},
+		{"parse", PhaseTimeouts{Parse: time.Second}, true},
+		{"write", PhaseTimeouts{Write: time.Second}, true},
+		{"negative", PhaseTimeouts{Upload: -time.Second}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSitemapSplitter("in.xml", 1, WithStreaming(), WithPhaseTimeouts(tt.timeouts))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSplitContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"></urlset>`))
+	}))
+	defer srv.Close()
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name   string
+		remote bool
+		ctx    func() (context.Context, context.CancelFunc)
+		want   error // Error the split wraps, nil if it succeeds
+	}{
+		{"background", false, func() (context.Context, context.CancelFunc) { return context.Background(), func() {} }, nil},
+		{"canceled", false, func() (context.Context, context.CancelFunc) { return canceled, func() {} }, context.Canceled},
+		{"deadline during download", true, func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 20*time.Millisecond)
+		}, context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			input := filepath.Join(dir, "in.xml")
+			writeSitemap(t, input, "https://example.com/a", "https://example.com/b")
+			if tt.remote {
+				input = srv.URL + "/in.xml"
+			}
+			index := filepath.Join(dir, "sitemap-index.xml")
+			if err := os.WriteFile(index, []byte("previous"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			s, err := NewSitemapSplitter(input, 1, WithOutputDir(dir))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			err = s.SplitContext(ctx)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got error %v, want %v", err, tt.want)
+			}
+			if !fileContains(index, "previous") {
+				t.Error("canceled split replaced the index")
+			}
+		})
+	}
+
+	s, err := NewSitemapSplitter("in.xml", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SplitContext(nil); err == nil {
+		t.Error("nil context accepted")
+	}
+}
@@ -0,0 +1,123 @@
+package sitemapsplitter
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// DecodeLimits caps the structure of decoded input so that a malformed or
+// hostile file cannot exhaust memory or wedge the process. Zero fields mean
+// no limit.
+type DecodeLimits struct {
+	MaxDepth      int // Maximum element nesting depth
+	MaxAttributes int // Maximum number of attributes on a single element
+	MaxTokenBytes int // Maximum size in bytes of a single token (tag, text, comment)
+}
+
+// Tokenizer creates a source of raw XML tokens for r, allowing a faster
+// third-party tokenizer to replace encoding/xml's. Tokens must be returned the
+// way xml.Decoder.RawToken returns them, with namespace prefixes unresolved;
+// namespace resolution and URL decoding happen on top of them.
+type Tokenizer func(r io.Reader) xml.TokenReader
+
+// newDecoder returns a decoder for r that uses the configured tokenizer and
+// enforces the configured limits
+func (s *SitemapSplitter) newDecoder(r io.Reader) *xml.Decoder {
+	limits := s.decodeLimits
+	if limits == (DecodeLimits{}) {
+		if s.tokenizer == nil {
+			return xml.NewDecoder(r)
+		}
+		return xml.NewTokenDecoder(s.tokenizer(r))
+	}
+
+	counter := &countingReader{r: bufio.NewReader(r), max: limits.MaxTokenBytes}
+	return xml.NewTokenDecoder(&limitedTokenReader{
+		tokens:  s.rawTokens(counter),
+		counter: counter,
+		limits:  limits,
+	})
+}
+
+// rawTokens returns the raw token source for r
+func (s *SitemapSplitter) rawTokens(r io.Reader) xml.TokenReader {
+	if s.tokenizer != nil {
+		return s.tokenizer(r)
+	}
+	return rawTokenReader{xml.NewDecoder(r)}
+}
+
+// rawTokenReader adapts xml.Decoder.RawToken to the TokenReader interface
+type rawTokenReader struct {
+	d *xml.Decoder
+}
+
+func (r rawTokenReader) Token() (xml.Token, error) {
+	return r.d.RawToken()
+}
+
+// countingReader counts bytes read since the last reset and fails once the
+// count exceeds max, bounding the memory a single token can consume
+type countingReader struct {
+	r   *bufio.Reader
+	n   int
+	max int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	if err == nil {
+		err = c.check()
+	}
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err != nil {
+		return b, err
+	}
+	c.n++
+	return b, c.check()
+}
+
+func (c *countingReader) check() error {
+	if c.max > 0 && c.n > c.max {
+		return fmt.Errorf("token exceeds size limit of %d bytes", c.max)
+	}
+	return nil
+}
+
+// limitedTokenReader passes raw tokens through while enforcing depth and
+// attribute limits. Namespace resolution is left to the wrapping Decoder.
+type limitedTokenReader struct {
+	tokens  xml.TokenReader
+	counter *countingReader
+	limits  DecodeLimits
+	depth   int
+}
+
+func (l *limitedTokenReader) Token() (xml.Token, error) {
+	tok, err := l.tokens.Token()
+	l.counter.n = 0
+	if err != nil {
+		return tok, err
+	}
+
+	switch t := tok.(type) {
+	case xml.StartElement:
+		l.depth++
+		if l.limits.MaxDepth > 0 && l.depth > l.limits.MaxDepth {
+			return nil, fmt.Errorf("element <%s> exceeds nesting depth limit of %d", t.Name.Local, l.limits.MaxDepth)
+		}
+		if l.limits.MaxAttributes > 0 && len(t.Attr) > l.limits.MaxAttributes {
+			return nil, fmt.Errorf("element <%s> has %d attributes, exceeding the limit of %d", t.Name.Local, len(t.Attr), l.limits.MaxAttributes)
+		}
+	case xml.EndElement:
+		l.depth--
+	}
+	return tok, nil
+}
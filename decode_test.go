@@ -0,0 +1,112 @@
+package sitemapsplitter
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDecodeLimits(t *testing.T) {
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:image="` + ImageNamespace + `">` +
+		`<url><loc>https://example.com/` + strings.Repeat("a", 200) + `</loc>` +
+		`<image:image><image:loc>https://example.com/a.jpg</image:loc></image:image></url>` +
+		`<url a="1" b="2" c="3"><loc>https://example.com/b</loc></url></urlset>`
+	tests := []struct {
+		name    string
+		limits  DecodeLimits
+		wantErr string
+	}{
+		{"within limits", DecodeLimits{MaxDepth: 4, MaxAttributes: 3, MaxTokenBytes: 1024}, ""},
+		{"depth", DecodeLimits{MaxDepth: 3}, "nesting depth limit of 3"},
+		{"attributes", DecodeLimits{MaxAttributes: 2}, "exceeding the limit of 2"},
+		{"token size", DecodeLimits{MaxTokenBytes: 100}, "size limit of 100 bytes"},
+	}
+	for _, tt := range tests {
+		for _, streaming := range []bool{false, true} {
+			name := tt.name
+			if streaming {
+				name += " streaming"
+			}
+			t.Run(name, func(t *testing.T) {
+				opts := []Option{WithSink(NewMemorySink()), WithDecodeLimits(tt.limits)}
+				if streaming {
+					opts = append(opts, WithStreaming())
+				}
+				s, err := NewSitemapSplitter("in.xml", 50000, opts...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = s.SplitFrom(strings.NewReader(in))
+				switch {
+				case tt.wantErr == "" && err != nil:
+					t.Errorf("split failed: %v", err)
+				case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+					t.Errorf("split error %v, want one mentioning %q", err, tt.wantErr)
+				}
+			})
+		}
+	}
+}
+
+// countingTokens is a raw token source that counts the tokens it returns
+type countingTokens struct {
+	d *xml.Decoder
+	n *int
+}
+
+func (c countingTokens) Token() (xml.Token, error) {
+	tok, err := c.d.RawToken()
+	if err == nil {
+		*c.n++
+	}
+	return tok, err
+}
+
+func TestTokenizer(t *testing.T) {
+	in := streamInput(20)
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr bool
+	}{
+		{"default", nil, false},
+		{"streaming", []Option{WithStreaming()}, false},
+		{"with limits", []Option{WithDecodeLimits(DecodeLimits{MaxDepth: 4, MaxTokenBytes: 4096})}, false},
+		{"limit exceeded", []Option{WithDecodeLimits(DecodeLimits{MaxDepth: 2})}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			split := func(opts ...Option) *MemorySink {
+				sink := NewMemorySink()
+				s, err := NewSitemapSplitter("in.xml", 7, append(append([]Option{WithSink(sink)}, tt.opts...), opts...)...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := s.SplitFrom(strings.NewReader(in)); (err != nil) != tt.wantErr {
+					t.Fatalf("split error %v, want error %v", err, tt.wantErr)
+				}
+				return sink
+			}
+			var tokens int
+			tokenizer := func(r io.Reader) xml.TokenReader { return countingTokens{xml.NewDecoder(r), &tokens} }
+
+			want, got := split(), split(WithTokenizer(tokenizer))
+			if tokens == 0 {
+				t.Fatal("tokenizer not used")
+			}
+			if !slices.Equal(got.Names(), want.Names()) {
+				t.Fatalf("tokenizer wrote %v, want %v", got.Names(), want.Names())
+			}
+			for _, name := range want.Names() {
+				a, _ := want.File(name)
+				b, _ := got.File(name)
+				if !bytes.Equal(a, b) {
+					t.Errorf("%s differs with the tokenizer:\n%s\nwant:\n%s", name, b, a)
+				}
+			}
+		})
+	}
+}
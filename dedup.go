@@ -0,0 +1,263 @@
+package sitemapsplitter
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DedupStore records which locs have already been published so duplicates
+// can be dropped across runs and feeds. A run only looks locs up while
+// filtering and adds the locs it wrote once its output is published, so a
+// failed run, a dry run or a preview leaves the store unchanged.
+// Implementations can be backed by an external key-value store such as Redis
+// (EXISTS and SETNX map directly onto Seen and Add).
+type DedupStore interface {
+	// Seen reports whether loc has been recorded
+	Seen(loc string) (bool, error)
+	// Add records loc and reports whether it had not been seen before
+	Add(loc string) (bool, error)
+}
+
+// MemoryDedupStore is an in-process DedupStore. It is safe for concurrent use
+// and can be shared between splitters within the same process.
+type MemoryDedupStore struct {
+	mu   sync.Mutex
+	seen map[string]struct{}
+}
+
+// NewMemoryDedupStore creates an empty MemoryDedupStore
+func NewMemoryDedupStore() *MemoryDedupStore {
+	return &MemoryDedupStore{seen: make(map[string]struct{})}
+}
+
+// Add records loc and reports whether it had not been seen before
+func (m *MemoryDedupStore) Add(loc string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.seen[loc]; ok {
+		return false, nil
+	}
+	m.seen[loc] = struct{}{}
+	return true, nil
+}
+
+// Seen reports whether loc has been recorded
+func (m *MemoryDedupStore) Seen(loc string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.seen[loc]
+	return ok, nil
+}
+
+// isDuplicate reports whether loc was already kept earlier in the run or the
+// configured store has seen it. The store is only looked up; the locs a run
+// writes are added to it by addPublished.
+func (s *SitemapSplitter) isDuplicate(loc string) (bool, error) {
+	if s.dedupStore == nil {
+		return false, nil
+	}
+
+	if _, ok := s.dedupSeen[loc]; ok {
+		return true, nil
+	}
+	s.dedupSeen[loc] = struct{}{}
+	seen, err := s.dedupStore.Seen(loc)
+	if err != nil {
+		return false, fmt.Errorf("error checking dedup store: %v", err)
+	}
+	return seen, nil
+}
+
+// addPublished records the locs of a published run in the dedup store
+func (s *SitemapSplitter) addPublished(locs []string) error {
+	for _, loc := range locs {
+		if _, err := s.dedupStore.Add(loc); err != nil {
+			return fmt.Errorf("error updating dedup store: %v", err)
+		}
+	}
+	return nil
+}
+
+// unpublished reports whether the dedup store has not seen the loc of u yet,
+// recording a URL it has seen as a dropped duplicate
+func (s *SitemapSplitter) unpublished(u URL) (bool, error) {
+	dup, err := s.isDuplicate(u.Loc)
+	if err != nil {
+		return false, err
+	}
+	if dup {
+		s.skip(u, SkipDuplicate)
+		s.warn(u.Loc, WarnDuplicateDropped)
+		return false, nil
+	}
+	return true, nil
+}
+
+// DedupPolicy controls which entry is kept when a loc occurs more than
+// once in the input
+type DedupPolicy int
+
+const (
+	// DedupKeepAll writes every entry, duplicates included
+	DedupKeepAll DedupPolicy = iota
+	// DedupFirst keeps the first entry of each loc
+	DedupFirst
+	// DedupNewest keeps the entry with the newest lastmod. Entries
+	// without a parsable lastmod count as older than any with one. Extensions
+	// and attributes only the dropped entries have are merged into it.
+	DedupNewest
+	// DedupHighestPriority keeps the entry with the highest priority,
+	// counting a missing priority as the protocol default of 0.5. Extensions
+	// and attributes only the dropped entries have are merged into it.
+	DedupHighestPriority
+	// DedupMerge keeps the first entry and fills in the fields, extensions
+	// and attributes it lacks from later entries
+	DedupMerge
+)
+
+// resolveDuplicates collapses entries sharing a loc into one according to
+// the duplicate policy. The resolved entry takes the position of the first
+// occurrence. Entries whose lastmod, changefreq or priority disagree with
+// the entry kept so far are reported as conflicts.
+func (s *SitemapSplitter) resolveDuplicates(urls []URL) []URL {
+	if s.dupPolicy == DedupKeepAll {
+		return urls
+	}
+
+	first := make(map[string]int, len(urls))
+	kept := urls[:0]
+	for _, u := range urls {
+		i, seen := first[u.Loc]
+		if !seen {
+			first[u.Loc] = len(kept)
+			kept = append(kept, u)
+			continue
+		}
+
+		if conflicting(kept[i], u) {
+			s.warn(u.Loc, WarnDuplicateConflict)
+		}
+		s.skip(u, SkipDuplicate)
+		s.warn(u.Loc, WarnDuplicateDropped)
+
+		switch s.dupPolicy {
+		case DedupNewest:
+			if s.newerLastMod(u, kept[i]) {
+				kept[i], u = u, kept[i]
+			}
+			mergeChildren(&kept[i], u)
+		case DedupHighestPriority:
+			if priorityValue(u) > priorityValue(kept[i]) {
+				kept[i], u = u, kept[i]
+			}
+			mergeChildren(&kept[i], u)
+		case DedupMerge:
+			mergeURL(&kept[i], u)
+		}
+	}
+	return kept
+}
+
+// conflicting reports whether a and b carry different values for a field
+// both of them set
+func conflicting(a, b URL) bool {
+	differ := func(x, y string) bool { return x != "" && y != "" && x != y }
+	return differ(a.LastMod, b.LastMod) || differ(a.ChangeFreq, b.ChangeFreq) || differ(a.Priority, b.Priority)
+}
+
+// newerLastMod reports whether the lastmod of a is newer than that of b
+func (s *SitemapSplitter) newerLastMod(a, b URL) bool {
+	ta, okA := parseLastMod(a.LastMod, s.location)
+	tb, okB := parseLastMod(b.LastMod, s.location)
+	switch {
+	case !okA:
+		return false
+	case !okB:
+		return true
+	}
+	return ta.After(tb)
+}
+
+// priorityValue returns the priority of u, or 0.5 if it is missing or
+// cannot be parsed
+func priorityValue(u URL) float64 {
+	p, err := strconv.ParseFloat(u.Priority, 64)
+	if err != nil {
+		return 0.5
+	}
+	return p
+}
+
+// mergeURL fills the empty fields of dst from src
+func mergeURL(dst *URL, src URL) {
+	if dst.LastMod == "" {
+		dst.LastMod = src.LastMod
+	}
+	if dst.ChangeFreq == "" {
+		dst.ChangeFreq = src.ChangeFreq
+	}
+	if dst.Priority == "" {
+		dst.Priority = src.Priority
+	}
+	mergeChildren(dst, src)
+	if dst.sourceModTime == (time.Time{}) {
+		dst.sourceModTime = src.sourceModTime
+	}
+	if dst.childModTime.IsZero() {
+		dst.childModTime = src.childModTime
+	}
+}
+
+// mergeChildren adds the extensions of src in namespaces dst has none of, and
+// the attributes dst lacks, to dst. Attributes whose prefix dst binds to
+// another namespace are left out.
+func mergeChildren(dst *URL, src URL) {
+	have := make(map[string]bool, len(dst.Extensions))
+	for _, ext := range dst.Extensions {
+		have[ext.Namespace] = true
+	}
+	for _, ext := range src.Extensions {
+		if !have[ext.Namespace] {
+			dst.Extensions = append(dst.Extensions, ext)
+		}
+	}
+
+	dst.Attrs = mergeAttrs(dst.Attrs, src.Attrs)
+	dst.LocAttrs = mergeAttrs(dst.LocAttrs, src.LocAttrs)
+}
+
+// mergeAttrs returns dst with the attributes of src it has no attribute of
+// the same name for
+func mergeAttrs(dst, src []xml.Attr) []xml.Attr {
+	merged := dst
+	for _, attr := range src {
+		if findAttr(dst, attr.Name) != nil {
+			continue
+		}
+		if prefix, _, ok := strings.Cut(attr.Name.Local, ":"); ok && prefix != "xml" && prefix != "xmlns" {
+			decl := xml.Name{Local: "xmlns:" + prefix}
+			own, theirs := findAttr(dst, decl), findAttr(src, decl)
+			if own != nil && (theirs == nil || own.Value != theirs.Value) {
+				continue
+			}
+		}
+		merged = append(merged, attr)
+	}
+	return merged
+}
+
+// findAttr returns the attribute of attrs named name, or nil
+func findAttr(attrs []xml.Attr, name xml.Name) *xml.Attr {
+	for i := range attrs {
+		if attrs[i].Name == name {
+			return &attrs[i]
+		}
+	}
+	return nil
+}
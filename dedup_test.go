@@ -0,0 +1,233 @@
+package sitemapsplitter
+
+import (
+	"encoding/xml"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDedupKeepsChildrenOfDroppedEntries(t *testing.T) {
+	const older = `<url acme:id="7"><loc>https://example.com/a</loc><lastmod>2024-01-01</lastmod>` +
+		`<image:image><image:loc>https://example.com/a.jpg</image:loc></image:image>` +
+		`<video:video><video:title>Intro</video:title></video:video>` +
+		`<xhtml:link rel="alternate" hreflang="de" href="https://example.com/de/a"/></url>`
+	const newer = `<url><loc>https://example.com/a</loc><lastmod>2024-06-01</lastmod><priority>0.9</priority></url>`
+
+	tests := []struct {
+		name   string
+		policy DedupPolicy
+		urls   string
+		want   []string
+	}{
+		{"newest after", DedupNewest, older + newer, []string{"<lastmod>2024-06-01</lastmod>"}},
+		{"newest before", DedupNewest, newer + older, []string{"<lastmod>2024-06-01</lastmod>"}},
+		{"highest priority", DedupHighestPriority, older + newer, []string{"<priority>0.9</priority>"}},
+		{"merge", DedupMerge, newer + older, []string{"<lastmod>2024-06-01</lastmod>"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:acme="urn:acme"` +
+				` xmlns:image="http://www.google.com/schemas/sitemap-image/1.1"` +
+				` xmlns:video="http://www.google.com/schemas/sitemap-video/1.1"` +
+				` xmlns:xhtml="http://www.w3.org/1999/xhtml">` + tt.urls + `</urlset>`
+			out := splitString(t, in, WithDedupPolicy(tt.policy))
+			if n := strings.Count(out, "<loc>https://example.com/a</loc>"); n != 1 {
+				t.Fatalf("got %d entries, want 1:\n%s", n, out)
+			}
+			want := append(tt.want, `acme:id="7"`, "<image:loc>https://example.com/a.jpg</image:loc>",
+				"<video:title>Intro</video:title>", `hreflang="de"`)
+			for _, w := range want {
+				if !strings.Contains(out, w) {
+					t.Errorf("output lacks %s:\n%s", w, out)
+				}
+			}
+		})
+	}
+}
+
+// brokenDedupStore fails every lookup, like an unreachable Redis
+type brokenDedupStore struct{}
+
+func (brokenDedupStore) Seen(string) (bool, error) { return false, errors.New("connection refused") }
+func (brokenDedupStore) Add(string) (bool, error)  { return false, errors.New("connection refused") }
+
+func TestDedupStore(t *testing.T) {
+	urlset := func(paths ...string) string {
+		var b strings.Builder
+		b.WriteString(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+		for _, p := range paths {
+			b.WriteString("<url><loc>https://example.com/" + p + "</loc></url>")
+		}
+		b.WriteString("</urlset>")
+		return b.String()
+	}
+	tests := []struct {
+		name    string
+		runs    []string // Inputs of consecutive runs sharing the store
+		want    int      // URLs written by the last run
+		skipped int      // Duplicates dropped by the last run
+	}{
+		{"within a run", []string{urlset("a", "b", "a")}, 2, 1},
+		{"across runs", []string{urlset("a", "b"), urlset("b", "c")}, 1, 1},
+		{"seen before and within", []string{urlset("a"), urlset("a", "b", "a")}, 1, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryDedupStore()
+			var s *SitemapSplitter
+			for _, in := range tt.runs {
+				var err error
+				s, err = NewSitemapSplitter("in.xml", 50000, WithSink(NewMemorySink()), WithDedupStore(store))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			result := s.LastResult()
+			if result.URLs != tt.want {
+				t.Errorf("wrote %d URLs, want %d", result.URLs, tt.want)
+			}
+			if got := result.Skipped[SkipDuplicate]; got != tt.skipped {
+				t.Errorf("skipped %d duplicates, want %d", got, tt.skipped)
+			}
+		})
+	}
+
+	t.Run("failing store", func(t *testing.T) {
+		s, err := NewSitemapSplitter("in.xml", 50000, WithSink(NewMemorySink()), WithDedupStore(brokenDedupStore{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SplitFrom(strings.NewReader(urlset("a"))); err == nil || !strings.Contains(err.Error(), "connection refused") {
+			t.Errorf("split error %v, want the store's error", err)
+		}
+	})
+}
+
+func TestDedupPolicy(t *testing.T) {
+	in := []URL{
+		{Loc: "https://example.com/a", LastMod: "2024-01-01", Priority: "0.9"},
+		{Loc: "https://example.com/b"},
+		{Loc: "https://example.com/a", LastMod: "2024-03-01", ChangeFreq: "daily"},
+		{Loc: "https://example.com/a", Priority: "1.0"},
+	}
+	tests := []struct {
+		name          string
+		policy        DedupPolicy
+		want          []URL
+		wantConflicts int // Conflicts with the entry kept at the time
+	}{
+		{"keep all", DedupKeepAll, in, 0},
+		{"first", DedupFirst, []URL{in[0], in[1]}, 2},
+		{"newest", DedupNewest, []URL{in[2], in[1]}, 1},
+		{"highest priority", DedupHighestPriority, []URL{in[3], in[1]}, 2},
+		{"merge", DedupMerge, []URL{{Loc: "https://example.com/a", LastMod: "2024-01-01", ChangeFreq: "daily", Priority: "0.9"}, in[1]}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSitemapSplitter("in.xml", 10, WithDedupPolicy(tt.policy))
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.reset()
+
+			got := s.resolveDuplicates(slices.Clone(in))
+			if len(got) != len(tt.want) {
+				t.Fatalf("kept %d entries, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				g, w := got[i], tt.want[i]
+				if g.Loc != w.Loc || g.LastMod != w.LastMod || g.ChangeFreq != w.ChangeFreq || g.Priority != w.Priority {
+					t.Errorf("entry %d is %+v, want %+v", i, g, w)
+				}
+			}
+			if n := s.result.Skipped[SkipDuplicate]; n != len(in)-len(tt.want) {
+				t.Errorf("%d duplicates skipped, want %d", n, len(in)-len(tt.want))
+			}
+			if n := s.result.WarningCounts[WarnDuplicateConflict]; n != tt.wantConflicts {
+				t.Errorf("%d conflicts, want %d", n, tt.wantConflicts)
+			}
+		})
+	}
+
+	if _, err := NewSitemapSplitter("in.xml", 10, WithDedupPolicy(DedupFirst), WithStreaming()); err == nil {
+		t.Error("dedup policy accepted with streaming")
+	}
+}
+
+func TestDedupStoreWithPolicy(t *testing.T) {
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` +
+		`<url><loc>https://example.com/a</loc><lastmod>2024-01-01</lastmod></url>` +
+		`<url><loc>https://example.com/b</loc></url>` +
+		`<url><loc>https://example.com/a</loc><lastmod>2024-03-01</lastmod></url>` +
+		`</urlset>`
+	store := NewMemoryDedupStore()
+	if _, err := store.Add("https://example.com/b"); err != nil {
+		t.Fatal(err)
+	}
+	sink := NewMemorySink()
+	s, err := NewSitemapSplitter("in.xml", 50000, WithSink(sink), WithDedupStore(store), WithDedupPolicy(DedupNewest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The policy picks the newest entry of a before the store records it,
+	// and the store still drops b, published before
+	data, _ := sink.File("in-1.xml")
+	var set URLSet
+	if err := xml.Unmarshal(data, &set); err != nil {
+		t.Fatal(err)
+	}
+	if len(set.URLs) != 1 || set.URLs[0].Loc != "https://example.com/a" || set.URLs[0].LastMod != "2024-03-01" {
+		t.Errorf("wrote %+v, want only the newest entry of a", set.URLs)
+	}
+	if got := s.LastResult().Skipped[SkipDuplicate]; got != 2 {
+		t.Errorf("skipped %d duplicates, want 2", got)
+	}
+	if seen, _ := store.Seen("https://example.com/a"); !seen {
+		t.Error("store did not record the kept entry")
+	}
+}
+
+func TestDedupStoreFailedRun(t *testing.T) {
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` +
+		`<url><loc>https://example.com/a</loc></url>` +
+		`<url><loc>https://example.com/b</loc></url>` +
+		`<url><loc>https://example.com/c</loc></url>` +
+		`</urlset>`
+	store := NewMemoryDedupStore()
+	s, err := NewSitemapSplitter("in.xml", 1, WithSink(NewMemorySink()), WithDedupStore(store), WithMaxFiles(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SplitFrom(strings.NewReader(in)); err == nil {
+		t.Fatal("run exceeding the file limit succeeded")
+	}
+	if seen, _ := store.Seen("https://example.com/a"); seen {
+		t.Error("failed run recorded its locs in the store")
+	}
+
+	// The retry publishes everything and only then records it
+	s, err = NewSitemapSplitter("in.xml", 1, WithSink(NewMemorySink()), WithDedupStore(store))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	if r := s.LastResult(); r.URLs != 3 {
+		t.Errorf("retry wrote %d URLs, want 3", r.URLs)
+	}
+	for _, loc := range []string{"https://example.com/a", "https://example.com/b", "https://example.com/c"} {
+		if seen, _ := store.Seen(loc); !seen {
+			t.Errorf("published %s not recorded in the store", loc)
+		}
+	}
+}
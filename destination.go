@@ -0,0 +1,76 @@
+package sitemapsplitter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// DestinationOpener creates the sink for the path of a destination string,
+// the part after "scheme:", e.g. "bucket/prefix" for "s3:bucket/prefix"
+type DestinationOpener func(path string) (Sink, error)
+
+var (
+	destinationsMu sync.RWMutex
+	destinations   = make(map[string]DestinationOpener)
+)
+
+// destinationScheme matches the scheme of an rclone-style destination. It
+// needs at least two characters, so Windows drive letters stay local paths.
+var destinationScheme = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9+.-]+):`)
+
+// RegisterDestination makes destinations of the form "scheme:path" open a
+// sink with open, e.g. "s3" for an S3 sink. Registering a scheme that already
+// has an opener replaces it. The scheme "local" always names a local
+// directory and cannot be registered.
+func RegisterDestination(scheme string, open DestinationOpener) {
+	if open == nil {
+		panic("sitemapsplitter: RegisterDestination opener is nil")
+	}
+	if strings.EqualFold(scheme, "local") {
+		panic("sitemapsplitter: RegisterDestination scheme local is reserved")
+	}
+
+	destinationsMu.Lock()
+	defer destinationsMu.Unlock()
+	destinations[strings.ToLower(scheme)] = open
+}
+
+// lookupDestination returns the opener registered for scheme, if any
+func lookupDestination(scheme string) DestinationOpener {
+	destinationsMu.RLock()
+	defer destinationsMu.RUnlock()
+	return destinations[scheme]
+}
+
+// parseDestination splits dest into its lowercased scheme and path. A dest
+// without a scheme is a local directory and has an empty scheme.
+func parseDestination(dest string) (scheme, path string) {
+	m := destinationScheme.FindStringSubmatch(dest)
+	if m == nil {
+		return "", dest
+	}
+	return strings.ToLower(m[1]), dest[len(m[0]):]
+}
+
+// openDestination resolves the configured destination into the output
+// directory of the default file sink or a registered sink
+func (s *SitemapSplitter) openDestination() error {
+	scheme, path := parseDestination(s.destination)
+	if scheme == "" || scheme == "local" {
+		s.outputDir = path
+		return nil
+	}
+
+	open := lookupDestination(scheme)
+	if open == nil {
+		return fmt.Errorf("no sink registered for %s destinations such as %q", scheme, s.destination)
+	}
+	sink, err := open(path)
+	if err != nil {
+		return fmt.Errorf("error opening destination %q: %v", s.destination, err)
+	}
+	s.sink = sink
+	return nil
+}
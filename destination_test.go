@@ -0,0 +1,77 @@
+package sitemapsplitter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseDestination(t *testing.T) {
+	tests := []struct {
+		dest, scheme, path string
+	}{
+		{"s3:bucket/prefix", "s3", "bucket/prefix"},
+		{"GCS:bucket/path", "gcs", "bucket/path"},
+		{"local:out/sitemaps", "local", "out/sitemaps"},
+		{"out/sitemaps", "", "out/sitemaps"},
+		{`C:\sitemaps`, "", `C:\sitemaps`},
+		{"./name:x", "", "./name:x"},
+		{"/srv/name:x", "", "/srv/name:x"},
+	}
+	for _, tt := range tests {
+		if scheme, path := parseDestination(tt.dest); scheme != tt.scheme || path != tt.path {
+			t.Errorf("parseDestination(%q) = %q, %q, want %q, %q", tt.dest, scheme, path, tt.scheme, tt.path)
+		}
+	}
+}
+
+func TestDestination(t *testing.T) {
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc></url></urlset>`
+	sink := NewMemorySink()
+	var opened []string
+	RegisterDestination("memtest", func(path string) (Sink, error) {
+		if path == "broken" {
+			return nil, errors.New("bucket not found")
+		}
+		opened = append(opened, path)
+		return sink, nil
+	})
+
+	s, err := NewSitemapSplitter("in.xml", 10, WithDestination("memtest:bucket/prefix"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(opened, []string{"bucket/prefix"}) {
+		t.Errorf("opened %v, want the path after the scheme", opened)
+	}
+	if want := []string{"in-1.xml", "sitemap-index.xml"}; !slices.Equal(sink.Names(), want) {
+		t.Errorf("sink holds %v, want %v", sink.Names(), want)
+	}
+
+	for _, dest := range []string{"local:", ""} {
+		dir := filepath.Join(t.TempDir(), "out")
+		s, err := NewSitemapSplitter("in.xml", 10, WithDestination(dest+dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "in-1.xml")); err != nil {
+			t.Errorf("destination %q: %v", dest+dir, err)
+		}
+	}
+
+	if _, err := NewSitemapSplitter("in.xml", 10, WithDestination("nosuch:bucket")); err == nil || !strings.Contains(err.Error(), "no sink registered for nosuch") {
+		t.Errorf("unregistered scheme error %v", err)
+	}
+	if _, err := NewSitemapSplitter("in.xml", 10, WithDestination("memtest:broken")); err == nil || !strings.Contains(err.Error(), "bucket not found") {
+		t.Errorf("failing opener error %v", err)
+	}
+}
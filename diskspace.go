@@ -0,0 +1,103 @@
+package sitemapsplitter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// checkDiskSpace fails if the filesystem of the file sink has less free
+// space than the given file sizes need, each rounded up to whole blocks.
+// Other sinks and platforms without a free space query are not checked.
+func (s *SitemapSplitter) checkDiskSpace(sizes []int64) error {
+	fs, ok := s.sink.(*FileSink)
+	if !s.diskCheck || !ok {
+		return nil
+	}
+
+	// The output directory is created on the first write, so the nearest
+	// existing ancestor tells the filesystem
+	dir, err := filepath.Abs(fs.dir)
+	if err != nil {
+		return fmt.Errorf("error checking free disk space: %v", err)
+	}
+	for {
+		if _, err := os.Stat(dir); err == nil || filepath.Dir(dir) == dir {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+
+	free, block, ok, err := freeSpace(dir)
+	if err != nil {
+		return fmt.Errorf("error checking free disk space: %v", err)
+	}
+	if !ok {
+		return nil
+	}
+	var need int64
+	for _, size := range sizes {
+		if block > 0 {
+			size = (size + block - 1) / block * block
+		}
+		need += size
+	}
+	if free < need {
+		return fmt.Errorf("output needs about %d bytes but only %d bytes are free in %s", need, free, dir)
+	}
+	return nil
+}
+
+// estimateOutputSizes returns the sizes of the files a split of chunks
+// writes, measured by encoding every chunk once without writing it, plus an
+// allowance for the index. Gzipped variants are counted at full size.
+func (s *SitemapSplitter) estimateOutputSizes(chunks []chunk) ([]int64, error) {
+	variants := len(s.chunkPaths(""))
+	var sizes []int64
+	for _, c := range chunks {
+		counter := &byteCounter{}
+		if err := s.encodeChunk(counter, c.urls); err != nil {
+			return nil, fmt.Errorf("error measuring output size: %v", err)
+		}
+		for range variants {
+			sizes = append(sizes, counter.n)
+		}
+	}
+	return append(sizes, indexEntryAllowance*int64(len(chunks)+1)), nil
+}
+
+// estimateStreamedSizes estimates the output of a streamed split from the
+// size of the input file in every output variant. Inputs that are not local
+// files yield no estimate, and neither do compressed ones, detected like
+// decompress does, whose size says little about the output's.
+func (s *SitemapSplitter) estimateStreamedSizes() []int64 {
+	if !s.diskCheck || s.reader != nil || s.source != nil || isRemote(s.path) {
+		return nil
+	}
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil
+	}
+	magic := make([]byte, len(gzipMagic))
+	_, err = io.ReadFull(f, magic)
+	if archiveSuffix(s.path) != "" || err == nil && bytes.Equal(magic, gzipMagic) {
+		s.logger.Warn("input is compressed, free disk space is not checked", "phase", "plan", "input", s.path)
+		return nil
+	}
+
+	sizes := make([]int64, len(s.chunkPaths("")))
+	for i := range sizes {
+		sizes[i] = info.Size()
+	}
+	return sizes
+}
+
+// indexEntryAllowance is the room reserved per sitemap index entry
+const indexEntryAllowance = 256
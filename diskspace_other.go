@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !freebsd
+
+package sitemapsplitter
+
+// freeSpace reports that free space cannot be queried on this platform
+func freeSpace(dir string) (free, block int64, ok bool, err error) {
+	return 0, 0, false, nil
+}
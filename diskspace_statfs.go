@@ -0,0 +1,15 @@
+//go:build linux || darwin || freebsd
+
+package sitemapsplitter
+
+import "syscall"
+
+// freeSpace returns the bytes available to unprivileged users and the block
+// size of the filesystem holding dir
+func freeSpace(dir string) (free, block int64, ok bool, err error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, 0, false, err
+	}
+	return int64(st.Bavail) * int64(st.Bsize), int64(st.Bsize), true, nil
+}
@@ -0,0 +1,62 @@
+package sitemapsplitter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEstimateStreamedSizes(t *testing.T) {
+	plain := []byte(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc></url></urlset>`)
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(plain)
+	zw.Close()
+
+	tests := []struct {
+		name     string
+		file     string
+		data     []byte
+		opts     []Option
+		want     int   // Estimated sizes, one per output variant
+		wantSize int64 // Size of each estimate
+		wantWarn bool
+	}{
+		{"plain", "in.xml", plain, nil, 1, int64(len(plain)), false},
+		{"plain dual output", "in.xml", plain, []Option{WithDualOutput(false)}, 2, int64(len(plain)), false},
+		{"gzip named xml", "in.xml", gz.Bytes(), nil, 0, 0, true},
+		{"gzip", "in.xml.gz", gz.Bytes(), nil, 0, 0, true},
+		{"archive", "in.zip", plain, nil, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			var log bytes.Buffer
+			opts := append([]Option{WithStreaming(), WithDiskSpaceCheck(), WithLogger(slog.New(slog.NewTextHandler(&log, nil)))}, tt.opts...)
+			s, err := NewSitemapSplitter(path, 1, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sizes := s.estimateStreamedSizes()
+			if len(sizes) != tt.want {
+				t.Fatalf("got %d estimates, want %d", len(sizes), tt.want)
+			}
+			for _, size := range sizes {
+				if size != tt.wantSize {
+					t.Errorf("estimated %d bytes, want %d", size, tt.wantSize)
+				}
+			}
+			if warned := strings.Contains(log.String(), "disk space is not checked"); warned != tt.wantWarn {
+				t.Errorf("warning logged %v, want %v:\n%s", warned, tt.wantWarn, log.String())
+			}
+		})
+	}
+}
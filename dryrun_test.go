@@ -0,0 +1,85 @@
+package sitemapsplitter
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc></url>` +
+		`<url><loc>https://example.com/b</loc></url><url><loc>https://example.com/c</loc></url></urlset>`
+	tests := []struct {
+		name string
+		opts func(dir string, export *bytes.Buffer) []Option
+	}{
+		{"plain", func(string, *bytes.Buffer) []Option { return nil }},
+		{"gzip", func(string, *bytes.Buffer) []Option { return []Option{WithGzipOutput(true)} }},
+		{"state, delta, history and export", func(dir string, export *bytes.Buffer) []Option {
+			return []Option{WithStateFile(filepath.Join(dir, "state.json")), WithDeltaSitemap(""),
+				WithHistory(filepath.Join(dir, "history"), 3), WithJSONLExport(export)}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			split := func(dryRun bool) (*Result, []string, string) {
+				dir := t.TempDir()
+				var export bytes.Buffer
+				opts := append([]Option{WithOutputDir(filepath.Join(dir, "out")), WithDryRun(dryRun)}, tt.opts(dir, &export)...)
+				s, err := NewSitemapSplitter(filepath.Join(dir, "in.xml"), 2, opts...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+					t.Fatal(err)
+				}
+				entries, _ := os.ReadDir(dir)
+				var names []string
+				for _, e := range entries {
+					names = append(names, e.Name())
+				}
+				return s.LastResult(), names, export.String()
+			}
+
+			want, _, _ := split(false)
+			got, written, export := split(true)
+			if len(written) != 0 || export != "" {
+				t.Errorf("dry run wrote %v and exported %q", written, export)
+			}
+			if !got.DryRun || want.DryRun {
+				t.Errorf("DryRun %v, want true; %v for the real run", got.DryRun, want.DryRun)
+			}
+			if got.URLs != want.URLs || len(got.Files) != len(want.Files) {
+				t.Fatalf("dry run reports %d URLs in %v, want %d in %v", got.URLs, got.Files, want.URLs, want.Files)
+			}
+			for i := range got.Files {
+				if got.Files[i].Name != want.Files[i].Name || got.Files[i].Bytes != want.Files[i].Bytes {
+					t.Errorf("dry run reports %+v, want %+v", got.Files[i], want.Files[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDryRunLeavesDedupStore(t *testing.T) {
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc></url>` +
+		`<url><loc>https://example.com/a</loc></url><url><loc>https://example.com/b</loc></url></urlset>`
+	store := NewMemoryDedupStore()
+	for _, dryRun := range []bool{true, false} {
+		s, err := NewSitemapSplitter("in.xml", 10, WithSink(NewMemorySink()), WithDedupStore(store), WithDryRun(dryRun))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+			t.Fatal(err)
+		}
+		if r := s.LastResult(); r.URLs != 2 || r.Skipped[SkipDuplicate] != 1 {
+			t.Errorf("dry run %v: wrote %d URLs dropping %d duplicates, want 2 and 1", dryRun, r.URLs, r.Skipped[SkipDuplicate])
+		}
+		if seen, _ := store.Seen("https://example.com/a"); seen == dryRun {
+			t.Errorf("dry run %v: loc recorded in the store %v", dryRun, seen)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package sitemapsplitter
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// Element is an arbitrary namespaced child element of a URL that is written
+// verbatim without a registered ExtensionHandler. It is meant for bespoke
+// crawler agreements where defining a full extension is not worth it.
+type Element struct {
+	Namespace string     // Namespace URI, declared on the urlset as xmlns:Prefix
+	Prefix    string     // Namespace prefix, e.g. "acme"; children inherit it when empty
+	Name      string     // Local element name
+	Attrs     []xml.Attr // Attributes written as given
+	Text      string     // Character data written before any children
+	Children  []Element  // Nested elements
+}
+
+// AddElement attaches a custom element to the URL
+func (u *URL) AddElement(el Element) {
+	u.Extensions = append(u.Extensions, Extension{Namespace: el.Namespace, Value: el})
+}
+
+// encode writes the element and its children
+func (el Element) encode(e *xml.Encoder, parent Element) error {
+	if el.Prefix == "" {
+		el.Prefix = parent.Prefix
+	}
+
+	name := el.Name
+	if el.Prefix != "" {
+		name = el.Prefix + ":" + el.Name
+	}
+	start := xml.StartElement{Name: xml.Name{Local: name}, Attr: el.Attrs}
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if el.Text != "" {
+		if err := e.EncodeToken(xml.CharData(el.Text)); err != nil {
+			return err
+		}
+	}
+	for _, child := range el.Children {
+		if err := child.encode(e, el); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// resolvePrefixes names the attributes of the element and its children that
+// are in a namespace declared outside of it after their prefix in sc
+func (el *Element) resolvePrefixes(sc nsScope) {
+	el.Attrs = prefixAttrs(el.Attrs, sc)
+	for i := range el.Children {
+		el.Children[i].resolvePrefixes(sc)
+	}
+}
+
+// namespaces calls fn with the prefix and namespace of the element and of
+// every child that declares one
+func (el Element) namespaces(fn func(prefix, namespace string)) {
+	if el.Prefix != "" && el.Namespace != "" {
+		fn(el.Prefix, el.Namespace)
+	}
+	for _, child := range el.Children {
+		child.namespaces(fn)
+	}
+}
+
+// rawExtension is an ExtensionHandler that preserves every element of its
+// namespace verbatim as an Element tree
+type rawExtension struct {
+	namespace string
+	prefix    string
+}
+
+// RawExtension returns an ExtensionHandler that keeps the elements of
+// namespace exactly as found, written back with prefix. It suits extensions
+// whose content only has to survive the split, without typed access.
+func RawExtension(namespace, prefix string) ExtensionHandler {
+	return rawExtension{namespace: namespace, prefix: prefix}
+}
+
+func (r rawExtension) Namespace() string { return r.namespace }
+
+func (r rawExtension) Prefix() string { return r.prefix }
+
+func (r rawExtension) Decode(d *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	return r.decodeElement(d, start, nil)
+}
+
+func (r rawExtension) Encode(e *xml.Encoder, v interface{}) error {
+	el, ok := v.(Element)
+	if !ok {
+		return fmt.Errorf("unexpected %T value for namespace %q", v, r.namespace)
+	}
+	return el.encode(e, Element{})
+}
+
+// decodeElement reads the element opened by start into an Element, with sc
+// holding the namespaces declared by its ancestors. Children from other
+// namespaces are dropped, while attributes from other namespaces keep their
+// prefix, see prefixAttrs.
+func (r rawExtension) decodeElement(d *xml.Decoder, start xml.StartElement, sc nsScope) (Element, error) {
+	el := Element{Namespace: r.namespace, Prefix: r.prefix, Name: start.Name.Local}
+	sc = sc.declare(start.Attr)
+	for _, attr := range start.Attr {
+		// The extension's own prefix is declared on the urlset
+		if attr.Name.Space == r.namespace {
+			attr.Name = xml.Name{Local: r.prefix + ":" + attr.Name.Local}
+		}
+		el.Attrs = append(el.Attrs, attr)
+	}
+	el.Attrs = prefixAttrs(el.Attrs, sc)
+
+	var text strings.Builder
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return el, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Space != r.namespace {
+				if err := d.Skip(); err != nil {
+					return el, err
+				}
+				continue
+			}
+			child, err := r.decodeElement(d, t, sc)
+			if err != nil {
+				return el, err
+			}
+			child.Namespace, child.Prefix = "", ""
+			el.Children = append(el.Children, child)
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			el.Text = text.String()
+			if len(el.Children) > 0 {
+				el.Text = strings.TrimSpace(el.Text)
+			}
+			return el, nil
+		}
+	}
+}
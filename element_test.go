@@ -0,0 +1,27 @@
+package sitemapsplitter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRawExtensionAttrs(t *testing.T) {
+	RegisterExtension(RawExtension("urn:merchant", "m"))
+
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:m="urn:merchant" xmlns:v="urn:vendor">
+<url><loc>https://example.com/a</loc>
+<m:offer xml:lang="en" v:tier="gold" m:price="1"><m:note xmlns:q="urn:q" q:x="1">hi</m:note></m:offer>
+</url></urlset>`
+	out := splitString(t, in)
+	for _, want := range []string{
+		`<m:offer xml:lang="en" xmlns:v="urn:vendor" v:tier="gold" m:price="1">`,
+		`<m:note xmlns:q="urn:q" q:x="1">hi</m:note>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %s:\n%s", want, out)
+		}
+	}
+	if again := splitString(t, out); again != out {
+		t.Errorf("second pass changed the output:\n%s\nwant:\n%s", again, out)
+	}
+}
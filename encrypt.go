@@ -0,0 +1,183 @@
+package sitemapsplitter
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Encrypted outputs are a header of encryptMagic and a random nonce prefix,
+// followed by segments of encryptSegment plaintext bytes, each sealed with
+// AES-GCM under a nonce of the prefix, the segment number and a flag marking
+// the last segment, so files are encrypted and decrypted as a stream and
+// truncation or reordering is detected.
+const (
+	encryptMagic   = "SSENC1"
+	encryptPrefix  = 7
+	encryptSegment = 64 * 1024
+)
+
+// EncryptedSuffix is appended to the name of every file written with
+// WithEncryption
+const EncryptedSuffix = ".enc"
+
+// newAEAD returns AES-GCM for a 16, 24 or 32 byte key
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// segmentNonce returns the nonce of segment n
+func segmentNonce(prefix []byte, n uint32, last bool) []byte {
+	nonce := make([]byte, 0, 12)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, n)
+	if last {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
+
+// encryptWriter encrypts everything written to it into w. Close seals the
+// last segment.
+type encryptWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	prefix []byte
+	n      uint32
+	buf    []byte
+}
+
+// newEncryptWriter returns a writer encrypting into w with aead
+func newEncryptWriter(w io.Writer, aead cipher.AEAD) *encryptWriter {
+	return &encryptWriter{w: w, aead: aead, buf: make([]byte, 0, encryptSegment)}
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	written := len(p)
+	for len(p) > 0 {
+		// A full segment is only sealed once more data follows, as the last
+		// segment is sealed differently
+		if len(e.buf) == encryptSegment {
+			if err := e.seal(false); err != nil {
+				return 0, err
+			}
+		}
+		n := copy(e.buf[len(e.buf):encryptSegment], p)
+		e.buf = e.buf[:len(e.buf)+n]
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// Close seals the buffered data as the last segment
+func (e *encryptWriter) Close() error {
+	return e.seal(true)
+}
+
+// seal encrypts the buffered segment and writes it, preceded by the header
+// for the first segment
+func (e *encryptWriter) seal(last bool) error {
+	if e.prefix == nil {
+		e.prefix = make([]byte, encryptPrefix)
+		if _, err := rand.Read(e.prefix); err != nil {
+			return fmt.Errorf("error generating nonce: %v", err)
+		}
+		if _, err := io.WriteString(e.w, encryptMagic); err != nil {
+			return err
+		}
+		if _, err := e.w.Write(e.prefix); err != nil {
+			return err
+		}
+	}
+	if e.n == ^uint32(0) {
+		return errors.New("encrypted file too large")
+	}
+	sealed := e.aead.Seal(nil, segmentNonce(e.prefix, e.n, last), e.buf, nil)
+	e.n++
+	e.buf = e.buf[:0]
+	_, err := e.w.Write(sealed)
+	return err
+}
+
+// decryptReader reads the plaintext of an encrypted file
+type decryptReader struct {
+	r      *bufio.Reader
+	aead   cipher.AEAD
+	prefix []byte
+	n      uint32
+	seg    []byte
+	plain  []byte
+	done   bool
+}
+
+// NewDecryptReader returns a reader of the plaintext of a file written with
+// WithEncryption using key. Reads fail if the file was modified or
+// truncated, or was encrypted with another key.
+func NewDecryptReader(r io.Reader, key []byte) (io.Reader, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %v", err)
+	}
+
+	header := make([]byte, len(encryptMagic)+encryptPrefix)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("error reading encrypted file header: %v", err)
+	}
+	if string(header[:len(encryptMagic)]) != encryptMagic {
+		return nil, fmt.Errorf("not an encrypted sitemap file")
+	}
+
+	return &decryptReader{
+		r:      bufio.NewReaderSize(r, encryptSegment+aead.Overhead()+1),
+		aead:   aead,
+		prefix: header[len(encryptMagic):],
+		seg:    make([]byte, encryptSegment+aead.Overhead()),
+	}, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+// open reads and decrypts the next segment
+func (d *decryptReader) open() error {
+	n, err := io.ReadFull(d.r, d.seg)
+	switch {
+	case err == io.ErrUnexpectedEOF || err == io.EOF:
+		d.done = true
+	case err != nil:
+		return err
+	default:
+		// A full segment is the last one if nothing follows it
+		if _, err := d.r.Peek(1); err == io.EOF {
+			d.done = true
+		}
+	}
+
+	plain, err := d.aead.Open(d.seg[:0], segmentNonce(d.prefix, d.n, d.done), d.seg[:n], nil)
+	if err != nil {
+		return fmt.Errorf("error decrypting file: %v", err)
+	}
+	d.n++
+	d.plain = plain
+	return nil
+}
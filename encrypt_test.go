@@ -0,0 +1,140 @@
+package sitemapsplitter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var testKey = bytes.Repeat([]byte{7}, 32)
+
+// encryptBytes returns plain encrypted with testKey
+func encryptBytes(t *testing.T, plain []byte) []byte {
+	t.Helper()
+	aead, err := newAEAD(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w := newEncryptWriter(&buf, aead)
+	if _, err := w.Write(plain); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// decryptBytes returns the plaintext of data decrypted with key
+func decryptBytes(data, key []byte) ([]byte, error) {
+	r, err := NewDecryptReader(bytes.NewReader(data), key)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 1, encryptSegment - 1, encryptSegment, encryptSegment + 1, 2 * encryptSegment, 3*encryptSegment + 5} {
+		plain := make([]byte, size)
+		for i := range plain {
+			plain[i] = byte(i * 31)
+		}
+		got, err := decryptBytes(encryptBytes(t, plain), testKey)
+		if err != nil {
+			t.Errorf("size %d: %v", size, err)
+			continue
+		}
+		if !bytes.Equal(got, plain) {
+			t.Errorf("size %d: decrypted %d bytes that differ from the plaintext", size, len(got))
+		}
+	}
+}
+
+func TestDecryptDetectsTampering(t *testing.T) {
+	plain := bytes.Repeat([]byte("<url><loc>https://example.com/</loc></url>"), encryptSegment/16)
+	data := encryptBytes(t, plain)
+	header := len(encryptMagic) + encryptPrefix
+	sealed := encryptSegment + 16 // Plaintext segment plus the GCM tag
+
+	tests := []struct {
+		name string
+		data []byte
+		key  []byte
+	}{
+		{"truncated header", data[:header-1], testKey},
+		{"header only", data[:header], testKey},
+		{"truncated in a segment", data[:header+sealed/2], testKey},
+		{"truncated at a segment boundary", data[:header+sealed], testKey},
+		{"truncated by one byte", data[:len(data)-1], testKey},
+		{"trailing garbage", append(bytes.Clone(data), 0), testKey},
+		{"segments reordered", append(append(bytes.Clone(data[:header]), data[header+sealed:header+2*sealed]...), data[header:header+sealed]...), testKey},
+		{"flipped bit", func() []byte { d := bytes.Clone(data); d[header+10] ^= 1; return d }(), testKey},
+		{"wrong key", data, bytes.Repeat([]byte{8}, 32)},
+		{"not encrypted", plain, testKey},
+	}
+	if len(data) <= header+2*sealed {
+		t.Fatalf("test input has %d bytes, want more than two segments", len(data))
+	}
+	for _, tt := range tests {
+		if got, err := decryptBytes(tt.data, tt.key); err == nil {
+			t.Errorf("%s: decrypted %d bytes without an error", tt.name, len(got))
+		}
+	}
+}
+
+func TestSplitEncrypted(t *testing.T) {
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc></url><url><loc>https://example.com/b</loc></url></urlset>`
+	sink := NewMemorySink()
+	s, err := NewSitemapSplitter("in.xml", 1, WithSink(sink), WithGzipOutput(true), WithEncryption(testKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"in-1.xml.gz.enc", "in-2.xml.gz.enc", "sitemap-index.xml.gz.enc"}
+	if names := sink.Names(); !slices.Equal(names, want) {
+		t.Fatalf("wrote %v, want %v", names, want)
+	}
+
+	// Files are gzipped before they are encrypted, so decrypting yields gzip
+	plain := make(map[string][]byte)
+	for _, name := range want {
+		data, _ := sink.File(name)
+		dec, err := decryptBytes(data, testKey)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(dec))
+		if err != nil {
+			t.Fatalf("%s: decrypted data is not gzip: %v", name, err)
+		}
+		if plain[name], err = io.ReadAll(zr); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+
+	for i, loc := range []string{"https://example.com/a", "https://example.com/b"} {
+		if name := want[i]; !bytes.Contains(plain[name], []byte("<loc>"+loc+"</loc>")) {
+			t.Errorf("%s lacks %s", name, loc)
+		}
+	}
+	var index SitemapIndex
+	if err := xml.Unmarshal(plain["sitemap-index.xml.gz.enc"], &index); err != nil {
+		t.Fatal(err)
+	}
+	var locs []string
+	for _, sm := range index.Sitemaps {
+		locs = append(locs, sm.Loc)
+	}
+	// The index lists the files as they are served after decryption
+	if wantLocs := []string{"https://example.com/in-1.xml.gz", "https://example.com/in-2.xml.gz"}; !slices.Equal(locs, wantLocs) {
+		t.Errorf("index lists %v, want %v", locs, wantLocs)
+	}
+}
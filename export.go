@@ -0,0 +1,27 @@
+package sitemapsplitter
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+)
+
+// exportJSONL writes one JSON object per URL to the configured export writer
+func (s *SitemapSplitter) exportJSONL(urls []URL) error {
+	if s.jsonlExport == nil || s.dryRun {
+		return nil
+	}
+
+	w := bufio.NewWriter(s.jsonlExport)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, u := range urls {
+		if err := enc.Encode(u); err != nil {
+			return fmt.Errorf("error writing JSON Lines export: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("error writing JSON Lines export: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package sitemapsplitter
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ExtensionHandler parses and emits the elements of a sitemap extension
+// namespace (image, video, news or a proprietary one). Handlers are looked up
+// by namespace URI when a child element of <url> is decoded.
+type ExtensionHandler interface {
+	// Namespace returns the namespace URI handled by this extension
+	Namespace() string
+	// Prefix returns the prefix used for elements and the xmlns declaration
+	// when writing output, e.g. "image"
+	Prefix() string
+	// Decode parses the element opened by start and returns its value
+	Decode(d *xml.Decoder, start xml.StartElement) (interface{}, error)
+	// Encode writes a value previously returned by Decode
+	Encode(e *xml.Encoder, v interface{}) error
+}
+
+// Extension is a decoded extension element attached to a URL
+type Extension struct {
+	Namespace string      `json:"namespace"` // Namespace URI of the element
+	Value     interface{} `json:"value"`     // Value produced by the namespace's handler
+}
+
+var (
+	extensionsMu sync.RWMutex
+	extensions   = make(map[string]ExtensionHandler)
+)
+
+// RegisterExtension makes an extension handler available for decoding and
+// encoding. Registering a handler for a namespace that already has one
+// replaces it.
+func RegisterExtension(h ExtensionHandler) {
+	if h == nil {
+		panic("sitemapsplitter: RegisterExtension handler is nil")
+	}
+
+	extensionsMu.Lock()
+	defer extensionsMu.Unlock()
+	extensions[h.Namespace()] = h
+}
+
+// lookupExtension returns the handler registered for namespace, if any
+func lookupExtension(namespace string) ExtensionHandler {
+	extensionsMu.RLock()
+	defer extensionsMu.RUnlock()
+	return extensions[namespace]
+}
+
+// encodeExtension writes ext using its registered handler. Custom elements
+// are written directly.
+func encodeExtension(e *xml.Encoder, ext Extension) error {
+	if el, ok := ext.Value.(Element); ok {
+		return el.encode(e, Element{})
+	}
+
+	h := lookupExtension(ext.Namespace)
+	if h == nil {
+		return fmt.Errorf("no extension registered for namespace %q", ext.Namespace)
+	}
+	return h.Encode(e, ext.Value)
+}
+
+// extensionNamespaceAttrs returns the xmlns declarations needed for the
+// extensions used by urls, sorted by prefix
+func extensionNamespaceAttrs(urls []URL) []xml.Attr {
+	seen := make(map[string]bool)
+	var attrs []xml.Attr
+	declare := func(prefix, namespace string) {
+		// The xhtml namespace is declared on every urlset
+		if seen[prefix] || namespace == XHTMLNamespace {
+			return
+		}
+		seen[prefix] = true
+		attrs = append(attrs, xml.Attr{
+			Name:  xml.Name{Local: "xmlns:" + prefix},
+			Value: namespace,
+		})
+	}
+
+	for _, u := range urls {
+		for _, ext := range u.Extensions {
+			if el, ok := ext.Value.(Element); ok {
+				el.namespaces(declare)
+				continue
+			}
+			if h := lookupExtension(ext.Namespace); h != nil {
+				declare(h.Prefix(), h.Namespace())
+			}
+		}
+	}
+
+	sort.Slice(attrs, func(i, j int) bool {
+		return attrs[i].Name.Local < attrs[j].Name.Local
+	})
+	return attrs
+}
+
+// registeredNamespaceAttrs returns the xmlns declarations of every registered
+// extension, sorted by prefix
+func registeredNamespaceAttrs() []xml.Attr {
+	extensionsMu.RLock()
+	defer extensionsMu.RUnlock()
+
+	namespaces := make([]string, 0, len(extensions))
+	for namespace := range extensions {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	seen := make(map[string]bool)
+	var attrs []xml.Attr
+	for _, namespace := range namespaces {
+		h := extensions[namespace]
+		if seen[h.Prefix()] || namespace == XHTMLNamespace {
+			continue
+		}
+		seen[h.Prefix()] = true
+		attrs = append(attrs, xml.Attr{
+			Name:  xml.Name{Local: "xmlns:" + h.Prefix()},
+			Value: h.Namespace(),
+		})
+	}
+
+	sort.Slice(attrs, func(i, j int) bool {
+		return attrs[i].Name.Local < attrs[j].Name.Local
+	})
+	return attrs
+}
@@ -0,0 +1,131 @@
+package sitemapsplitter
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+)
+
+// urlFilter applies the per-URL stages of a run (rewriting, validation,
+// exclusion, allowlisting, pattern and lastmod filtering, sampling, robots
+// filtering and deduplication) to URLs in input order, so that the same
+// decisions are made whether the input is read at once or streamed
+type urlFilter struct {
+	s       *SitemapSplitter
+	read    int                    // URLs read from the input
+	pos     int                    // Position of the next valid URL in the input
+	robots  map[string]robotsRules // robots.txt rules per origin
+	exclude map[string]struct{}    // Locs of the exclude file
+	allow   prefixSet              // Allowed loc prefixes, nil to allow all
+	since   time.Time              // Earliest lastmod kept, zero for no lower bound
+}
+
+// newURLFilter creates the filter for one run, reading the exclude and
+// allowlist files
+func (s *SitemapSplitter) newURLFilter() (*urlFilter, error) {
+	f := &urlFilter{s: s, robots: make(map[string]robotsRules)}
+	if s.excludeFile != "" {
+		var err error
+		if f.exclude, err = readURLList(s.excludeFile); err != nil {
+			return nil, fmt.Errorf("error reading exclude file: %v", err)
+		}
+	}
+
+	prefixes := s.allowPrefixes
+	if s.allowFile != "" {
+		listed, err := readURLList(s.allowFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading allowlist file: %v", err)
+		}
+		prefixes = slices.AppendSeq(slices.Clone(prefixes), maps.Keys(listed))
+	}
+	if s.allowFile != "" || len(prefixes) > 0 {
+		f.allow = newPrefixSet(prefixes)
+	}
+
+	f.since = s.modAfter
+	if s.modWindow > 0 {
+		if start := s.now().Add(-s.modWindow); start.After(f.since) {
+			f.since = start
+		}
+	}
+	return f, nil
+}
+
+// keep rewrites u in place and reports whether it belongs in the output.
+// Dropped URLs are recorded in the run's result.
+func (f *urlFilter) keep(u *URL) (bool, error) {
+	s := f.s
+	f.read++
+	s.rewrite(u)
+
+	if s.validate {
+		if err := s.validateURL(*u); err != nil {
+			s.skip(*u, SkipInvalid)
+			return false, nil
+		}
+	}
+
+	if _, ok := f.exclude[u.Loc]; ok {
+		s.skip(*u, SkipExcluded)
+		return false, nil
+	}
+	if f.allow != nil && !f.allow.match(u.Loc) {
+		s.skip(*u, SkipNotAllowed)
+		return false, nil
+	}
+	if matchAny(s.excludeRes, u.Loc) {
+		s.skip(*u, SkipPatternExcluded)
+		return false, nil
+	}
+	if len(s.includeRes) > 0 && !matchAny(s.includeRes, u.Loc) {
+		s.skip(*u, SkipNotIncluded)
+		return false, nil
+	}
+	if !f.inModRange(*u) {
+		s.skip(*u, SkipLastModRange)
+		return false, nil
+	}
+
+	pos := f.pos
+	f.pos++
+	if !s.sampled(pos, u.Loc) {
+		s.skip(*u, SkipSampled)
+		return false, nil
+	}
+
+	if s.robotsAgent != "" {
+		ok, err := s.robotsAllowed(u.Loc, f.robots)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			s.skip(*u, SkipRobots)
+			return false, nil
+		}
+	}
+
+	// With a duplicate policy the store is consulted only once the policy
+	// has picked the entry to keep, see filterURLs
+	if s.dupPolicy == DedupKeepAll {
+		return s.unpublished(*u)
+	}
+	return true, nil
+}
+
+// inModRange reports whether the lastmod of u lies within the configured
+// range, which every URL does when no range is set
+func (f *urlFilter) inModRange(u URL) bool {
+	if f.since.IsZero() && f.s.modBefore.IsZero() {
+		return true
+	}
+	t, ok := parseLastMod(u.LastMod, f.s.location)
+	if !ok {
+		return false
+	}
+	if !f.since.IsZero() && t.Before(f.since) {
+		return false
+	}
+	return f.s.modBefore.IsZero() || t.Before(f.s.modBefore)
+}
@@ -0,0 +1,269 @@
+package sitemapsplitter
+
+import (
+	"encoding/xml"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSkipReasons(t *testing.T) {
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` +
+		`<url><loc>https://example.com/a</loc></url>` +
+		`<url><loc>https://example.com/a</loc></url>` +
+		`<url><loc>not a url</loc></url>` +
+		`<url><loc>https://example.com/staging/b</loc></url>` +
+		`<url><loc>https://example.com/staging/c</loc></url>` +
+		`<url><loc>https://example.com/staging/d</loc></url>` +
+		`<url><loc>https://other.example/e</loc></url>` +
+		`</urlset>`
+	tests := []struct {
+		name     string
+		examples int
+		want     map[SkipReason][]string
+	}{
+		{"counts only", 0, map[SkipReason][]string{}},
+		{"two examples", 2, map[SkipReason][]string{
+			SkipDuplicate:       {"https://example.com/a"},
+			SkipInvalid:         {"not a url"},
+			SkipPatternExcluded: {"https://example.com/staging/b", "https://example.com/staging/c"},
+			SkipNotAllowed:      {"https://other.example/e"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSitemapSplitter("in.xml", 50000, WithSink(NewMemorySink()), WithSkipExamples(tt.examples),
+				WithMaxErrorRate(50), WithExclude("/staging/"), WithAllowPrefixes("https://example.com/"), WithDedupStore(NewMemoryDedupStore()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+				t.Fatal(err)
+			}
+
+			r := s.LastResult()
+			wantCounts := map[SkipReason]int{SkipDuplicate: 1, SkipInvalid: 1, SkipPatternExcluded: 3, SkipNotAllowed: 1}
+			if !maps.Equal(r.Skipped, wantCounts) {
+				t.Errorf("skipped %v, want %v", r.Skipped, wantCounts)
+			}
+			if r.URLs != 1 {
+				t.Errorf("wrote %d URLs, want 1", r.URLs)
+			}
+			if !maps.EqualFunc(r.SkipExamples, tt.want, slices.Equal) {
+				t.Errorf("skip examples %v, want %v", r.SkipExamples, tt.want)
+			}
+		})
+	}
+}
+
+func TestExcludeFile(t *testing.T) {
+	locs := []string{"https://example.com/a", "https://example.com/b", "https://www.example.com/c", "https://example.com/d"}
+	tests := []struct {
+		name    string
+		list    string // Content of the exclude file, none if empty
+		opts    []Option
+		want    []string
+		wantErr bool
+	}{
+		{"exact locs", "https://example.com/a\nhttps://example.com/d\n", nil, []string{"https://example.com/b", "https://www.example.com/c"}, false},
+		{"comments and blank lines", "# retired\n\n  https://example.com/b  \n#https://example.com/d\n", nil, []string{"https://example.com/a", "https://www.example.com/c", "https://example.com/d"}, false},
+		{"prefix does not match", "https://example.com/\n", nil, locs, false},
+		{"after rewriting", "https://example.com/c\n", []Option{WithCanonicalHost("example.com")}, []string{"https://example.com/a", "https://example.com/b", "https://example.com/d"}, false},
+		{"missing file", "", nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "exclude.txt")
+			if tt.list != "" {
+				if err := os.WriteFile(path, []byte(tt.list), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := keptLocs(t, locs, append([]Option{WithExcludeFile(path)}, tt.opts...)...)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("split succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("kept %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// keptLocs splits a sitemap of locs into a single chunk and returns the locs
+// it holds
+func keptLocs(t *testing.T, locs []string, opts ...Option) ([]string, error) {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	for _, l := range locs {
+		b.WriteString("<url><loc>" + l + "</loc></url>")
+	}
+	b.WriteString(`</urlset>`)
+
+	sink := NewMemorySink()
+	s, err := NewSitemapSplitter("in.xml", 50000, append([]Option{WithSink(sink)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SplitFrom(strings.NewReader(b.String())); err != nil {
+		return nil, err
+	}
+	data, _ := sink.File("in-1.xml")
+	var set URLSet
+	if err := xml.Unmarshal(data, &set); err != nil {
+		t.Fatal(err)
+	}
+	var kept []string
+	for _, u := range set.URLs {
+		kept = append(kept, u.Loc)
+	}
+	return kept, nil
+}
+
+func TestAllowlist(t *testing.T) {
+	locs := []string{"https://example.com/shop/a", "https://example.com/blog/b", "https://www.example.com/shop/c", "https://example.com/shopping"}
+	tests := []struct {
+		name     string
+		prefixes []string
+		list     string // Content of the allowlist file, none if empty
+		opts     []Option
+		want     []string
+		wantErr  bool
+	}{
+		{"prefixes", []string{"https://example.com/shop/"}, "", nil, []string{"https://example.com/shop/a"}, false},
+		{"file", nil, "# shop only\n\nhttps://example.com/shop/\n", nil, []string{"https://example.com/shop/a"}, false},
+		{"prefixes and file", []string{"https://example.com/blog/"}, "https://example.com/shop\n", nil,
+			[]string{"https://example.com/shop/a", "https://example.com/blog/b", "https://example.com/shopping"}, false},
+		{"after rewriting", []string{"https://example.com/shop/"}, "", []Option{WithCanonicalHost("example.com")},
+			[]string{"https://example.com/shop/a", "https://example.com/shop/c"}, false},
+		{"nothing allowed", []string{"https://other.example/"}, "", nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{WithAllowPrefixes(tt.prefixes...)}, tt.opts...)
+			if tt.list != "" {
+				path := filepath.Join(t.TempDir(), "allow.txt")
+				if err := os.WriteFile(path, []byte(tt.list), 0644); err != nil {
+					t.Fatal(err)
+				}
+				opts = append(opts, WithAllowlistFile(path))
+			}
+			got, err := keptLocs(t, locs, opts...)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("split succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("kept %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// lastModInput is a urlset whose loc paths name the lastmod of each URL, or
+// none for the URL without one
+const lastModInput = `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` +
+	`<url><loc>https://example.com/2024-05-31</loc><lastmod>2024-05-31</lastmod></url>` +
+	`<url><loc>https://example.com/2024-06-01</loc><lastmod>2024-06-01</lastmod></url>` +
+	`<url><loc>https://example.com/2024-06-01T23:59:59</loc><lastmod>2024-06-01T23:59:59+07:00</lastmod></url>` +
+	`<url><loc>https://example.com/2024-06-10</loc><lastmod>2024-06-10</lastmod></url>` +
+	`<url><loc>https://example.com/none</loc></url>` +
+	`</urlset>`
+
+// splitLastModInput splits lastModInput into a single chunk and returns the
+// paths of the locs it holds
+func splitLastModInput(t *testing.T, opts ...Option) []string {
+	t.Helper()
+	sink := NewMemorySink()
+	s, err := NewSitemapSplitter("in.xml", 50000, append([]Option{WithSink(sink), WithTimezone(time.FixedZone("UTC+7", 7*60*60))}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.clock = func() time.Time { return time.Date(2024, 6, 11, 12, 0, 0, 0, time.UTC) }
+	if err := s.SplitFrom(strings.NewReader(lastModInput)); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := sink.File("in-1.xml")
+	var set URLSet
+	if err := xml.Unmarshal(data, &set); err != nil {
+		t.Fatal(err)
+	}
+	var kept []string
+	for _, u := range set.URLs {
+		kept = append(kept, strings.TrimPrefix(u.Loc, "https://example.com/"))
+	}
+	return kept
+}
+
+func TestLastModRange(t *testing.T) {
+	zone := time.FixedZone("UTC+7", 7*60*60)
+	june1, june10 := time.Date(2024, 6, 1, 0, 0, 0, 0, zone), time.Date(2024, 6, 10, 0, 0, 0, 0, zone)
+	tests := []struct {
+		name          string
+		after, before time.Time
+		want          []string
+	}{
+		{"after is inclusive", june1, time.Time{}, []string{"2024-06-01", "2024-06-01T23:59:59", "2024-06-10"}},
+		{"before is exclusive", time.Time{}, june10, []string{"2024-05-31", "2024-06-01", "2024-06-01T23:59:59"}},
+		{"both ends", june1, june10, []string{"2024-06-01", "2024-06-01T23:59:59"}},
+		{"single instant", june1, june1.Add(time.Nanosecond), []string{"2024-06-01"}},
+		{"no range keeps undated", time.Time{}, time.Time{}, []string{"2024-05-31", "2024-06-01", "2024-06-01T23:59:59", "2024-06-10", "none"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitLastModInput(t, WithLastModRange(tt.after, tt.before)); !slices.Equal(got, tt.want) {
+				t.Errorf("kept %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewSitemapSplitter("in.xml", 10, WithLastModRange(june10, june1)); err == nil {
+		t.Error("range ending before it starts accepted")
+	}
+	if _, err := NewSitemapSplitter("in.xml", 10, WithLastModRange(june1, june1)); err == nil {
+		t.Error("empty range accepted")
+	}
+}
+
+func TestLastModWindow(t *testing.T) {
+	// The clock stands at 2024-06-11 19:00 in the splitter's timezone
+	zone := time.FixedZone("UTC+7", 7*60*60)
+	tests := []struct {
+		name   string
+		window time.Duration
+		opts   []Option
+		want   []string
+	}{
+		{"start of window is inclusive", 10*24*time.Hour + 19*time.Hour, nil, []string{"2024-06-01", "2024-06-01T23:59:59", "2024-06-10"}},
+		{"just past the start", 10*24*time.Hour + 19*time.Hour - time.Nanosecond, nil, []string{"2024-06-01T23:59:59", "2024-06-10"}},
+		{"narrower range wins", 30 * 24 * time.Hour, []Option{WithLastModRange(time.Date(2024, 6, 10, 0, 0, 0, 0, zone), time.Time{})}, []string{"2024-06-10"}},
+		{"narrower window wins", 2 * 24 * time.Hour, []Option{WithLastModRange(time.Date(2024, 6, 1, 0, 0, 0, 0, zone), time.Time{})}, []string{"2024-06-10"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitLastModInput(t, append([]Option{WithLastModWindow(tt.window)}, tt.opts...)...); !slices.Equal(got, tt.want) {
+				t.Errorf("kept %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewSitemapSplitter("in.xml", 10, WithLastModWindow(-time.Hour)); err == nil {
+		t.Error("negative window accepted")
+	}
+}
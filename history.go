@@ -0,0 +1,128 @@
+package sitemapsplitter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// stampLayout names history files, releases and backups after the time they
+// were made, so that they sort chronologically
+const stampLayout = "20060102T150405.000000000Z"
+
+// HistoryEntry is the persisted record of one run: its manifest of written
+// files and its Result
+type HistoryEntry struct {
+	Time   time.Time      `json:"time"`
+	Input  string         `json:"input"`
+	URLs   int            `json:"urls"`
+	Files  []ManifestFile `json:"files"`
+	Result *Result        `json:"result"`
+}
+
+// ManifestFile describes one file written by a run
+type ManifestFile struct {
+	Name string `json:"name"`
+	URLs int    `json:"urls,omitempty"`
+}
+
+// recordHistory writes the run's history entry, listing the chunk files
+// written and the urls they hold, into the history directory and prunes
+// entries beyond the retention count
+func (s *SitemapSplitter) recordHistory(started time.Time, files []ManifestFile, urls int) error {
+	entry := HistoryEntry{
+		Time:   started.UTC(),
+		Input:  s.path,
+		URLs:   urls,
+		Files:  files,
+		Result: s.result,
+	}
+	if !s.noIndex {
+		entry.Files = append(entry.Files, ManifestFile{Name: s.indexFile()})
+	}
+	for _, name := range s.extraNames() {
+		f := ManifestFile{Name: name}
+		if name == s.newsName && s.result.News != nil {
+			f.URLs = s.result.News.URLs
+		}
+		entry.Files = append(entry.Files, f)
+	}
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding run history: %v", err)
+	}
+	if err := os.MkdirAll(s.historyDir, 0755); err != nil {
+		return fmt.Errorf("error creating history directory: %v", err)
+	}
+	name := entry.Time.Format(stampLayout) + ".json"
+	if err := os.WriteFile(filepath.Join(s.historyDir, name), data, 0644); err != nil {
+		return fmt.Errorf("error writing run history: %v", err)
+	}
+
+	return pruneHistory(s.historyDir, s.historyKeep)
+}
+
+// historyFiles lists the history files in dir, oldest first
+func historyFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, e := range entries {
+		if e.Type().IsRegular() && strings.HasSuffix(e.Name(), ".json") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// pruneHistory removes the oldest history files so at most keep remain. A
+// keep of 0 retains everything.
+func pruneHistory(dir string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+
+	names, err := historyFiles(dir)
+	if err != nil {
+		return fmt.Errorf("error listing run history: %v", err)
+	}
+	for len(names) > keep {
+		if err := os.Remove(filepath.Join(dir, names[0])); err != nil {
+			return fmt.Errorf("error pruning run history: %v", err)
+		}
+		names = names[1:]
+	}
+	return nil
+}
+
+// ReadHistory returns the run history recorded in dir, newest first, so
+// regressions such as a sudden drop in URL count can be spotted
+func ReadHistory(dir string) ([]HistoryEntry, error) {
+	names, err := historyFiles(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error listing run history: %v", err)
+	}
+
+	history := make([]HistoryEntry, 0, len(names))
+	for i := len(names) - 1; i >= 0; i-- {
+		data, err := os.ReadFile(filepath.Join(dir, names[i]))
+		if err != nil {
+			return nil, fmt.Errorf("error reading run history: %v", err)
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, fmt.Errorf("error parsing run history %s: %v", names[i], err)
+		}
+		history = append(history, entry)
+	}
+	return history, nil
+}
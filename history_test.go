@@ -0,0 +1,70 @@
+package sitemapsplitter
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	tests := []struct {
+		name string
+		keep int
+		runs int
+		want []int // URL counts of the remaining records, newest first
+	}{
+		{"keep all", 0, 3, []int{3, 2, 1}},
+		{"retention", 2, 4, []int{4, 3}},
+		{"single", 1, 2, []int{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "history")
+			s, err := NewSitemapSplitter("in.xml", 2, WithSink(NewMemorySink()), WithHistory(dir, tt.keep))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for run := 1; run <= tt.runs; run++ {
+				var b strings.Builder
+				b.WriteString(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+				for i := range run {
+					fmt.Fprintf(&b, "<url><loc>https://example.com/%d</loc></url>", i)
+				}
+				b.WriteString("</urlset>")
+				if err := s.SplitFrom(strings.NewReader(b.String())); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			history, err := ReadHistory(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []int
+			for _, e := range history {
+				got = append(got, e.URLs)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("history holds URL counts %v, want %v", got, tt.want)
+			}
+
+			newest := history[0]
+			var names, wantNames []string
+			for _, f := range newest.Files {
+				names = append(names, f.Name)
+			}
+			for i := range (tt.runs + 1) / 2 {
+				wantNames = append(wantNames, fmt.Sprintf("in-%d.xml", i+1))
+			}
+			wantNames = append(wantNames, "sitemap-index.xml")
+			if !slices.Equal(names, wantNames) {
+				t.Errorf("newest record lists %v, want %v", names, wantNames)
+			}
+			if newest.Result == nil || newest.Result.URLs != tt.runs {
+				t.Errorf("newest record has result %+v, want %d URLs", newest.Result, tt.runs)
+			}
+		})
+	}
+}
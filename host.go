@@ -0,0 +1,37 @@
+package sitemapsplitter
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+// canonicalizeHost rewrites loc's host to the configured canonical host when
+// it is the www or apex variant of it in any case, reporting whether loc
+// changed
+func (s *SitemapSplitter) canonicalizeHost(u *URL) bool {
+	if s.canonicalHost == "" {
+		return false
+	}
+
+	parsed, err := url.Parse(u.Loc)
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+
+	host, port := strings.ToLower(parsed.Hostname()), parsed.Port()
+	if strings.TrimPrefix(host, "www.") != strings.TrimPrefix(s.canonicalHost, "www.") {
+		return false
+	}
+
+	canonical := s.canonicalHost
+	if port != "" {
+		canonical = net.JoinHostPort(s.canonicalHost, port)
+	}
+	if parsed.Host == canonical {
+		return false
+	}
+	parsed.Host = canonical
+	u.Loc = parsed.String()
+	return true
+}
@@ -0,0 +1,34 @@
+package sitemapsplitter
+
+import "testing"
+
+func TestCanonicalizeHost(t *testing.T) {
+	tests := []struct {
+		canonical string
+		loc       string
+		want      string
+		changed   bool
+	}{
+		{"example.com", "https://www.example.com/a", "https://example.com/a", true},
+		{"example.com", "https://example.com/a", "https://example.com/a", false},
+		{"example.com", "https://WWW.Example.com/a", "https://example.com/a", true},
+		{"example.com", "https://Example.COM/a", "https://example.com/a", true},
+		{"www.example.com", "https://WWW.example.com/a", "https://www.example.com/a", true},
+		{"www.example.com", "https://example.com:8443/a", "https://www.example.com:8443/a", true},
+		{"www.example.com", "https://www.example.com:8443/a", "https://www.example.com:8443/a", false},
+		{"Example.com", "https://www.example.com/a", "https://example.com/a", true},
+		{"example.com", "https://shop.example.com/a", "https://shop.example.com/a", false},
+		{"example.com", "https://EXAMPLE.org/a", "https://EXAMPLE.org/a", false},
+	}
+	for _, tt := range tests {
+		s, err := NewSitemapSplitter("in.xml", 1, WithCanonicalHost(tt.canonical))
+		if err != nil {
+			t.Fatal(err)
+		}
+		u := URL{Loc: tt.loc}
+		changed := s.canonicalizeHost(&u)
+		if u.Loc != tt.want || changed != tt.changed {
+			t.Errorf("%s with canonical host %s: got %s (changed %v), want %s (changed %v)", tt.loc, tt.canonical, u.Loc, changed, tt.want, tt.changed)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package sitemapsplitter
+
+import (
+	"regexp"
+	"strings"
+)
+
+// bcp47Tag matches well-formed BCP 47 language tags: a language with optional
+// extended language, script, region, variant, extension and private use
+// subtags, or a private use tag on its own. Whether the subtags are
+// registered is not checked.
+var bcp47Tag = regexp.MustCompile(`(?i)^(?:(?:[a-z]{2,3}(?:-[a-z]{3}){0,3}|[a-z]{4,8})` +
+	`(?:-[a-z]{4})?(?:-(?:[a-z]{2}|[0-9]{3}))?(?:-(?:[a-z0-9]{5,8}|[0-9][a-z0-9]{3}))*` +
+	`(?:-[0-9a-wy-z](?:-[a-z0-9]{2,8})+)*(?:-x(?:-[a-z0-9]{1,8})+)?|x(?:-[a-z0-9]{1,8})+)$`)
+
+// validHreflang reports whether value is x-default or a well-formed BCP 47
+// language tag
+func validHreflang(value string) bool {
+	return strings.EqualFold(value, "x-default") || bcp47Tag.MatchString(value)
+}
+
+// checkHreflang warns about hreflang alternates whose language tag is
+// malformed and about alternates pointing at a URL of the input that does
+// not link back, which search engines treat as a broken cluster. Alternates
+// pointing outside the input cannot be checked for a return link.
+func (s *SitemapSplitter) checkHreflang(urls []URL) {
+	locs := make(map[string]bool, len(urls))
+	links := make(map[string]map[string]bool) // Alternate hrefs by loc
+	for _, u := range urls {
+		locs[u.Loc] = true
+		for _, alt := range u.Alternates() {
+			if alt.Hreflang == "" {
+				continue
+			}
+			if links[u.Loc] == nil {
+				links[u.Loc] = make(map[string]bool)
+			}
+			links[u.Loc][alt.Href] = true
+		}
+	}
+
+	invalid, oneWay := 0, 0
+	for _, u := range urls {
+		for _, alt := range u.Alternates() {
+			if alt.Hreflang == "" {
+				continue
+			}
+			if !validHreflang(alt.Hreflang) {
+				s.warn(u.Loc, WarnHreflangInvalid)
+				invalid++
+			}
+			if alt.Href != u.Loc && locs[alt.Href] && !links[alt.Href][u.Loc] {
+				s.warn(u.Loc, WarnHreflangNoReturn)
+				oneWay++
+			}
+		}
+	}
+	s.logger.Info("checked hreflang alternates", "phase", "filter", "invalid", invalid, "without_return_link", oneWay)
+}
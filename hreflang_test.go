@@ -0,0 +1,71 @@
+package sitemapsplitter
+
+import "testing"
+
+func TestValidHreflang(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"en", true},
+		{"en-US", true},
+		{"de-at", true},
+		{"zh-Hant-TW", true},
+		{"es-419", true},
+		{"sl-rozaj-biske", true},
+		{"x-default", true},
+		{"X-Default", true},
+		{"en-US-x-private", true},
+		{"", false},
+		{"en_US", false},
+		{"e", false},
+		{"toolonglanguage", false},
+		{"en-", false},
+		{"en-US-", false},
+		{"123", false},
+	}
+	for _, tt := range tests {
+		if got := validHreflang(tt.value); got != tt.want {
+			t.Errorf("validHreflang(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCheckHreflang(t *testing.T) {
+	alt := func(loc string, links map[string]string) URL {
+		u := URL{Loc: loc}
+		for lang, href := range links {
+			u.AddAlternate(Alternate{Rel: "alternate", Hreflang: lang, Href: href})
+		}
+		return u
+	}
+	const en, de, fr = "https://example.com/en", "https://example.com/de", "https://example.com/fr"
+	urls := []URL{
+		alt(en, map[string]string{"en": en, "de": de, "fr": fr}),
+		alt(de, map[string]string{"en": en, "de": de}),
+		alt(fr, map[string]string{"fr_FR": fr}),
+		alt("https://example.com/other", map[string]string{"it": "https://elsewhere.example/it"}),
+	}
+
+	s, err := NewSitemapSplitter("in.xml", 10, WithHreflangValidation())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.result = &Result{WarningCounts: make(map[WarningCode]int)}
+	s.maxWarnings = 10
+	s.checkHreflang(urls)
+
+	want := map[Warning]bool{
+		{Code: WarnHreflangNoReturn, Loc: en}: true, // fr does not link back to en
+		{Code: WarnHreflangInvalid, Loc: fr}:  true,
+	}
+	for _, w := range s.result.Warnings {
+		if !want[w] {
+			t.Errorf("unexpected warning %v", w)
+		}
+		delete(want, w)
+	}
+	for w := range want {
+		t.Errorf("missing warning %v", w)
+	}
+}
@@ -0,0 +1,160 @@
+package sitemapsplitter
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// doRequest sends req with the configured user-agent, waiting first for a
+// free slot of the host's concurrency limit, until the request delay has
+// passed since the previous request to the same host and until the rate
+// limit allows another request. Only child sitemaps fetched with
+// WithChildConcurrency are requested concurrently.
+func (s *SitemapSplitter) doRequest(req *http.Request) (*http.Response, error) {
+	if s.userAgent != "" {
+		req.Header.Set("User-Agent", s.userAgent)
+	}
+
+	host := req.URL.Host
+	release, err := s.acquireHost(host)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.pace(host); err != nil {
+		release()
+		return nil, err
+	}
+
+	// The fetch timeout covers the request and reading its body, but not the
+	// wait before it
+	ctx, cancel := s.ctx, context.CancelFunc(nil)
+	if timeout := s.phaseTimeouts.Fetch; timeout > 0 {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, phaseTimeout("fetch", timeout))
+	}
+	if ctx != nil {
+		req = req.WithContext(ctx)
+	}
+
+	started := time.Now()
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		release()
+		if cancel != nil {
+			if ctx.Err() != nil {
+				err = context.Cause(ctx)
+			}
+			cancel()
+		}
+		s.logger.Warn("HTTP request failed", "phase", "fetch", "method", req.Method, "url", req.URL.Redacted(), "error", err)
+		return nil, err
+	}
+	s.logger.Debug("HTTP request", "phase", "fetch", "method", req.Method, "url", req.URL.Redacted(), "status", resp.StatusCode, "duration", time.Since(started))
+	if cancel != nil {
+		resp.Body = cancelBody{resp.Body, cancel}
+	}
+	resp.Body = releaseBody{resp.Body, release}
+	return resp, nil
+}
+
+// semaphore holds one element per request in flight
+type semaphore chan struct{}
+
+// acquireHost waits for a free slot of the concurrency limit of host and
+// returns the function freeing it again, which may be called more than once
+func (s *SitemapSplitter) acquireHost(host string) (func(), error) {
+	if s.hostFetches <= 0 {
+		return func() {}, nil
+	}
+
+	s.pacing.Lock()
+	slots, ok := s.hostSlots[host]
+	if !ok {
+		if s.hostSlots == nil {
+			s.hostSlots = make(map[string]semaphore)
+		}
+		slots = make(semaphore, s.hostFetches)
+		s.hostSlots[host] = slots
+	}
+	s.pacing.Unlock()
+
+	ctx := s.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	select {
+	case slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, s.canceled()
+	}
+	var once sync.Once
+	return func() { once.Do(func() { <-slots }) }, nil
+}
+
+// pace waits until the request delay of host has passed since the start of
+// the previous request to it and the rate limit allows another request. The
+// start of a request is recorded under the lock when it is admitted, so
+// concurrent requests are spaced as well, but the lock is not held while
+// waiting, so a request to one host never waits for the delay of another.
+func (s *SitemapSplitter) pace(host string) error {
+	for {
+		s.pacing.Lock()
+		now := time.Now()
+		next := now
+		if last, ok := s.lastRequest[host]; ok && s.requestDelay > 0 {
+			next = later(next, last.Add(s.requestDelay))
+		}
+		if s.rateLimit > 0 && !s.lastSent.IsZero() {
+			next = later(next, s.lastSent.Add(time.Duration(float64(time.Second)/s.rateLimit)))
+		}
+		if !next.After(now) {
+			if s.requestDelay > 0 {
+				if s.lastRequest == nil {
+					s.lastRequest = make(map[string]time.Time)
+				}
+				s.lastRequest[host] = now
+			}
+			if s.rateLimit > 0 {
+				s.lastSent = now
+			}
+			s.pacing.Unlock()
+			return nil
+		}
+		s.pacing.Unlock()
+
+		// Another request may take the slot meanwhile, so check again
+		if err := s.sleep(next.Sub(now)); err != nil {
+			return err
+		}
+	}
+}
+
+// later returns the later of a and b
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// sleep waits for d, returning early with an error if the context of the run
+// in progress is done
+func (s *SitemapSplitter) sleep(d time.Duration) error {
+	ctx := s.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return s.canceled()
+	}
+}
@@ -0,0 +1,312 @@
+package sitemapsplitter
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// arrivalServer serves n child sitemaps of one URL each and records when
+// every request arrived. It returns the server, a sitemap index listing the
+// children and a function returning the arrival times in order.
+func arrivalServer(t *testing.T, n int) (*httptest.Server, string, func() []time.Time) {
+	t.Helper()
+	var (
+		mu       sync.Mutex
+		arrivals []time.Time
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		arrivals = append(arrivals, time.Now())
+		mu.Unlock()
+		fmt.Fprintf(w, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com%s</loc></url></urlset>`, r.URL.Path)
+	}))
+	t.Cleanup(srv.Close)
+
+	var b strings.Builder
+	b.WriteString(`<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	for i := range n {
+		fmt.Fprintf(&b, `<sitemap><loc>%s/child-%d.xml</loc></sitemap>`, srv.URL, i)
+	}
+	b.WriteString(`</sitemapindex>`)
+
+	return srv, b.String(), func() []time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		times := slices.Clone(arrivals)
+		slices.SortFunc(times, time.Time.Compare)
+		return times
+	}
+}
+
+// checkSpacing fails t unless all n requests arrived and consecutive ones
+// arrived at least gap apart, allowing for a little scheduling jitter
+func checkSpacing(t *testing.T, times []time.Time, n int, gap time.Duration) {
+	t.Helper()
+	if len(times) != n {
+		t.Fatalf("%d requests arrived, want %d", len(times), n)
+	}
+	for i := 1; i < len(times); i++ {
+		if d := times[i].Sub(times[i-1]); d < gap*9/10 {
+			t.Errorf("requests %d and %d arrived %v apart, want at least %v", i-1, i, d, gap)
+		}
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	const in = `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc></url></urlset>`
+	tests := []struct {
+		name      string
+		userAgent string
+		want      string
+	}{
+		{"configured", "ExampleBot/1.0", "ExampleBot/1.0"},
+		{"client default", "", "Go-http-client/1.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.UserAgent()
+				w.Write([]byte(in))
+			}))
+			defer srv.Close()
+
+			s, err := NewSitemapSplitter(srv.URL+"/sitemap.xml", 10, WithUserAgent(tt.userAgent), WithOutputDir(t.TempDir()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Split(); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("User-Agent %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestDelay(t *testing.T) {
+	const delay = 100 * time.Millisecond
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	first, second := httptest.NewServer(handler), httptest.NewServer(handler)
+	defer first.Close()
+	defer second.Close()
+
+	tests := []struct {
+		name string
+		urls []string // Requested in order, all of them timed
+		wait bool
+	}{
+		{"same host", []string{first.URL, first.URL}, true},
+		{"other host", []string{first.URL, second.URL}, false},
+		{"first request", []string{first.URL}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSitemapSplitter("in.xml", 10, WithRequestDelay(delay))
+			if err != nil {
+				t.Fatal(err)
+			}
+			started := time.Now()
+			for _, u := range tt.urls {
+				req, err := http.NewRequest(http.MethodGet, u, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp, err := s.doRequest(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp.Body.Close()
+			}
+			if waited := time.Since(started) >= delay; waited != tt.wait {
+				t.Errorf("requests took %v, want wait %v", time.Since(started), tt.wait)
+			}
+		})
+	}
+
+	if _, err := NewSitemapSplitter("in.xml", 10, WithRequestDelay(-time.Second)); err == nil {
+		t.Error("negative request delay accepted")
+	}
+}
+
+func TestRequestDelayConcurrentChildren(t *testing.T) {
+	const (
+		delay    = 50 * time.Millisecond
+		children = 4
+	)
+	_, index, arrivals := arrivalServer(t, children)
+
+	s, err := NewSitemapSplitter(filepath.Join(t.TempDir(), "index.xml"), 50000,
+		WithRequestDelay(delay), WithChildConcurrency(children))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.reader = strings.NewReader(index)
+	if _, err := s.readURLs(); err != nil {
+		t.Fatal(err)
+	}
+	checkSpacing(t, arrivals(), children, delay)
+}
+
+func TestRateLimit(t *testing.T) {
+	const (
+		perSecond = 20
+		requests  = 4
+		gap       = time.Second / perSecond
+	)
+
+	t.Run("single fetches", func(t *testing.T) {
+		srv, _, arrivals := arrivalServer(t, 0)
+		s, err := NewSitemapSplitter("in.xml", 10, WithRateLimit(perSecond))
+		if err != nil {
+			t.Fatal(err)
+		}
+		started := time.Now()
+		for i := range requests {
+			req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/child-%d.xml", srv.URL, i), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := s.doRequest(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+		}
+		if elapsed, want := time.Since(started), (requests-1)*gap; elapsed < want {
+			t.Errorf("%d requests took %v, want at least %v", requests, elapsed, want)
+		}
+		checkSpacing(t, arrivals(), requests, gap)
+	})
+
+	t.Run("concurrent children", func(t *testing.T) {
+		_, index, arrivals := arrivalServer(t, requests)
+		s, err := NewSitemapSplitter(filepath.Join(t.TempDir(), "index.xml"), 50000,
+			WithRateLimit(perSecond), WithChildConcurrency(requests))
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.reader = strings.NewReader(index)
+		started := time.Now()
+		if _, err := s.readURLs(); err != nil {
+			t.Fatal(err)
+		}
+		if elapsed, want := time.Since(started), (requests-1)*gap; elapsed < want {
+			t.Errorf("%d children took %v, want at least %v", requests, elapsed, want)
+		}
+		checkSpacing(t, arrivals(), requests, gap)
+	})
+
+	if _, err := NewSitemapSplitter("in.xml", 10, WithRateLimit(-1)); err == nil {
+		t.Error("negative rate limit accepted")
+	}
+}
+
+func TestRequestDelayOtherHostNotBlocked(t *testing.T) {
+	const delay = 200 * time.Millisecond
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	first, second := httptest.NewServer(handler), httptest.NewServer(handler)
+	defer first.Close()
+	defer second.Close()
+
+	s, err := NewSitemapSplitter("in.xml", 10, WithRequestDelay(delay))
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(u string) {
+		req, err := http.NewRequest(http.MethodGet, u, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		resp, err := s.doRequest(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		resp.Body.Close()
+	}
+
+	get(first.URL)
+	waiting := make(chan struct{})
+	go func() {
+		defer close(waiting)
+		get(first.URL)
+	}()
+	time.Sleep(delay / 4)
+	started := time.Now()
+	get(second.URL)
+	if elapsed := time.Since(started); elapsed >= delay/2 {
+		t.Errorf("request to another host took %v while one waited for its delay", elapsed)
+	}
+	<-waiting
+}
+
+func TestHostConcurrency(t *testing.T) {
+	const requests = 6
+	var (
+		mu             sync.Mutex
+		inFlight, peak int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		peak = max(peak, inFlight)
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	for _, limit := range []int{1, 2} {
+		t.Run(fmt.Sprint(limit), func(t *testing.T) {
+			peak = 0
+			s, err := NewSitemapSplitter("in.xml", 10, WithHostConcurrency(limit))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var wg sync.WaitGroup
+			for range requests {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					resp, err := s.doRequest(req)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					resp.Body.Close()
+				}()
+			}
+			wg.Wait()
+
+			mu.Lock()
+			defer mu.Unlock()
+			if peak > limit {
+				t.Errorf("%d requests in flight at once, limit %d", peak, limit)
+			}
+			if peak < limit {
+				t.Errorf("at most %d requests in flight, want the limit %d to be used", peak, limit)
+			}
+		})
+	}
+
+	if _, err := NewSitemapSplitter("in.xml", 10, WithHostConcurrency(-1)); err == nil {
+		t.Error("negative host concurrency accepted")
+	}
+}
@@ -0,0 +1,82 @@
+package sitemapsplitter
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// ImageNamespace is the namespace of Google's image sitemap extension
+const ImageNamespace = "http://www.google.com/schemas/sitemap-image/1.1"
+
+// Image is an <image:image> entry describing an image on the page of a URL.
+// Only Loc is still used by Google; the other fields are kept so that older
+// sitemaps round-trip unchanged.
+type Image struct {
+	Loc         string `xml:"loc" json:"loc"`
+	Caption     string `xml:"caption,omitempty" json:"caption,omitempty"`
+	GeoLocation string `xml:"geo_location,omitempty" json:"geo_location,omitempty"`
+	Title       string `xml:"title,omitempty" json:"title,omitempty"`
+	License     string `xml:"license,omitempty" json:"license,omitempty"`
+}
+
+func init() {
+	RegisterExtension(imageExtension{})
+}
+
+// AddImage attaches an image entry to the URL
+func (u *URL) AddImage(img Image) {
+	u.Extensions = append(u.Extensions, Extension{Namespace: ImageNamespace, Value: img})
+}
+
+// Images returns the image entries of the URL
+func (u URL) Images() []Image {
+	var images []Image
+	for _, ext := range u.Extensions {
+		if img, ok := ext.Value.(Image); ok {
+			images = append(images, img)
+		}
+	}
+	return images
+}
+
+// imageExtension decodes <image:image> elements into Image values. Other
+// elements of the namespace are preserved verbatim.
+type imageExtension struct{}
+
+func (imageExtension) Namespace() string { return ImageNamespace }
+
+func (imageExtension) Prefix() string { return "image" }
+
+func (imageExtension) Decode(d *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	if start.Name.Local != "image" {
+		return RawExtension(ImageNamespace, "image").Decode(d, start)
+	}
+
+	var img Image
+	if err := d.DecodeElement(&img, &start); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+func (imageExtension) Encode(e *xml.Encoder, v interface{}) error {
+	img, ok := v.(Image)
+	if !ok {
+		return fmt.Errorf("unexpected %T value for namespace %q", v, ImageNamespace)
+	}
+
+	start := xml.StartElement{Name: xml.Name{Local: "image:image"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := encodeFields(e, "image:", []field{
+		{"loc", img.Loc},
+		{"caption", img.Caption},
+		{"geo_location", img.GeoLocation},
+		{"title", img.Title},
+		{"license", img.License},
+	}); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
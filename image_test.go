@@ -0,0 +1,49 @@
+package sitemapsplitter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestImages(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string // Elements of the URL besides its loc
+		want []string
+	}{
+		{"loc only", `<image:image><image:loc>https://example.com/a.jpg</image:loc></image:image>`,
+			[]string{`<image:image>`, `<image:loc>https://example.com/a.jpg</image:loc>`}},
+		{"all fields", `<image:image><image:loc>https://example.com/a.jpg</image:loc><image:caption>A &amp; B</image:caption><image:geo_location>Paris</image:geo_location><image:title>A</image:title><image:license>https://example.com/l</image:license></image:image>`,
+			[]string{`<image:caption>A &amp; B</image:caption>`, `<image:geo_location>Paris</image:geo_location>`, `<image:title>A</image:title>`, `<image:license>https://example.com/l</image:license>`}},
+		{"several images", `<image:image><image:loc>https://example.com/a.jpg</image:loc></image:image><image:image><image:loc>https://example.com/b.jpg</image:loc></image:image>`,
+			[]string{`<image:loc>https://example.com/a.jpg</image:loc>`, `<image:loc>https://example.com/b.jpg</image:loc>`}},
+		{"other element", `<image:note>kept</image:note>`, []string{`<image:note>kept</image:note>`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:image="` + ImageNamespace + `"><url><loc>https://example.com/p</loc>` + tt.in + `</url></urlset>`
+			out := splitString(t, in)
+			want := append([]string{`xmlns:image="` + ImageNamespace + `"`}, tt.want...)
+			for _, w := range want {
+				if !strings.Contains(out, w) {
+					t.Errorf("output lacks %s:\n%s", w, out)
+				}
+			}
+			if again := splitString(t, out); again != out {
+				t.Errorf("second pass changed the output:\n%s\nwant:\n%s", again, out)
+			}
+		})
+	}
+}
+
+func TestAddImage(t *testing.T) {
+	var u URL
+	u.AddImage(Image{Loc: "https://example.com/a.jpg"})
+	u.AddImage(Image{Loc: "https://example.com/b.jpg", Title: "B"})
+	u.AddElement(Element{Namespace: "urn:other", Prefix: "o", Name: "x"})
+
+	got := u.Images()
+	if len(got) != 2 || got[0].Loc != "https://example.com/a.jpg" || got[1].Title != "B" {
+		t.Errorf("Images() = %v, want the two images added", got)
+	}
+}
@@ -0,0 +1,54 @@
+package sitemapsplitter
+
+import "sort"
+
+// IndexOrder controls the order of entries in the generated sitemap index
+type IndexOrder int
+
+const (
+	// IndexOrderChunk lists entries by chunk number
+	IndexOrderChunk IndexOrder = iota
+	// IndexOrderNewestFirst lists entries by lastmod, newest first, so
+	// crawlers reach the freshest chunk files first
+	IndexOrderNewestFirst
+)
+
+// sortIndex orders the index entries according to the configured order. A
+// custom comparator takes precedence over the order setting.
+func (s *SitemapSplitter) sortIndex(entries []Sitemap) {
+	s.sortIndexWith(entries, nil)
+}
+
+// sortIndexWith orders entries like sortIndex and calls swap, if not nil,
+// for every swap of two entries so that values kept alongside them follow
+func (s *SitemapSplitter) sortIndexWith(entries []Sitemap, swap func(i, j int)) {
+	if s.indexLess == nil && s.indexOrder != IndexOrderNewestFirst {
+		return
+	}
+	sort.Stable(indexSorter{s: s, entries: entries, swap: swap})
+}
+
+// indexSorter sorts index entries for sortIndexWith
+type indexSorter struct {
+	s       *SitemapSplitter
+	entries []Sitemap
+	swap    func(i, j int)
+}
+
+func (x indexSorter) Len() int { return len(x.entries) }
+
+func (x indexSorter) Less(i, j int) bool {
+	if x.s.indexLess != nil {
+		return x.s.indexLess(x.entries[i], x.entries[j])
+	}
+	ti, _ := parseLastMod(x.entries[i].LastMod, x.s.location)
+	tj, _ := parseLastMod(x.entries[j].LastMod, x.s.location)
+	return ti.After(tj)
+}
+
+func (x indexSorter) Swap(i, j int) {
+	x.entries[i], x.entries[j] = x.entries[j], x.entries[i]
+	if x.swap != nil {
+		x.swap(i, j)
+	}
+}
@@ -0,0 +1,195 @@
+package sitemapsplitter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestIndexOrder(t *testing.T) {
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` +
+		`<url><loc>https://example.com/a</loc><lastmod>2024-01-01</lastmod></url>` +
+		`<url><loc>https://example.com/b</loc><lastmod>2024-03-01</lastmod></url>` +
+		`<url><loc>https://example.com/c</loc><lastmod>2024-02-01</lastmod></url>` +
+		`<url><loc>https://example.com/d</loc><lastmod>2023-12-01</lastmod></url>` +
+		`</urlset>`
+	byNameDesc := func(a, b Sitemap) bool { return a.Loc > b.Loc }
+	tests := []struct {
+		name string
+		opts []Option
+		want []string // Chunk names in index order
+	}{
+		{"chunk", nil, []string{"in-1.xml", "in-2.xml", "in-3.xml", "in-4.xml"}},
+		{"newest first", []Option{WithIndexOrder(IndexOrderNewestFirst)}, []string{"in-2.xml", "in-3.xml", "in-1.xml", "in-4.xml"}},
+		{"newest first streaming", []Option{WithIndexOrder(IndexOrderNewestFirst), WithStreaming()}, []string{"in-2.xml", "in-3.xml", "in-1.xml", "in-4.xml"}},
+		{"custom", []Option{WithIndexLess(byNameDesc)}, []string{"in-4.xml", "in-3.xml", "in-2.xml", "in-1.xml"}},
+		{"custom wins", []Option{WithIndexLess(byNameDesc), WithIndexOrder(IndexOrderNewestFirst)}, []string{"in-4.xml", "in-3.xml", "in-2.xml", "in-1.xml"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := NewMemorySink()
+			s, err := NewSitemapSplitter("in.xml", 1, append([]Option{WithSink(sink)}, tt.opts...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+				t.Fatal(err)
+			}
+
+			data, _ := sink.File("sitemap-index.xml")
+			var index SitemapIndex
+			if err := xml.Unmarshal(data, &index); err != nil {
+				t.Fatal(err)
+			}
+			var got, files []string
+			for _, e := range index.Sitemaps {
+				got = append(got, strings.TrimPrefix(e.Loc, "https://example.com/"))
+			}
+			for _, f := range s.LastResult().Files {
+				files = append(files, f.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("index lists %v, want %v", got, tt.want)
+			}
+			if !slices.Equal(files, tt.want) {
+				t.Errorf("result lists %v, want %v as in the index", files, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexBaseURL(t *testing.T) {
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` +
+		`<url><loc>https://a.example.com/x</loc></url>` +
+		`<url><loc>http://b.example.com/y</loc></url>` +
+		`</urlset>`
+	tests := []struct {
+		name    string
+		baseURL string
+		want    []string
+	}{
+		{"derived from chunks", "", []string{"https://a.example.com/in-1.xml", "http://b.example.com/in-2.xml"}},
+		{"base URL", "https://cdn.example.com/sitemaps/", []string{"https://cdn.example.com/sitemaps/in-1.xml", "https://cdn.example.com/sitemaps/in-2.xml"}},
+		{"missing slash", "https://cdn.example.com/sitemaps", []string{"https://cdn.example.com/sitemaps/in-1.xml", "https://cdn.example.com/sitemaps/in-2.xml"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := NewMemorySink()
+			s, err := NewSitemapSplitter("in.xml", 1, WithSink(sink), WithIndexBaseURL(tt.baseURL))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+				t.Fatal(err)
+			}
+
+			data, _ := sink.File("sitemap-index.xml")
+			var index SitemapIndex
+			if err := xml.Unmarshal(data, &index); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range index.Sitemaps {
+				got = append(got, e.Loc)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("index lists %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewSitemapSplitter("in.xml", 1, WithIndexBaseURL("/sitemaps/")); err == nil {
+		t.Error("relative index base URL accepted")
+	}
+}
+
+func TestIndexName(t *testing.T) {
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc></url><url><loc>https://example.com/b</loc></url></urlset>`
+	tests := []struct {
+		name string
+		opts []Option
+		want []string // Files written, sorted
+	}{
+		{"default", nil, []string{"in-1.xml", "in-2.xml", "sitemap-index.xml"}},
+		{"custom name", []Option{WithIndexName("sitemap.xml")}, []string{"in-1.xml", "in-2.xml", "sitemap.xml"}},
+		{"gzipped", []Option{WithIndexName("sitemap.xml"), WithGzipOutput(true)}, []string{"in-1.xml.gz", "in-2.xml.gz", "sitemap.xml.gz"}},
+		{"without index", []Option{WithoutIndex()}, []string{"in-1.xml", "in-2.xml"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := NewMemorySink()
+			s, err := NewSitemapSplitter("in.xml", 1, append([]Option{WithSink(sink)}, tt.opts...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+				t.Fatal(err)
+			}
+			if got := sink.Names(); !slices.Equal(got, tt.want) {
+				t.Errorf("wrote %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewSitemapSplitter("in.xml", 1, WithIndexName("")); err == nil {
+		t.Error("empty index name accepted")
+	}
+}
+
+func TestIndexLocTemplate(t *testing.T) {
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc><lastmod>2024-05-01</lastmod></url></urlset>`
+	plain := NewMemorySink()
+	s, err := NewSitemapSplitter("in.xml", 1, WithSink(plain))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	chunk, _ := plain.File("in-1.xml")
+	sum := sha256.Sum256(chunk)
+	hash := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		tmpl    string
+		opts    []Option
+		want    string // Loc of the index entry, empty if the split fails
+		wantErr bool
+	}{
+		{"https://cdn.example.com/seo/{{.Name}}", nil, "https://cdn.example.com/seo/in-1.xml", false},
+		{"{{.BaseURL}}{{.Name}}?n={{.URLs}}&d={{.LastMod}}", nil, "https://example.com/in-1.xml?n=1&amp;d=2024-05-01", false},
+		{"{{.BaseURL}}{{.Name}}", []Option{WithIndexBaseURL("https://static.example.com/maps")}, "https://static.example.com/maps/in-1.xml", false},
+		{"https://cdn.example.com/{{.Name}}?v={{slice .Hash 0 8}}", nil, "https://cdn.example.com/in-1.xml?v=" + hash[:8], false},
+		{"/seo/{{.Name}}", nil, "", true},
+		{"{{.Missing}}", nil, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tmpl, func(t *testing.T) {
+			sink := NewMemorySink()
+			s, err := NewSitemapSplitter("in.xml", 1, append([]Option{WithSink(sink), WithIndexLocTemplate(tt.tmpl)}, tt.opts...)...)
+			if err == nil {
+				err = s.SplitFrom(strings.NewReader(in))
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Error("split succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			index, _ := sink.File("sitemap-index.xml")
+			if !strings.Contains(string(index), "<loc>"+tt.want+"</loc>") {
+				t.Errorf("index lacks %s:\n%s", tt.want, index)
+			}
+		})
+	}
+
+	if _, err := NewSitemapSplitter("in.xml", 1, WithIndexLocTemplate("{{.Name")); err == nil {
+		t.Error("malformed template accepted")
+	}
+}
@@ -0,0 +1,196 @@
+package sitemapsplitter
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// decodeIndex reads the <sitemap> entries of a sitemap index whose root
+// element d has just consumed and decodes every child sitemap, so the URLs of
+// all children are re-split as one input. Each child is read as soon as its
+// entry is complete, up to childFetches of them at once.
+func (s *SitemapSplitter) decodeIndex(d *xml.Decoder, name string, fn func(URL) error) error {
+	if s.childFetches <= 1 {
+		return s.indexEntries(d, name, func(entry Sitemap) error {
+			return s.readChild(entry, fn)
+		})
+	}
+	f := &childFetcher{s: s, fn: fn}
+	return f.wait(s.indexEntries(d, name, f.add))
+}
+
+// indexEntries calls fn with every <sitemap> entry of the index whose root
+// element d has just consumed
+func (s *SitemapSplitter) indexEntries(d *xml.Decoder, name string, fn func(Sitemap) error) error {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return fmt.Errorf("error parsing XML in %s: %v", name, err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "sitemap" {
+				if err := d.Skip(); err != nil {
+					return fmt.Errorf("error parsing XML in %s: %v", name, err)
+				}
+				continue
+			}
+
+			var entry Sitemap
+			if err := d.DecodeElement(&entry, &t); err != nil {
+				return fmt.Errorf("error parsing XML in %s: %v", name, err)
+			}
+			if entry.Loc == "" {
+				continue
+			}
+			if err := fn(entry); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// childFetcher reads child sitemaps concurrently and passes their URLs on in
+// the order the children were added
+type childFetcher struct {
+	s       *SitemapSplitter
+	fn      func(URL) error
+	pending []*childFetch // Children being read, oldest first
+}
+
+// childFetch is a child sitemap read by a childFetcher
+type childFetch struct {
+	done chan struct{} // Closed once urls and err are set
+	urls []URL
+	err  error
+}
+
+// add starts reading the child of entry, first passing on the oldest child
+// when childFetches of them are already being read
+func (f *childFetcher) add(entry Sitemap) error {
+	if len(f.pending) == f.s.childFetches {
+		if err := f.next(); err != nil {
+			return err
+		}
+	}
+	c := &childFetch{done: make(chan struct{})}
+	f.pending = append(f.pending, c)
+	go func() {
+		defer close(c.done)
+		c.err = f.s.readChild(entry, func(u URL) error {
+			c.urls = append(c.urls, u)
+			return nil
+		})
+	}()
+	return nil
+}
+
+// next waits for the oldest child being read and passes its URLs on
+func (f *childFetcher) next() error {
+	c := f.pending[0]
+	f.pending = f.pending[1:]
+	<-c.done
+	if c.err != nil {
+		return c.err
+	}
+	for _, u := range c.urls {
+		if err := f.fn(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wait passes on the URLs of the children still being read. After err, or
+// the first error of a child, it only waits for the rest to finish.
+func (f *childFetcher) wait(err error) error {
+	for len(f.pending) > 0 {
+		if err != nil {
+			<-f.pending[0].done
+			f.pending = f.pending[1:]
+			continue
+		}
+		err = f.next()
+	}
+	return err
+}
+
+// readChild decodes the child sitemap of entry. Remote children are fetched
+// over HTTP, recording their Last-Modified header as source modification
+// time, or the entry's lastmod without one. Other locs are file paths,
+// relative ones resolved against the directory of the input, or against its
+// URL for a remote input.
+func (s *SitemapSplitter) readChild(entry Sitemap, fn func(URL) error) error {
+	loc := s.childLocation(entry.Loc)
+	s.logger.Debug("reading child sitemap", "phase", "parse", "loc", loc)
+
+	if !isRemote(loc) {
+		f, err := os.Open(loc)
+		if err != nil {
+			return fmt.Errorf("error reading child sitemap: %v", err)
+		}
+		defer f.Close()
+
+		info, err := f.Stat()
+		if err != nil {
+			return fmt.Errorf("error reading child sitemap: %v", err)
+		}
+		return s.decodeDocument(f, loc, info.ModTime(), nil, fn)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, loc, nil)
+	if err != nil {
+		return fmt.Errorf("error fetching child sitemap: %v", err)
+	}
+	resp, err := s.doRequest(req)
+	if err != nil {
+		return fmt.Errorf("error fetching child sitemap: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error fetching child sitemap %s: %s", loc, resp.Status)
+	}
+
+	var modTime time.Time
+	if header := resp.Header.Get("Last-Modified"); header != "" {
+		modTime, _ = http.ParseTime(header)
+	}
+	if modTime.IsZero() && entry.LastMod != "" {
+		modTime, _ = parseLastMod(entry.LastMod, s.location)
+	}
+	return s.decodeDocument(resp.Body, loc, modTime, nil, func(u URL) error {
+		u.childModTime = modTime
+		return fn(u)
+	})
+}
+
+// childLocation resolves the loc of a sitemap index entry
+func (s *SitemapSplitter) childLocation(loc string) string {
+	if isRemote(loc) {
+		return loc
+	}
+	if isRemote(s.path) {
+		base, err := url.Parse(s.path)
+		if err != nil {
+			return loc
+		}
+		ref, err := url.Parse(loc)
+		if err != nil {
+			return loc
+		}
+		return base.ResolveReference(ref).String()
+	}
+	if filepath.IsAbs(loc) {
+		return loc
+	}
+	return filepath.Join(filepath.Dir(s.path), loc)
+}
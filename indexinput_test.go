@@ -0,0 +1,309 @@
+package sitemapsplitter
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIndexLastModFromChildren(t *testing.T) {
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	child := func(path, lastModified string) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if lastModified != "" {
+				w.Header().Set("Last-Modified", lastModified)
+			}
+			fmt.Fprintf(w, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>%s%s/page</loc></url></urlset>`, srv.URL, path)
+		})
+	}
+	child("/header.xml", "Tue, 02 Jan 2024 10:00:00 GMT")
+	child("/entry.xml", "")
+	child("/none.xml", "")
+	mux.HandleFunc("/index.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`+
+			`<sitemap><loc>%[1]s/header.xml</loc><lastmod>2023-01-01</lastmod></sitemap>`+
+			`<sitemap><loc>%[1]s/entry.xml</loc><lastmod>2024-03-04T05:06:07Z</lastmod></sitemap>`+
+			`<sitemap><loc>%[1]s/none.xml</loc></sitemap></sitemapindex>`, srv.URL)
+	})
+
+	out := t.TempDir()
+	s, err := NewSitemapSplitter(srv.URL+"/index.xml", 1, WithOutputDir(out), WithTimezone(time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Split(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(out, "sitemap-index.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		chunk       string
+		wantLastMod string
+	}{
+		{"index-1.xml", "2024-01-02T10:00:00Z"},
+		{"index-2.xml", "2024-03-04T05:06:07Z"},
+		{"index-3.xml", ""},
+	}
+	for _, tt := range tests {
+		_, entry, _ := strings.Cut(string(data), tt.chunk+"</loc>")
+		entry, _, _ = strings.Cut(entry, "</sitemap>")
+		if tt.wantLastMod == "" {
+			if strings.Contains(entry, "<lastmod>") {
+				t.Errorf("%s: index entry %q has a lastmod, want none", tt.chunk, entry)
+			}
+		} else if !strings.Contains(entry, "<lastmod>"+tt.wantLastMod) {
+			t.Errorf("%s: index entry %q lacks lastmod %s", tt.chunk, entry, tt.wantLastMod)
+		}
+	}
+}
+
+func TestIndexLastModLocalInput(t *testing.T) {
+	old := time.Date(2020, 5, 6, 7, 8, 9, 0, time.UTC)
+	tests := []struct {
+		name  string
+		input func(dir string) string // Writes the input and returns its path
+	}{
+		{"sitemap", func(dir string) string {
+			path := filepath.Join(dir, "in.xml")
+			writeSitemap(t, path, "https://example.com/a")
+			return path
+		}},
+		{"local child", func(dir string) string {
+			writeSitemap(t, filepath.Join(dir, "child.xml"), "https://example.com/a")
+			path := filepath.Join(dir, "in.xml")
+			index := `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><sitemap><loc>child.xml</loc></sitemap></sitemapindex>`
+			if err := os.WriteFile(path, []byte(index), 0644); err != nil {
+				t.Fatal(err)
+			}
+			return path
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			input := tt.input(dir)
+			names, _ := filepath.Glob(filepath.Join(dir, "*.xml"))
+			for _, name := range names {
+				if err := os.Chtimes(name, old, old); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			out := filepath.Join(dir, "out")
+			s, err := NewSitemapSplitter(input, 1, WithOutputDir(out), WithTimezone(time.UTC))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Split(); err != nil {
+				t.Fatal(err)
+			}
+			if fileContains(filepath.Join(out, "sitemap-index.xml"), "<lastmod>") {
+				t.Error("index entry has a lastmod, want none for a chunk without lastmods")
+			}
+		})
+	}
+}
+
+func TestChildConcurrency(t *testing.T) {
+	const children = 8
+	var (
+		mu             sync.Mutex
+		inFlight, peak int // Children being served now and at most
+	)
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	for i := range children {
+		mux.HandleFunc(fmt.Sprintf("/child-%d.xml", i), func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			inFlight++
+			peak = max(peak, inFlight)
+			mu.Unlock()
+			defer func() {
+				mu.Lock()
+				inFlight--
+				mu.Unlock()
+			}()
+			// Later children answer sooner, so reading them in turn is
+			// the only way to keep index order
+			time.Sleep(time.Duration(children-i) * 5 * time.Millisecond)
+			if i == 5 && r.URL.Query().Has("fail") {
+				http.NotFound(w, r)
+				return
+			}
+			fmt.Fprintf(w, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/%[1]d/a</loc></url><url><loc>https://example.com/%[1]d/b</loc></url></urlset>`, i)
+		})
+	}
+	index := func(query string) string {
+		var b strings.Builder
+		b.WriteString(`<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+		for i := range children {
+			fmt.Fprintf(&b, `<sitemap><loc>%s/child-%d.xml%s</loc></sitemap>`, srv.URL, i, query)
+		}
+		return b.String() + `</sitemapindex>`
+	}
+	var want []string
+	for i := range children {
+		want = append(want, fmt.Sprintf("https://example.com/%d/a", i), fmt.Sprintf("https://example.com/%d/b", i))
+	}
+
+	tests := []struct {
+		name    string
+		n       int
+		query   string
+		wantErr bool
+	}{
+		{"sequential", 0, "", false},
+		{"bounded", 3, "", false},
+		{"more than children", 20, "", false},
+		{"failing child", 3, "?fail", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			peak = 0
+			mu.Unlock()
+			s, err := NewSitemapSplitter(filepath.Join(t.TempDir(), "index.xml"), 50000, WithChildConcurrency(tt.n))
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.reader = strings.NewReader(index(tt.query))
+			urls, err := s.readURLs()
+			if tt.wantErr {
+				if err == nil {
+					t.Error("failing child did not fail the read")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, u := range urls {
+				got = append(got, u.Loc)
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("locs not in index order:\n got %v\nwant %v", got, want)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if limit := max(tt.n, 1); peak > limit {
+				t.Errorf("%d children read at once, limit %d", peak, limit)
+			}
+			if tt.n > 1 && peak < 2 {
+				t.Error("children were not read concurrently")
+			}
+		})
+	}
+}
+
+func TestIndexInput(t *testing.T) {
+	const index = `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><sitemap><loc>%s</loc></sitemap><sitemap><loc>%s</loc></sitemap></sitemapindex>`
+	children := map[string][]string{
+		"/one.xml": {"https://example.com/a", "https://example.com/b", "https://example.com/c"},
+		"/two.xml": {"https://example.com/d", "https://example.com/e"},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/index.xml" {
+			fmt.Fprintf(w, index, "one.xml", "/two.xml")
+			return
+		}
+		locs, ok := children[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`))
+		for _, l := range locs {
+			fmt.Fprintf(w, "<url><loc>%s</loc></url>", l)
+		}
+		w.Write([]byte(`</urlset>`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name  string
+		input func(dir string) string // Writes the input and returns its path
+		want  [][]string              // Locs of each chunk, nil if the split fails
+	}{
+		{"local children", func(dir string) string {
+			writeSitemap(t, filepath.Join(dir, "one.xml"), children["/one.xml"]...)
+			writeSitemap(t, filepath.Join(dir, "two.xml"), children["/two.xml"]...)
+			return writeIndexInput(t, dir, "one.xml", filepath.Join(dir, "two.xml"))
+		}, [][]string{{"https://example.com/a", "https://example.com/b"}, {"https://example.com/c", "https://example.com/d"}, {"https://example.com/e"}}},
+		{"remote children", func(dir string) string {
+			return writeIndexInput(t, dir, srv.URL+"/one.xml", srv.URL+"/two.xml")
+		}, [][]string{{"https://example.com/a", "https://example.com/b"}, {"https://example.com/c", "https://example.com/d"}, {"https://example.com/e"}}},
+		{"remote index", func(dir string) string {
+			return srv.URL + "/index.xml"
+		}, [][]string{{"https://example.com/a", "https://example.com/b"}, {"https://example.com/c", "https://example.com/d"}, {"https://example.com/e"}}},
+		{"missing child", func(dir string) string {
+			writeSitemap(t, filepath.Join(dir, "one.xml"), children["/one.xml"]...)
+			return writeIndexInput(t, dir, "one.xml", "missing.xml")
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := NewMemorySink()
+			s, err := NewSitemapSplitter(tt.input(t.TempDir()), 2, WithSink(sink))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = s.Split()
+			if tt.want == nil {
+				if err == nil {
+					t.Error("split succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, want := range tt.want {
+				data, ok := sink.File(fmt.Sprintf("index-%d.xml", i+1))
+				if !ok {
+					t.Fatalf("chunk %d not written, got %v", i+1, sink.Names())
+				}
+				for _, l := range want {
+					if !strings.Contains(string(data), "<loc>"+l+"</loc>") {
+						t.Errorf("chunk %d lacks %s", i+1, l)
+					}
+				}
+			}
+			if n := len(sink.Names()); n != len(tt.want)+1 {
+				t.Errorf("wrote %v, want %d chunks and the index", sink.Names(), len(tt.want))
+			}
+		})
+	}
+}
+
+// writeIndexInput writes a sitemap index listing locs to index.xml in dir and
+// returns its path
+func writeIndexInput(t *testing.T, dir string, locs ...string) string {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString(`<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	for _, l := range locs {
+		fmt.Fprintf(&b, "<sitemap><loc>%s</loc></sitemap>", l)
+	}
+	b.WriteString(`</sitemapindex>`)
+	path := filepath.Join(dir, "index.xml")
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,50 @@
+package sitemapsplitter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// IndexLocData is the data an index loc template is executed with, see
+// WithIndexLocTemplate
+type IndexLocData struct {
+	Name    string // Chunk file name as referenced by the index, e.g. "sitemap-1.xml.gz"
+	BaseURL string // Configured index base URL, or the one derived from the chunk
+	Hash    string // Hex SHA-256 of the chunk's uncompressed content
+	LastMod string // Lastmod of the index entry
+	URLs    int    // URLs in the chunk
+}
+
+// indexLoc returns the index loc of chunk c, expanding the index loc template
+// when one is configured
+func (s *SitemapSplitter) indexLoc(c chunk, baseURL, lastMod string) (string, error) {
+	name := s.indexedName(c.name)
+	if s.locTemplate == nil {
+		return baseURL + name, nil
+	}
+
+	h := sha256.New()
+	if err := s.encodeChunk(h, c.urls); err != nil {
+		return "", fmt.Errorf("error hashing sitemap file: %v", err)
+	}
+	data := IndexLocData{
+		Name:    name,
+		BaseURL: baseURL,
+		Hash:    hex.EncodeToString(h.Sum(nil)),
+		LastMod: lastMod,
+		URLs:    len(c.urls),
+	}
+
+	var b strings.Builder
+	if err := s.locTemplate.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("error expanding index loc template: %v", err)
+	}
+	loc := b.String()
+	if u, err := url.Parse(loc); err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("index loc %q from the template is not an absolute URL", loc)
+	}
+	return loc, nil
+}
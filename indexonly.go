@@ -0,0 +1,116 @@
+package sitemapsplitter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// IndexLastMod selects where WriteIndex takes the lastmod of index entries
+// from
+type IndexLastMod int
+
+const (
+	// IndexLastModOmit writes entries without lastmod
+	IndexLastModOmit IndexLastMod = iota
+	// IndexLastModNow stamps every entry with the time of the run
+	IndexLastModNow
+	// IndexLastModHeader uses the Last-Modified header returned for a HEAD
+	// request to each sitemap, omitting lastmod when the server sends none
+	IndexLastModHeader
+)
+
+// WriteIndex writes only a sitemap index listing locs, the absolute URLs of
+// sitemaps that already exist, without reading or splitting the input. The
+// index is written to the configured sink under the index name, ordered by
+// the configured index order.
+func (s *SitemapSplitter) WriteIndex(locs []string, lastMod IndexLastMod) error {
+	if len(locs) == 0 {
+		return fmt.Errorf("no sitemap URLs given")
+	}
+	if err := checkIndexEntries(len(locs)); err != nil {
+		return err
+	}
+
+	now := s.formatTime(s.now())
+	entries := make([]Sitemap, 0, len(locs))
+	for _, loc := range locs {
+		parsed, err := url.Parse(loc)
+		if err != nil || !parsed.IsAbs() || parsed.Host == "" {
+			return fmt.Errorf("invalid sitemap URL %q", loc)
+		}
+
+		entry := Sitemap{Loc: loc}
+		switch lastMod {
+		case IndexLastModNow:
+			entry.LastMod = now
+		case IndexLastModHeader:
+			if entry.LastMod, err = s.fetchLastModified(loc); err != nil {
+				return err
+			}
+		}
+		entries = append(entries, entry)
+	}
+	s.sortIndex(entries)
+
+	sitemapIndex := SitemapIndex{
+		XMLNS:    "http://www.sitemaps.org/schemas/sitemap/0.9",
+		Sitemaps: entries,
+	}
+	if err := s.checkIndexBytes(sitemapIndex); err != nil {
+		return err
+	}
+	if err := s.writeXMLFile(s.indexFile(), OutputIndex, sitemapIndex); err != nil {
+		return fmt.Errorf("error writing sitemap index: %v", err)
+	}
+	s.logger.Info("wrote sitemap index", "phase", "write", "name", s.indexFile(), "sitemaps", len(entries))
+	return nil
+}
+
+// fetchLastModified returns the Last-Modified time of the sitemap at loc in
+// the configured lastmod format, or "" if the server does not send one
+func (s *SitemapSplitter) fetchLastModified(loc string) (string, error) {
+	req, err := http.NewRequest(http.MethodHead, loc, nil)
+	if err != nil {
+		return "", fmt.Errorf("error checking sitemap %s: %v", loc, err)
+	}
+	resp, err := s.doRequest(req)
+	if err != nil {
+		return "", fmt.Errorf("error checking sitemap %s: %v", loc, err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error checking sitemap %s: %s", loc, resp.Status)
+	}
+	header := resp.Header.Get("Last-Modified")
+	if header == "" {
+		return "", nil
+	}
+	t, err := http.ParseTime(header)
+	if err != nil {
+		return "", nil
+	}
+	return s.formatTime(t.In(s.location)), nil
+}
+
+// ReadSitemapList reads sitemap URLs from r, one per line, for WriteIndex.
+// Blank lines and lines starting with # are skipped.
+func ReadSitemapList(r io.Reader) ([]string, error) {
+	var locs []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		locs = append(locs, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading sitemap list: %v", err)
+	}
+	return locs, nil
+}
@@ -0,0 +1,88 @@
+package sitemapsplitter
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteIndex(t *testing.T) {
+	modified := time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("got %s request, want HEAD", r.Method)
+		}
+		switch r.URL.Path {
+		case "/missing.xml":
+			http.NotFound(w, r)
+			return
+		case "/a.xml":
+			w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		locs    []string
+		lastMod IndexLastMod
+		want    []Sitemap
+		wantErr bool
+	}{
+		{"omit", []string{"https://example.com/a.xml", "https://example.com/b.xml"}, IndexLastModOmit,
+			[]Sitemap{{Loc: "https://example.com/a.xml"}, {Loc: "https://example.com/b.xml"}}, false},
+		{"now", []string{"https://example.com/a.xml"}, IndexLastModNow,
+			[]Sitemap{{Loc: "https://example.com/a.xml", LastMod: time.Now().UTC().Format("2006-01-02")}}, false},
+		{"header", []string{srv.URL + "/a.xml", srv.URL + "/b.xml"}, IndexLastModHeader,
+			[]Sitemap{{Loc: srv.URL + "/a.xml", LastMod: "2024-06-01"}, {Loc: srv.URL + "/b.xml"}}, false},
+		{"failed HEAD", []string{srv.URL + "/missing.xml"}, IndexLastModHeader, nil, true},
+		{"relative URL", []string{"/a.xml"}, IndexLastModOmit, nil, true},
+		{"no URLs", nil, IndexLastModOmit, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := NewMemorySink()
+			s, err := NewSitemapSplitter("in.xml", 1, WithSink(sink), WithLastModFormat(LastModDate), WithTimezone(time.UTC))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = s.WriteIndex(tt.locs, tt.lastMod)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WriteIndex error %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if names := sink.Names(); len(names) > 0 {
+					t.Errorf("failed WriteIndex stored %v", names)
+				}
+				return
+			}
+
+			data, _ := sink.File("sitemap-index.xml")
+			var index SitemapIndex
+			if err := xml.Unmarshal(data, &index); err != nil {
+				t.Fatal(err)
+			}
+			for i := range index.Sitemaps {
+				index.Sitemaps[i].XMLName = xml.Name{}
+			}
+			if !slices.Equal(index.Sitemaps, tt.want) {
+				t.Errorf("index lists %v, want %v", index.Sitemaps, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadSitemapList(t *testing.T) {
+	in := "# sitemaps\nhttps://example.com/a.xml\n\n  https://example.com/b.xml  \n#https://example.com/c.xml\n"
+	locs, err := ReadSitemapList(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"https://example.com/a.xml", "https://example.com/b.xml"}; !slices.Equal(locs, want) {
+		t.Errorf("read %v, want %v", locs, want)
+	}
+}
@@ -0,0 +1,300 @@
+package sitemapsplitter
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// archiveSuffixes lists the archive extensions recognized as multi-sitemap
+// input, longest first so ".tar.gz" wins over ".gz"
+var archiveSuffixes = []string{".tar.gz", ".tgz", ".tar", ".zip"}
+
+// archiveSuffix returns the archive extension of name, or "" if name is not
+// an archive
+func archiveSuffix(name string) string {
+	lower := strings.ToLower(name)
+	for _, suffix := range archiveSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return suffix
+		}
+	}
+	return ""
+}
+
+// inputBaseName returns the input filename without its extension, used as
+// the prefix of chunk names
+func inputBaseName(p string) string {
+	if isRemote(p) {
+		p = remoteName(p)
+	}
+	filename := filepath.Base(p)
+	if suffix := archiveSuffix(filename); suffix != "" {
+		return filename[:len(filename)-len(suffix)]
+	}
+	if strings.EqualFold(filepath.Ext(filename), ".gz") {
+		filename = filename[:len(filename)-len(".gz")]
+	}
+	return filename[:len(filename)-len(filepath.Ext(filename))]
+}
+
+// readURLs reads every URL from the input, which is either a single sitemap
+// or a tar/zip archive of sitemaps combined into one input set
+func (s *SitemapSplitter) readURLs() ([]URL, error) {
+	var urls []URL
+	err := s.eachURL(func(u URL) error {
+		urls = append(urls, u)
+		return nil
+	})
+	return urls, err
+}
+
+// eachURL decodes the input one <url> element at a time and calls fn with
+// each URL in input order. Errors returned by fn stop decoding and are
+// returned as is.
+func (s *SitemapSplitter) eachURL(fn func(URL) error) error {
+	if s.ctx != nil {
+		next := fn
+		fn = func(u URL) error {
+			if err := s.canceled(); err != nil {
+				return err
+			}
+			return next(u)
+		}
+	}
+
+	if s.source != nil {
+		return eachSourceURL(s.source, fn)
+	}
+	if s.reader != nil {
+		return s.decodeURLs(s.reader, "input", s.readerModTime, fn)
+	}
+
+	switch archiveSuffix(s.path) {
+	case ".zip":
+		return s.readZip(fn)
+	case ".tar", ".tar.gz", ".tgz":
+		return s.readTar(fn)
+	}
+
+	f, err := os.Open(s.path)
+	if err != nil {
+		return fmt.Errorf("error reading sitemap file: %v", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("error reading sitemap file: %v", err)
+	}
+	return s.decodeURLs(f, s.path, info.ModTime(), fn)
+}
+
+// hashInput returns the digest of the input used to detect unchanged input.
+// A SplitFrom reader has been buffered in memory so it can be read twice.
+func (s *SitemapSplitter) hashInput() (string, error) {
+	if br, ok := s.reader.(*bytes.Reader); ok {
+		defer br.Seek(0, io.SeekStart)
+		return hashReader(br)
+	}
+	return hashFile(s.path)
+}
+
+// EmptyInputPolicy controls how input without any URLs is handled
+type EmptyInputPolicy int
+
+const (
+	// EmptyInputFail fails the split when the input holds no URLs
+	EmptyInputFail EmptyInputPolicy = iota
+	// EmptyInputAllow treats empty input as success and writes a valid index
+	// without any entries
+	EmptyInputAllow
+)
+
+// decodeURLs parses a single sitemap document read from r, calling fn with
+// every URL as soon as its element is complete and recording modTime as its
+// source modification time. Empty and whitespace-only documents yield no URLs.
+// A sitemap index yields the URLs of all its child sitemaps, and a text
+// sitemap a URL per line.
+func (s *SitemapSplitter) decodeURLs(r io.Reader, name string, modTime time.Time, fn func(URL) error) error {
+	return s.decodeDocument(r, name, modTime, func(d *xml.Decoder) error {
+		return s.decodeIndex(d, name, fn)
+	}, fn)
+}
+
+// decodeDocument is decodeURLs with the handling of a sitemap index left to
+// onIndex, which is called once the <sitemapindex> root has been read. A nil
+// onIndex rejects sitemap indexes.
+func (s *SitemapSplitter) decodeDocument(r io.Reader, name string, modTime time.Time, onIndex func(*xml.Decoder) error, fn func(URL) error) error {
+	r, err := decompress(r)
+	if err != nil {
+		return fmt.Errorf("error decompressing %s: %v", name, err)
+	}
+	r, text := sniffText(r)
+	if text {
+		return s.decodeText(r, name, modTime, fn)
+	}
+
+	d := s.newDecoder(r)
+	depth := 0
+	var root nsScope // Namespaces declared on the <urlset> root
+	for {
+		tok, err := d.Token()
+		if err == io.EOF && depth == 0 {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error parsing XML in %s: %v", name, err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				if t.Name.Local == "sitemapindex" {
+					if onIndex == nil {
+						return fmt.Errorf("error parsing XML in %s: nested sitemap indexes are not supported", name)
+					}
+					return onIndex(d)
+				}
+				if t.Name.Local != "urlset" {
+					return fmt.Errorf("error parsing XML in %s: expected element type <urlset> but have <%s>", name, t.Name.Local)
+				}
+				root = root.declare(t.Attr)
+				depth++
+				continue
+			}
+			if t.Name.Local != "url" {
+				if err := d.Skip(); err != nil {
+					return fmt.Errorf("error parsing XML in %s: %v", name, err)
+				}
+				continue
+			}
+
+			var u URL
+			if err := d.DecodeElement(&u, &t); err != nil {
+				return fmt.Errorf("error parsing XML in %s: %v", name, err)
+			}
+			if len(root) > 0 {
+				u.resolvePrefixes(root)
+			}
+			u.sourceModTime = modTime
+			if err := fn(u); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			// Only the end of the root is seen here; anything after it is
+			// ignored, as xml.Decoder.Decode does
+			return nil
+		}
+	}
+}
+
+// gzipMagic is the header every gzip stream starts with
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// decompress returns a reader of the decompressed content of r if r holds
+// gzip data, recognized by its magic bytes rather than by file extension,
+// and a reader of r's content otherwise
+func decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(gzipMagic))
+	if err != nil || !bytes.Equal(magic, gzipMagic) {
+		// Not gzip, or too short to tell
+		return br, nil
+	}
+	return gzip.NewReader(br)
+}
+
+// isSitemapMember reports whether an archive member should be read as a
+// sitemap
+func isSitemapMember(name string) bool {
+	if strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(path.Base(name), ".") {
+		return false
+	}
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".xml", ".xml.gz", ".txt", ".txt.gz"} {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// skipIndex ignores a sitemap index found in an archive, whose children are
+// expected to be the other members
+func skipIndex(*xml.Decoder) error {
+	return nil
+}
+
+// readZip reads the sitemaps contained in a zip archive
+func (s *SitemapSplitter) readZip(fn func(URL) error) error {
+	zr, err := zip.OpenReader(s.path)
+	if err != nil {
+		return fmt.Errorf("error reading sitemap archive: %v", err)
+	}
+	defer zr.Close()
+
+	for _, member := range zr.File {
+		if member.FileInfo().IsDir() || !isSitemapMember(member.Name) {
+			continue
+		}
+
+		rc, err := member.Open()
+		if err != nil {
+			return fmt.Errorf("error reading %s from archive: %v", member.Name, err)
+		}
+		err = s.decodeDocument(rc, member.Name, member.Modified, skipIndex, fn)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readTar reads the sitemaps contained in a tar archive, optionally gzipped
+func (s *SitemapSplitter) readTar(fn func(URL) error) error {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return fmt.Errorf("error reading sitemap archive: %v", err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if suffix := archiveSuffix(s.path); suffix == ".tar.gz" || suffix == ".tgz" {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("error reading sitemap archive: %v", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading sitemap archive: %v", err)
+		}
+		if hdr.Typeflag != tar.TypeReg || !isSitemapMember(hdr.Name) {
+			continue
+		}
+
+		if err := s.decodeDocument(tr, hdr.Name, hdr.ModTime, skipIndex, fn); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package sitemapsplitter
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// archiveMember is a file of a test archive
+type archiveMember struct {
+	name, content string
+}
+
+// writeArchive writes members to path as a zip, tar or gzipped tar archive,
+// depending on the path's suffix
+func writeArchive(t *testing.T, path string, members []archiveMember) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if strings.HasSuffix(path, ".zip") {
+		zw := zip.NewWriter(f)
+		for _, m := range members {
+			w, err := zw.Create(m.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			io.WriteString(w, m.content)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	var w io.Writer = f
+	if strings.HasSuffix(path, ".gz") || strings.HasSuffix(path, ".tgz") {
+		gz := gzip.NewWriter(f)
+		defer gz.Close()
+		w = gz
+	}
+	tw := tar.NewWriter(w)
+	for _, m := range members {
+		hdr := &tar.Header{Name: m.name, Mode: 0644, Size: int64(len(m.content)), ModTime: time.Now()}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(tw, m.content)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestArchiveInput(t *testing.T) {
+	members := []archiveMember{
+		{"a.xml", `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a1</loc></url><url><loc>https://example.com/a2</loc></url></urlset>`},
+		{"sitemap-index.xml", `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><sitemap><loc>https://example.com/a.xml</loc></sitemap></sitemapindex>`},
+		{"sub/b.txt", "https://example.com/b1\n"},
+		{".hidden.xml", `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/hidden</loc></url></urlset>`},
+		{"__MACOSX/a.xml", "not a sitemap"},
+		{"README.md", "not a sitemap"},
+	}
+	for _, name := range []string{"site.zip", "site.tar", "site.tar.gz", "site.tgz"} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			input := filepath.Join(dir, name)
+			writeArchive(t, input, members)
+			s, err := NewSitemapSplitter(input, 2, WithOutputDir(dir))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Split(); err != nil {
+				t.Fatal(err)
+			}
+
+			for file, locs := range map[string][]string{"site-1.xml": {"/a1", "/a2"}, "site-2.xml": {"/b1"}} {
+				for _, loc := range locs {
+					if !fileContains(filepath.Join(dir, file), "<loc>https://example.com"+loc+"</loc>") {
+						t.Errorf("%s lacks %s", file, loc)
+					}
+				}
+			}
+			if s.LastResult().URLs != 3 {
+				t.Errorf("wrote %d URLs, want 3", s.LastResult().URLs)
+			}
+		})
+	}
+}
+
+func TestEmptyInput(t *testing.T) {
+	const urlset = `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"></urlset>`
+	tests := []struct {
+		name      string
+		in        string
+		policy    EmptyInputPolicy
+		streaming bool
+		wantErr   bool
+	}{
+		{"empty file fails", "", EmptyInputFail, false, true},
+		{"whitespace fails", " \n\t\n", EmptyInputFail, false, true},
+		{"empty urlset fails", urlset, EmptyInputFail, false, true},
+		{"empty file allowed", "", EmptyInputAllow, false, false},
+		{"whitespace allowed", " \n\t\n", EmptyInputAllow, false, false},
+		{"empty urlset allowed", "\n" + urlset + "\n", EmptyInputAllow, false, false},
+		{"streaming fails", " \n", EmptyInputFail, true, true},
+		{"streaming allowed", urlset, EmptyInputAllow, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := NewMemorySink()
+			opts := []Option{WithSink(sink), WithEmptyInput(tt.policy)}
+			if tt.streaming {
+				opts = append(opts, WithStreaming())
+			}
+			s, err := NewSitemapSplitter("in.xml", 10, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = s.SplitFrom(strings.NewReader(tt.in))
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "no URLs found") {
+					t.Errorf("split error %v, want no URLs found", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if names := sink.Names(); len(names) != 1 || names[0] != "sitemap-index.xml" {
+				t.Fatalf("split stored %v, want only the index", names)
+			}
+			index, _ := sink.File("sitemap-index.xml")
+			var parsed SitemapIndex
+			if err := xml.Unmarshal(index, &parsed); err != nil {
+				t.Fatalf("index is not valid XML: %v", err)
+			}
+			if len(parsed.Sitemaps) != 0 {
+				t.Errorf("index lists %d sitemaps, want none", len(parsed.Sitemaps))
+			}
+		})
+	}
+}
+
+// gzipped returns s gzip compressed
+func gzipped(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	io.WriteString(zw, s)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestGzipInput(t *testing.T) {
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc></url></urlset>`
+	tests := []struct {
+		name      string
+		file      string
+		content   []byte
+		streaming bool
+		wantErr   bool
+	}{
+		{"gz suffix", "in.xml.gz", gzipped(t, in), false, false},
+		{"detected without suffix", "in.xml", gzipped(t, in), false, false},
+		{"plain with gz suffix", "in.xml.gz", []byte(in), false, false},
+		{"streaming", "in.xml.gz", gzipped(t, in), true, false},
+		{"truncated", "in.xml.gz", gzipped(t, in)[:20], false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			input := filepath.Join(dir, tt.file)
+			if err := os.WriteFile(input, tt.content, 0644); err != nil {
+				t.Fatal(err)
+			}
+			opts := []Option{WithOutputDir(dir)}
+			if tt.streaming {
+				opts = append(opts, WithStreaming())
+			}
+			s, err := NewSitemapSplitter(input, 10, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = s.Split()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("split error %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !fileContains(filepath.Join(dir, "in-1.xml"), "<loc>https://example.com/a</loc>") {
+				t.Error("in-1.xml lacks the input's URL")
+			}
+		})
+	}
+
+	t.Run("reader", func(t *testing.T) {
+		out := splitString(t, string(gzipped(t, in)))
+		if !strings.Contains(out, "<loc>https://example.com/a</loc>") {
+			t.Errorf("output lacks the input's URL:\n%s", out)
+		}
+	})
+}
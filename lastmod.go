@@ -0,0 +1,67 @@
+package sitemapsplitter
+
+import "time"
+
+// LastModFormat controls how lastmod values are written to the output
+type LastModFormat int
+
+const (
+	// LastModPreserve keeps lastmod values exactly as they appear in the input
+	LastModPreserve LastModFormat = iota
+	// LastModDate writes lastmod values as a date only (2024-05-01)
+	LastModDate
+	// LastModDateTime writes lastmod values as full RFC3339 timestamps
+	LastModDateTime
+)
+
+// w3cLayouts lists the W3C Datetime variants accepted by the sitemap protocol
+var w3cLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// parseLastMod parses a W3C Datetime value, interpreting values without a
+// zone in loc
+func parseLastMod(value string, loc *time.Location) (time.Time, bool) {
+	for _, layout := range w3cLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// formatTime formats t according to the configured lastmod format
+func (s *SitemapSplitter) formatTime(t time.Time) string {
+	if s.lastModFormat == LastModDate {
+		return t.Format("2006-01-02")
+	}
+	return t.Format(time.RFC3339)
+}
+
+// normalizeLastMod rewrites the lastmod of u according to the configured
+// format, warning about values that change or cannot be parsed. Values that
+// cannot be parsed are left unchanged.
+func (s *SitemapSplitter) normalizeLastMod(u *URL) {
+	if u.LastMod == "" || s.lastModFormat == LastModPreserve {
+		return
+	}
+	t, ok := parseLastMod(u.LastMod, s.location)
+	if !ok {
+		s.warn(u.Loc, WarnLastModUnparsable)
+		return
+	}
+	if formatted := s.formatTime(t); formatted != u.LastMod {
+		s.warn(u.Loc, WarnLastModNormalized)
+		u.LastMod = formatted
+	}
+}
+
+// now returns the current time in the configured timezone
+func (s *SitemapSplitter) now() time.Time {
+	return s.clock().In(s.location)
+}
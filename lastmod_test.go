@@ -0,0 +1,34 @@
+package sitemapsplitter
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLastModFormat(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	tests := []struct {
+		name     string
+		format   LastModFormat
+		location *time.Location
+		in       string
+		want     string
+	}{
+		{"preserve", LastModPreserve, time.UTC, "2024-05-01T10:00:00+02:00", "2024-05-01T10:00:00+02:00"},
+		{"date", LastModDate, time.UTC, "2024-05-01T10:00:00+02:00", "2024-05-01"},
+		{"date from month", LastModDate, time.UTC, "2024-05", "2024-05-01"},
+		{"date time keeps zone", LastModDateTime, tokyo, "2024-05-01T10:00:00Z", "2024-05-01T10:00:00Z"},
+		{"date only in timezone", LastModDateTime, tokyo, "2024-05-01", "2024-05-01T00:00:00+09:00"},
+		{"unparsable", LastModDateTime, time.UTC, "yesterday", "yesterday"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc><lastmod>` + tt.in + `</lastmod></url></urlset>`
+			out := splitString(t, in, WithLastModFormat(tt.format), WithTimezone(tt.location))
+			if want := "<lastmod>" + tt.want + "</lastmod>"; !strings.Contains(out, want) {
+				t.Errorf("output lacks %s:\n%s", want, out)
+			}
+		})
+	}
+}
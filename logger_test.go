@@ -0,0 +1,64 @@
+package sitemapsplitter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoggerPhases(t *testing.T) {
+	const in = `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc></url><url><loc>https://example.com/b</loc></url></urlset>`
+	phases := []string{"fetch", "parse", "filter", "plan", "write", "upload"}
+	tests := []struct {
+		name     string
+		sink     Sink
+		wantWarn string // Message of a warning expected in the log
+	}{
+		{"success", NewMemorySink(), ""},
+		{"failed write", failingSink{}, "sink write failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			s, err := NewSitemapSplitter("in.xml", 1, WithSink(tt.sink), WithLogger(logger))
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.SplitFrom(strings.NewReader(in))
+
+			seen := make(map[string]bool)
+			warned := false
+			scanner := bufio.NewScanner(&buf)
+			for scanner.Scan() {
+				var record struct {
+					Level string `json:"level"`
+					Msg   string `json:"msg"`
+					Phase string `json:"phase"`
+				}
+				if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+					t.Fatal(err)
+				}
+				if !slices.Contains(phases, record.Phase) {
+					t.Errorf("record %q has phase %q", record.Msg, record.Phase)
+				}
+				seen[record.Level] = true
+				warned = warned || record.Level == "WARN" && record.Msg == tt.wantWarn
+			}
+			if !seen["INFO"] {
+				t.Errorf("log lacks info records: %v", seen)
+			}
+			if tt.wantWarn != "" && !warned {
+				t.Errorf("log lacks the warning %q", tt.wantWarn)
+			}
+		})
+	}
+
+	if _, err := NewSitemapSplitter("in.xml", 1, WithLogger(nil)); err == nil {
+		t.Error("nil logger accepted")
+	}
+}
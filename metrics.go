@@ -0,0 +1,36 @@
+package sitemapsplitter
+
+// observe adds the outcome of a finished Split to the expvar map configured
+// with WithExpvarMap. Counts of a failed run are not added, as its Result
+// may be incomplete or left over from an earlier run.
+func (s *SitemapSplitter) observe(err error) {
+	m := s.expvarMap
+	if m == nil {
+		return
+	}
+
+	m.Add("runs", 1)
+	if err != nil {
+		m.Add("errors", 1)
+		return
+	}
+	r := s.result
+	if r.UpToDate {
+		m.Add("runs_up_to_date", 1)
+		return
+	}
+
+	m.Add("urls_processed", int64(r.URLs+r.Dropped()))
+	m.Add("urls_written", int64(r.URLs))
+	m.Add("urls_skipped", int64(r.Dropped()))
+	for reason, n := range r.Skipped {
+		m.Add("urls_skipped_"+string(reason), int64(n))
+	}
+	written := 0
+	for _, f := range r.Files {
+		if !f.Unchanged {
+			written++
+		}
+	}
+	m.Add("chunks_written", int64(written/len(s.chunkPaths(""))))
+}
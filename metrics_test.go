@@ -0,0 +1,52 @@
+package sitemapsplitter
+
+import (
+	"expvar"
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestExpvarMap(t *testing.T) {
+	const in = `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc></url>` +
+		`<url><loc>https://example.com/a</loc></url><url><loc>https://example.com/b</loc></url><url><loc>https://example.com/c</loc></url></urlset>`
+	tests := []struct {
+		name string
+		in   string
+		opts []Option
+		runs int // Splits sharing the map
+		want map[string]int64
+	}{
+		{"success", in, nil, 1, map[string]int64{
+			"runs": 1, "urls_processed": 4, "urls_written": 3, "urls_skipped": 1, "urls_skipped_duplicate": 1, "chunks_written": 2,
+		}},
+		{"dual output", in, []Option{WithDualOutput(true)}, 1, map[string]int64{
+			"runs": 1, "urls_processed": 4, "urls_written": 3, "urls_skipped": 1, "urls_skipped_duplicate": 1, "chunks_written": 2,
+		}},
+		{"shared map", in, nil, 2, map[string]int64{
+			"runs": 2, "urls_processed": 8, "urls_written": 6, "urls_skipped": 2, "urls_skipped_duplicate": 2, "chunks_written": 4,
+		}},
+		{"failure", `<urlset`, nil, 1, map[string]int64{"runs": 1, "errors": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := new(expvar.Map).Init()
+			for range tt.runs {
+				opts := append([]Option{WithSink(NewMemorySink()), WithDeduplicate(true), WithExpvarMap(m)}, tt.opts...)
+				s, err := NewSitemapSplitter("in.xml", 2, opts...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				s.SplitFrom(strings.NewReader(tt.in))
+			}
+
+			got := make(map[string]int64)
+			m.Do(func(kv expvar.KeyValue) {
+				got[kv.Key] = kv.Value.(*expvar.Int).Value()
+			})
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("counters %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package sitemapsplitter
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// namePlaceholder matches a placeholder of a file name template, with an
+// optional zero-padded width for numbers
+var namePlaceholder = regexp.MustCompile(`\{([a-z]+)(?::0([1-9])d)?\}`)
+
+// checkNameTemplate reports placeholders of tmpl that are unknown or malformed,
+// and templates that would give every chunk the same name
+func checkNameTemplate(tmpl string) error {
+	hasIndex := false
+	for _, m := range namePlaceholder.FindAllStringSubmatch(tmpl, -1) {
+		switch m[1] {
+		case "index":
+			hasIndex = true
+		case "base", "section", "date", "ext":
+			if m[2] != "" {
+				return fmt.Errorf("placeholder {%s} in file name template does not take a width", m[1])
+			}
+		default:
+			return fmt.Errorf("unknown placeholder {%s} in file name template", m[1])
+		}
+	}
+	if strings.ContainsAny(namePlaceholder.ReplaceAllString(tmpl, ""), "{}") {
+		return fmt.Errorf("malformed placeholder in file name template %q", tmpl)
+	}
+	if !hasIndex {
+		return fmt.Errorf("file name template must contain {index}")
+	}
+	return nil
+}
+
+// expandNameTemplate fills in the file name template for the i-th chunk,
+// whose first URL determines the section
+func (s *SitemapSplitter) expandNameTemplate(baseFilename string, i int, urls []URL) string {
+	return namePlaceholder.ReplaceAllStringFunc(s.nameTemplate, func(p string) string {
+		m := namePlaceholder.FindStringSubmatch(p)
+		switch m[1] {
+		case "base":
+			return baseFilename
+		case "date":
+			return s.runDate
+		case "ext":
+			return s.chunkExt()
+		case "section":
+			if len(urls) == 0 {
+				return "root"
+			}
+			return sectionName(urls[0].Loc)
+		}
+		width, _ := strconv.Atoi(m[2])
+		return fmt.Sprintf("%0*d", width, i+1)
+	})
+}
+
+// sectionName returns the first path segment of loc for use in a file name,
+// or "root" for URLs directly below the host
+func sectionName(loc string) string {
+	parsed, err := url.Parse(loc)
+	if err != nil {
+		return "root"
+	}
+	segment, _, _ := strings.Cut(strings.TrimPrefix(parsed.EscapedPath(), "/"), "/")
+	if segment == "" || segment == "." || segment == ".." {
+		return "root"
+	}
+	return segment
+}
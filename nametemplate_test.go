@@ -0,0 +1,105 @@
+package sitemapsplitter
+
+import (
+	"encoding/xml"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileNameTemplate(t *testing.T) {
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` +
+		`<url><loc>https://example.com/blog/a</loc></url>` +
+		`<url><loc>https://example.com/shop/b</loc></url>` +
+		`<url><loc>https://example.com/</loc></url>` +
+		`</urlset>`
+	tests := []struct {
+		tmpl string
+		opts []Option
+		want []string // Chunk names, sorted
+	}{
+		{"{base}-{index}{ext}", nil, []string{"in-1.xml", "in-2.xml", "in-3.xml"}},
+		{"part{index:03d}.xml", nil, []string{"part001.xml", "part002.xml", "part003.xml"}},
+		{"{section}-{index}{ext}", nil, []string{"blog-1.xml", "root-3.xml", "shop-2.xml"}},
+		{"{base}/{index}{ext}", []Option{WithOutputFormat(FormatText)}, []string{"in/1.txt", "in/2.txt", "in/3.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tmpl, func(t *testing.T) {
+			sink := NewMemorySink()
+			s, err := NewSitemapSplitter("in.xml", 1, append([]Option{WithSink(sink), WithFileNameTemplate(tt.tmpl), WithoutIndex()}, tt.opts...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+				t.Fatal(err)
+			}
+			if got := sink.Names(); !slices.Equal(got, tt.want) {
+				t.Errorf("wrote %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDateNaming(t *testing.T) {
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` +
+		`<url><loc>https://example.com/a</loc></url>` +
+		`<url><loc>https://example.com/b</loc></url>` +
+		`</urlset>`
+	sink := NewMemorySink()
+	s, err := NewSitemapSplitter("in.xml", 1, WithSink(sink), WithDateNaming(),
+		WithIndexBaseURL("https://example.com/sitemaps/"), WithTimezone(time.FixedZone("UTC+7", 7*60*60)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Already the next day in the configured timezone
+	s.clock = func() time.Time { return time.Date(2024, 6, 1, 20, 0, 0, 0, time.UTC) }
+	if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"in-2024-06-02-1.xml", "in-2024-06-02-2.xml", "sitemap-index.xml"}
+	if got := sink.Names(); !slices.Equal(got, want) {
+		t.Errorf("wrote %v, want %v", got, want)
+	}
+	data, _ := sink.File("sitemap-index.xml")
+	var index SitemapIndex
+	if err := xml.Unmarshal(data, &index); err != nil {
+		t.Fatal(err)
+	}
+	var locs []string
+	for _, entry := range index.Sitemaps {
+		locs = append(locs, entry.Loc)
+	}
+	wantLocs := []string{"https://example.com/sitemaps/in-2024-06-02-1.xml", "https://example.com/sitemaps/in-2024-06-02-2.xml"}
+	if !slices.Equal(locs, wantLocs) {
+		t.Errorf("index locs %v, want %v", locs, wantLocs)
+	}
+}
+
+func TestCheckNameTemplate(t *testing.T) {
+	tests := []struct {
+		tmpl    string
+		wantErr string // Part of the error, empty if the template is valid
+	}{
+		{"{base}-{date}-{index:04d}{ext}", ""},
+		{"{base}.xml", "must contain {index}"},
+		{"{name}-{index}.xml", "unknown placeholder {name}"},
+		{"{base:02d}-{index}.xml", "does not take a width"},
+		{"{index}-{base.xml", "malformed placeholder"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tmpl, func(t *testing.T) {
+			_, err := NewSitemapSplitter("in.xml", 1, WithFileNameTemplate(tt.tmpl))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("valid template rejected: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got error %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package sitemapsplitter
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+// NewsNamespace is the namespace of Google's news sitemap extension
+const NewsNamespace = "http://www.google.com/schemas/sitemap-news/0.9"
+
+// News is a <news:news> entry describing a news article
+type News struct {
+	Publication     NewsPublication `xml:"publication" json:"publication"`
+	PublicationDate string          `xml:"publication_date" json:"publication_date"`
+	Title           string          `xml:"title" json:"title"`
+	Keywords        string          `xml:"keywords,omitempty" json:"keywords,omitempty"`
+	StockTickers    string          `xml:"stock_tickers,omitempty" json:"stock_tickers,omitempty"`
+}
+
+// NewsPublication identifies the publication of a news article
+type NewsPublication struct {
+	Name     string `xml:"name" json:"name"`
+	Language string `xml:"language" json:"language"`
+}
+
+func init() {
+	RegisterExtension(newsExtension{})
+}
+
+// AddNews attaches a news entry to the URL
+func (u *URL) AddNews(n News) {
+	u.Extensions = append(u.Extensions, Extension{Namespace: NewsNamespace, Value: n})
+}
+
+// News returns the news entries of the URL
+func (u URL) News() []News {
+	var news []News
+	for _, ext := range u.Extensions {
+		if n, ok := ext.Value.(News); ok {
+			news = append(news, n)
+		}
+	}
+	return news
+}
+
+// newsExtension decodes <news:news> elements into News values. Other elements
+// of the namespace are preserved verbatim.
+type newsExtension struct{}
+
+func (newsExtension) Namespace() string { return NewsNamespace }
+
+func (newsExtension) Prefix() string { return "news" }
+
+func (newsExtension) Decode(d *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	if start.Name.Local != "news" {
+		return RawExtension(NewsNamespace, "news").Decode(d, start)
+	}
+
+	var n News
+	if err := d.DecodeElement(&n, &start); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (newsExtension) Encode(e *xml.Encoder, v interface{}) error {
+	n, ok := v.(News)
+	if !ok {
+		return fmt.Errorf("unexpected %T value for namespace %q", v, NewsNamespace)
+	}
+
+	start := xml.StartElement{Name: xml.Name{Local: "news:news"}}
+	publication := xml.StartElement{Name: xml.Name{Local: "news:publication"}}
+	for _, tok := range []xml.Token{start, publication} {
+		if err := e.EncodeToken(tok); err != nil {
+			return err
+		}
+	}
+	if err := encodeFields(e, "news:", []field{
+		{"name", n.Publication.Name},
+		{"language", n.Publication.Language},
+	}); err != nil {
+		return err
+	}
+	if err := e.EncodeToken(publication.End()); err != nil {
+		return err
+	}
+	if err := encodeFields(e, "news:", []field{
+		{"publication_date", n.PublicationDate},
+		{"title", n.Title},
+		{"keywords", n.Keywords},
+		{"stock_tickers", n.StockTickers},
+	}); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+const (
+	// newsFreshness is how long after publication news belongs in the news
+	// sitemap
+	newsFreshness = 48 * time.Hour
+	// newsLimit is the most URLs Google News accepts in a news sitemap
+	newsLimit = 1000
+)
+
+// newsURL is a URL of the news sitemap with the publication time of its
+// newest news entry
+type newsURL struct {
+	url       URL
+	published time.Time
+}
+
+// newestNews returns the publication time of the newest news entry of u, or
+// the zero time if it has none with a valid date
+func (s *SitemapSplitter) newestNews(u URL) time.Time {
+	var newest time.Time
+	for _, n := range u.News() {
+		if t, ok := parseLastMod(n.PublicationDate, s.location); ok && t.After(newest) {
+			newest = t
+		}
+	}
+	return newest
+}
+
+// checkNewsAge warns about news entries of u published longer ago than the
+// configured maximum age, which Google News no longer picks up
+func (s *SitemapSplitter) checkNewsAge(u URL) {
+	if s.newsMaxAge <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-s.newsMaxAge)
+	for _, n := range u.News() {
+		if t, ok := parseLastMod(n.PublicationDate, s.location); ok && t.Before(cutoff) {
+			s.warn(u.Loc, WarnNewsStale)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package sitemapsplitter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewsSitemapKeepsFreshNews(t *testing.T) {
+	now := time.Now().UTC()
+	entry := func(loc string, published time.Time) string {
+		return "<url><loc>" + loc + "</loc><news:news><news:publication><news:name>Example</news:name>" +
+			"<news:language>en</news:language></news:publication><news:publication_date>" +
+			published.Format(time.RFC3339) + "</news:publication_date><news:title>T</news:title></news:news></url>"
+	}
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:news="` + NewsNamespace + `">` +
+		entry("https://example.com/fresh", now.Add(-time.Hour)) +
+		entry("https://example.com/stale", now.Add(-72*time.Hour)) +
+		"<url><loc>https://example.com/page</loc></url></urlset>"
+
+	for _, streaming := range []bool{false, true} {
+		dir := t.TempDir()
+		opts := []Option{WithOutputDir(dir), WithNewsSitemap("")}
+		if streaming {
+			opts = append(opts, WithStreaming())
+		}
+		s, err := NewSitemapSplitter(filepath.Join(dir, "in.xml"), 50000, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+			t.Fatal(err)
+		}
+
+		news, err := os.ReadFile(filepath.Join(dir, "news.xml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(news), "/fresh<") || strings.Contains(string(news), "/stale<") || strings.Contains(string(news), "/page<") {
+			t.Errorf("streaming=%v: news sitemap holds the wrong URLs:\n%s", streaming, news)
+		}
+		for _, loc := range []string{"/fresh<", "/stale<", "/page<"} {
+			if !fileContains(filepath.Join(dir, "in-1.xml"), loc) {
+				t.Errorf("streaming=%v: chunk is missing %s", streaming, loc)
+			}
+		}
+	}
+}
+
+func TestNewsSitemapLimit(t *testing.T) {
+	now := time.Now().UTC()
+	var b strings.Builder
+	b.WriteString(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:news="` + NewsNamespace + `">`)
+	// The five oldest articles come first and last, so both ends are cut
+	ages := []int{1001, 1002, 1003}
+	for i := range newsLimit {
+		ages = append(ages, i)
+	}
+	ages = append(ages, 1004, 1005)
+	for _, age := range ages {
+		fmt.Fprintf(&b, "<url><loc>https://example.com/article-%d</loc><news:news><news:publication><news:name>Example</news:name>"+
+			"<news:language>en</news:language></news:publication><news:publication_date>%s</news:publication_date>"+
+			"<news:title>T</news:title></news:news></url>", age, now.Add(-time.Duration(age)*time.Minute).Format(time.RFC3339))
+	}
+	b.WriteString("</urlset>")
+
+	for _, streaming := range []bool{false, true} {
+		dir := t.TempDir()
+		opts := []Option{WithOutputDir(dir), WithNewsSitemap(""), WithHistory(filepath.Join(dir, "history"), 1)}
+		if streaming {
+			opts = append(opts, WithStreaming())
+		}
+		s, err := NewSitemapSplitter(filepath.Join(dir, "in.xml"), 50000, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SplitFrom(strings.NewReader(b.String())); err != nil {
+			t.Fatal(err)
+		}
+
+		news, err := os.ReadFile(filepath.Join(dir, "news.xml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := strings.Count(string(news), "<url>"); n != newsLimit {
+			t.Errorf("streaming=%v: news sitemap holds %d URLs, want %d", streaming, n, newsLimit)
+		}
+		for age := 1001; age <= 1005; age++ {
+			if strings.Contains(string(news), fmt.Sprintf("/article-%d<", age)) {
+				t.Errorf("streaming=%v: news sitemap kept the older article-%d", streaming, age)
+			}
+		}
+		if !strings.Contains(string(news), "/article-0<") || !strings.Contains(string(news), fmt.Sprintf("/article-%d<", newsLimit-1)) {
+			t.Errorf("streaming=%v: news sitemap lost newer articles", streaming)
+		}
+		if res := s.LastResult(); res.News == nil || res.News.URLs != newsLimit {
+			t.Errorf("streaming=%v: result reports news sitemap %+v", streaming, res.News)
+		}
+		if !fileContains(filepath.Join(dir, "in-1.xml"), "/article-1005<") {
+			t.Errorf("streaming=%v: chunk lost an article left out of the news sitemap", streaming)
+		}
+		entries, _ := filepath.Glob(filepath.Join(dir, "history", "*.json"))
+		if len(entries) != 1 || !fileContains(entries[0], `"name": "news.xml",
+      "urls": 1000`) {
+			t.Errorf("streaming=%v: history manifest lacks the news sitemap", streaming)
+		}
+	}
+}
+
+func TestNewsSitemapCollision(t *testing.T) {
+	tests := []struct {
+		name string
+		opts func(dir string) []Option
+	}{
+		{"chunk", func(string) []Option { return []Option{WithNewsSitemap("in-1.xml")} }},
+		{"index", func(string) []Option { return []Option{WithNewsSitemap("sitemap-index.xml")} }},
+		{"delta", func(dir string) []Option {
+			return []Option{WithNewsSitemap("changed.xml"), WithDeltaSitemap(""), WithStateFile(filepath.Join(dir, "state.json"))}
+		}},
+	}
+	for _, tt := range tests {
+		for _, streaming := range []bool{false, true} {
+			dir := t.TempDir()
+			opts := append([]Option{WithOutputDir(dir)}, tt.opts(dir)...)
+			if streaming {
+				opts = append(opts, WithStreaming())
+			}
+			s, err := NewSitemapSplitter(filepath.Join(dir, "in.xml"), 1, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = s.SplitFrom(strings.NewReader(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc></url></urlset>`))
+			if err == nil || !strings.Contains(err.Error(), "collision") {
+				t.Errorf("%s, streaming=%v: got error %v, want a collision", tt.name, streaming, err)
+			}
+		}
+	}
+}
+
+func TestNewsExtension(t *testing.T) {
+	now := time.Now().UTC()
+	entry := func(published time.Time) string {
+		return "<news:news><news:publication><news:name>Example &amp; Co</news:name><news:language>en</news:language></news:publication>" +
+			"<news:publication_date>" + published.Format(time.RFC3339) + "</news:publication_date><news:title>T</news:title>" +
+			"<news:keywords>a, b</news:keywords></news:news>"
+	}
+	tests := []struct {
+		name      string
+		published time.Time
+		opts      []Option
+		wantStale int // W_NEWS_STALE warnings
+	}{
+		{"fresh", now.Add(-time.Hour), []Option{WithProfile(ProfileNews)}, 0},
+		{"stale", now.Add(-72 * time.Hour), []Option{WithProfile(ProfileNews)}, 1},
+		{"custom max age", now.Add(-2 * time.Hour), []Option{WithNewsMaxAge(time.Hour)}, 1},
+		{"no max age", now.Add(-72 * time.Hour), nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:news="` + NewsNamespace + `"><url><loc>https://example.com/a</loc>` +
+				entry(tt.published) + `</url></urlset>`
+			sink := NewMemorySink()
+			s, err := NewSitemapSplitter("in.xml", 50000, append([]Option{WithSink(sink)}, tt.opts...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+				t.Fatal(err)
+			}
+
+			out, _ := sink.File("in-1.xml")
+			for _, want := range []string{
+				`xmlns:news="` + NewsNamespace + `"`,
+				`<news:name>Example &amp; Co</news:name>`,
+				`<news:publication_date>` + tt.published.Format(time.RFC3339) + `</news:publication_date>`,
+				`<news:keywords>a, b</news:keywords>`,
+			} {
+				if !strings.Contains(string(out), want) {
+					t.Errorf("output lacks %s:\n%s", want, out)
+				}
+			}
+			if got := s.LastResult().WarningCounts[WarnNewsStale]; got != tt.wantStale {
+				t.Errorf("%d stale news warnings, want %d", got, tt.wantStale)
+			}
+		})
+	}
+}
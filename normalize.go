@@ -0,0 +1,65 @@
+package sitemapsplitter
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// normalizeLoc cleans up loc: illegal characters are percent-encoded, scheme
+// and host are lowercased, the default port is removed and runs of slashes in
+// the path are collapsed. It reports whether loc changed. Locs that cannot be
+// parsed even after encoding are left as they are.
+func normalizeLoc(u *URL) bool {
+	parsed, err := url.Parse(escapeIllegal(u.Loc))
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+
+	parsed.Host = strings.ToLower(parsed.Host)
+	if port := parsed.Port(); port == "80" && parsed.Scheme == "http" || port == "443" && parsed.Scheme == "https" {
+		host := parsed.Hostname()
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		parsed.Host = host
+	}
+
+	escaped := parsed.EscapedPath()
+	for strings.Contains(escaped, "//") {
+		escaped = strings.ReplaceAll(escaped, "//", "/")
+	}
+	if path, err := url.PathUnescape(escaped); err == nil {
+		parsed.Path, parsed.RawPath = path, escaped
+	}
+
+	normalized := parsed.String()
+	if normalized == u.Loc {
+		return false
+	}
+	u.Loc = normalized
+	return true
+}
+
+// escapeIllegal percent-encodes every byte of loc that may not appear in a
+// URL, including a % that does not start an escape sequence
+func escapeIllegal(loc string) string {
+	var b strings.Builder
+	for i := 0; i < len(loc); i++ {
+		c := loc[i]
+		switch {
+		case c == '%' && i+2 < len(loc) && isHex(loc[i+1]) && isHex(loc[i+2]):
+			b.WriteByte(c)
+		case c != '%' && c > ' ' && c < 0x7f && !strings.ContainsRune(`"<>\^`+"`{|}", rune(c)):
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// isHex reports whether c is a hexadecimal digit
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
@@ -0,0 +1,53 @@
+package sitemapsplitter
+
+import "testing"
+
+func TestEscapeIllegal(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"https://example.com/a", "https://example.com/a"},
+		{"https://example.com/a b", "https://example.com/a%20b"},
+		{"https://example.com/café", "https://example.com/caf%C3%A9"},
+		{"https://example.com/100%", "https://example.com/100%25"},
+		{"https://example.com/%zz", "https://example.com/%25zz"},
+		{"https://example.com/%2F", "https://example.com/%2F"},
+		{"https://example.com/%2", "https://example.com/%252"},
+		{"https://example.com/a{b}|c", "https://example.com/a%7Bb%7D%7Cc"},
+		{"https://example.com/?q=<x>", "https://example.com/?q=%3Cx%3E"},
+		{"https://example.com/a\tb", "https://example.com/a%09b"},
+	}
+	for _, tt := range tests {
+		if got := escapeIllegal(tt.in); got != tt.want {
+			t.Errorf("escapeIllegal(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeLoc(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"HTTPS://Example.COM/Path", "https://example.com/Path"},
+		{"http://example.com:80/a", "http://example.com/a"},
+		{"https://example.com:443/a", "https://example.com/a"},
+		{"http://example.com:443/a", "http://example.com:443/a"},
+		{"https://example.com:8443/a", "https://example.com:8443/a"},
+		{"https://[::1]:443/a", "https://[::1]/a"},
+		{"https://example.com//a///b", "https://example.com/a/b"},
+		{"https://example.com/a%2Fb//c", "https://example.com/a%2Fb/c"},
+		{"https://example.com/a b?q=1 2", "https://example.com/a%20b?q=1%202"},
+		{"https://example.com/a?x=//y", "https://example.com/a?x=//y"},
+		{"/relative/path", "/relative/path"},
+	}
+	for _, tt := range tests {
+		u := URL{Loc: tt.in}
+		changed := normalizeLoc(&u)
+		if u.Loc != tt.want {
+			t.Errorf("normalizeLoc(%q) = %q, want %q", tt.in, u.Loc, tt.want)
+		}
+		if changed != (tt.in != tt.want) {
+			t.Errorf("normalizeLoc(%q) reported changed = %v", tt.in, changed)
+		}
+	}
+}
@@ -0,0 +1,798 @@
+package sitemapsplitter
+
+import (
+	"expvar"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Option configures optional behavior of a SitemapSplitter
+type Option func(*SitemapSplitter)
+
+// WithLastModFormat sets the format used for lastmod values written to the
+// split sitemaps and the sitemap index
+func WithLastModFormat(format LastModFormat) Option {
+	return func(s *SitemapSplitter) {
+		s.lastModFormat = format
+	}
+}
+
+// WithTimezone sets the timezone used when generating new timestamps and when
+// interpreting date-only lastmod values
+func WithTimezone(loc *time.Location) Option {
+	return func(s *SitemapSplitter) {
+		s.location = loc
+	}
+}
+
+// WithStateFile sets the path of a state file used to remember what the
+// previous run produced
+func WithStateFile(path string) Option {
+	return func(s *SitemapSplitter) {
+		s.stateFile = path
+	}
+}
+
+// WithDeltaSitemap additionally writes a sitemap with the given filename
+// containing only URLs added or modified since the previous run. An empty
+// name defaults to changed.xml. Requires WithStateFile.
+func WithDeltaSitemap(name string) Option {
+	return func(s *SitemapSplitter) {
+		if name == "" {
+			name = "changed.xml"
+		}
+		s.deltaName = name
+	}
+}
+
+// WithNewsSitemap additionally writes a sitemap with the given filename
+// containing only URLs with a news entry published in the last 48 hours, the
+// window Google News picks articles from. The chunks keep every URL,
+// including those whose news is older. Beyond the 1,000 URLs Google News
+// accepts, the URLs with the newest news are kept. The news sitemap is
+// reported in Result.News and the run history but, as its URLs are already
+// in the chunks, not listed in the index. An empty name defaults to news.xml.
+func WithNewsSitemap(name string) Option {
+	return func(s *SitemapSplitter) {
+		if name == "" {
+			name = "news.xml"
+		}
+		s.newsName = name
+	}
+}
+
+// WithSharding places chunk files into numbered subdirectories (00/, 01/, ...)
+// holding at most filesPerDir files each. Index entries reflect the layout.
+func WithSharding(filesPerDir int) Option {
+	return func(s *SitemapSplitter) {
+		s.filesPerShard = filesPerDir
+	}
+}
+
+// WithMaxFiles fails the split before anything is written if it would produce
+// more than n files, counting chunk files, the index and the delta sitemap
+func WithMaxFiles(n int) Option {
+	return func(s *SitemapSplitter) {
+		s.maxFiles = n
+	}
+}
+
+// WithSampleEvery keeps only every nth URL of the input, starting with the
+// first. Useful for publishing a small but realistic sitemap on staging.
+func WithSampleEvery(n int) Option {
+	return func(s *SitemapSplitter) {
+		s.sampleEvery = n
+	}
+}
+
+// WithSamplePercent keeps roughly percent of the input URLs. Selection is
+// derived from a hash of the seed and each loc, so the same seed always yields
+// the same sample.
+func WithSamplePercent(percent float64, seed int64) Option {
+	return func(s *SitemapSplitter) {
+		s.samplePercent = percent
+		s.sampleSeed = seed
+	}
+}
+
+// WithRedaction strips or masks the given query parameters from every loc
+// before output. The number of affected URLs is reported in the Result.
+func WithRedaction(rules ...RedactRule) Option {
+	return func(s *SitemapSplitter) {
+		s.redactRules = append(s.redactRules, rules...)
+	}
+}
+
+// WithRobotsTxt drops URLs disallowed by their site's robots.txt for the given
+// user-agent. Each host's robots.txt is fetched once per run.
+func WithRobotsTxt(userAgent string) Option {
+	return func(s *SitemapSplitter) {
+		s.robotsAgent = userAgent
+	}
+}
+
+// WithHTTPClient sets the client used for outbound HTTP requests
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *SitemapSplitter) {
+		s.httpClient = client
+	}
+}
+
+// WithUserAgent sets the User-Agent header of every outbound HTTP request,
+// including robots.txt fetches, which otherwise identify themselves with the
+// robots.txt user-agent
+func WithUserAgent(userAgent string) Option {
+	return func(s *SitemapSplitter) {
+		s.userAgent = userAgent
+	}
+}
+
+// WithRequestDelay waits at least delay between the starts of two HTTP
+// requests to the same host, such as the HEAD requests of WriteIndex with
+// IndexLastModHeader, even when child sitemaps are read concurrently
+func WithRequestDelay(delay time.Duration) Option {
+	return func(s *SitemapSplitter) {
+		s.requestDelay = delay
+	}
+}
+
+// WithRateLimit caps outbound HTTP requests at perSecond per second across
+// all hosts and all features making requests, such as remote input, robots.txt,
+// index lastmod lookups and alert webhooks. It applies on top of
+// WithRequestDelay, and 0 removes the limit.
+func WithRateLimit(perSecond float64) Option {
+	return func(s *SitemapSplitter) {
+		s.rateLimit = perSecond
+	}
+}
+
+// WithHostConcurrency allows at most n HTTP requests to the same host to be
+// in flight at once, counting each from its start until its body is closed.
+// It matters when child sitemaps are read concurrently with
+// WithChildConcurrency; 0 removes the limit.
+func WithHostConcurrency(n int) Option {
+	return func(s *SitemapSplitter) {
+		s.hostFetches = n
+	}
+}
+
+// WithChildConcurrency reads up to n child sitemaps of a sitemap index input
+// at once. Their URLs are still passed on in index order, so the output is
+// the same as when reading the children one after another, the default.
+func WithChildConcurrency(n int) Option {
+	return func(s *SitemapSplitter) {
+		s.childFetches = n
+	}
+}
+
+// WithCanonicalHost rewrites locs whose host is the www or apex variant of
+// host to host itself, e.g. "www.example.com" forces www and "example.com"
+// forces the apex. Other hosts are left untouched.
+func WithCanonicalHost(host string) Option {
+	return func(s *SitemapSplitter) {
+		s.canonicalHost = strings.ToLower(host)
+	}
+}
+
+// WithJSONLExport additionally writes every output URL to w as JSON Lines,
+// one object per URL with loc, lastmod, changefreq, priority and extensions
+func WithJSONLExport(w io.Writer) Option {
+	return func(s *SitemapSplitter) {
+		s.jsonlExport = w
+	}
+}
+
+// WithDedupStore drops URLs whose loc the store has already seen, in this run
+// or a previous one. Use NewMemoryDedupStore for in-process deduplication or
+// plug in an external store to deduplicate across runs. With WithDedupPolicy
+// the store is consulted after the policy has collapsed the repeated locs of
+// the input, so it sees only the entry the policy kept.
+func WithDedupStore(store DedupStore) Option {
+	return func(s *SitemapSplitter) {
+		s.dedupStore = store
+	}
+}
+
+// WithPhaseTimeouts bounds the fetch, parse, filter, plan, write and upload
+// phases of a split separately, so that a slow phase fails the run before it
+// starves the later ones. A phase that runs out of time fails the split with
+// an error naming the phase.
+func WithPhaseTimeouts(timeouts PhaseTimeouts) Option {
+	return func(s *SitemapSplitter) {
+		s.phaseTimeouts = timeouts
+	}
+}
+
+// WithDecodeLimits caps element depth, attribute count and token size while
+// decoding input, failing with a descriptive error when a cap is exceeded
+func WithDecodeLimits(limits DecodeLimits) Option {
+	return func(s *SitemapSplitter) {
+		s.decodeLimits = limits
+	}
+}
+
+// WithSkipUnchanged skips all work when the input file and options are
+// identical to the last successful run recorded in the state file. The Result
+// then reports UpToDate. For a sitemap index only the index itself is
+// compared, not its children. With WithRobotsTxt the robots.txt files obeyed
+// by the last run are fetched again and compared as well. Functions passed to
+// options, such as backfill lookups, cannot be compared. Requires
+// WithStateFile.
+func WithSkipUnchanged() Option {
+	return func(s *SitemapSplitter) {
+		s.skipUnchanged = true
+	}
+}
+
+// WithCountAlert raises alert when the number of URLs about to be published
+// differs from the previous run's by more than alert.MaxChange percent. The
+// run fails unless alert.Continue is set, and alert.Webhook, if any, receives
+// a CountAlertEvent first. In streaming mode the check happens after the
+// chunks are written but before the index and state are. Requires
+// WithStateFile.
+func WithCountAlert(alert CountAlert) Option {
+	return func(s *SitemapSplitter) {
+		s.countAlert = &alert
+	}
+}
+
+// WithCachePurge sends a purge request for every file a run stored once the
+// output is live, so that a CDN does not keep serving stale sitemaps. The
+// request URL is purge.URL executed as a text/template with PurgeData, e.g.
+// "https://cdn.example/purge?url={{.BaseURL}}{{.Name}}". A failed purge fails
+// the run before its state is saved, so the next run purges again. Dry runs
+// purge nothing.
+func WithCachePurge(purge CachePurge) Option {
+	return func(s *SitemapSplitter) {
+		s.purge = &purge
+	}
+}
+
+// WithOutputDir writes the chunks, delta sitemap and index below dir instead
+// of next to the input, creating dir if it is missing. Ignored when WithSink
+// is used.
+func WithOutputDir(dir string) Option {
+	return func(s *SitemapSplitter) {
+		s.outputDir = dir
+	}
+}
+
+// WithDestination writes the output to an rclone-style destination string.
+// "scheme:path", e.g. "s3:bucket/prefix", is handed to the opener registered
+// for scheme with RegisterDestination, which fails NewSitemapSplitter when
+// none is. A path without a scheme, or one starting with "local:", is a local
+// directory as for WithOutputDir; write "./name:x" for a local directory
+// whose name contains a colon. WithSink takes precedence.
+func WithDestination(dest string) Option {
+	return func(s *SitemapSplitter) {
+		s.destination = dest
+	}
+}
+
+// WithFileNameTemplate names chunks after tmpl instead of <base>-N.xml. The
+// template includes the extension and may use the placeholders {base} for
+// the input's base name, {index} for the 1-based chunk number, {index:03d}
+// for the number zero-padded to the given width, {section} for the first
+// path segment of the chunk's first URL ("root" for URLs below the host),
+// {date} for the date the run started (2024-06-01, in the configured
+// timezone) and {ext} for the extension of the output format. {index} is
+// required so that names are unique. Sections only describe a whole chunk if
+// the input is ordered by section.
+func WithFileNameTemplate(tmpl string) Option {
+	return func(s *SitemapSplitter) {
+		s.nameTemplate = tmpl
+	}
+}
+
+// WithDateNaming embeds the date of the run in chunk names, e.g.
+// sitemap-2024-06-01-3.xml, so that archived generations describe
+// themselves. It is shorthand for WithFileNameTemplate("{base}-{date}-{index}{ext}").
+func WithDateNaming() Option {
+	return WithFileNameTemplate("{base}-{date}-{index}{ext}")
+}
+
+// WithFilePermissions sets the mode of files created by the default file
+// sink, 0644 by default. Ignored when WithSink is used.
+func WithFilePermissions(perm os.FileMode) Option {
+	return func(s *SitemapSplitter) {
+		s.filePerm = perm
+	}
+}
+
+// WithIndexName sets the filename of the sitemap index, sitemap-index.xml by
+// default
+func WithIndexName(name string) Option {
+	return func(s *SitemapSplitter) {
+		s.indexName = name
+	}
+}
+
+// WithIndexLocTemplate builds the loc of every index entry from the Go
+// text/template tmpl, executed with an IndexLocData, instead of joining the
+// base URL and the chunk name. For example
+// "https://cdn.example.com/seo/{{.Name}}?v={{slice .Hash 0 8}}" serves the
+// chunks from a CDN with a cache-busting query string. The expansion must be
+// an absolute URL.
+func WithIndexLocTemplate(tmpl string) Option {
+	return func(s *SitemapSplitter) {
+		s.indexLocTmpl = tmpl
+	}
+}
+
+// WithoutIndex skips writing the sitemap index, for callers that build their
+// own index from the chunks. The index entry limits are not checked then.
+func WithoutIndex() Option {
+	return func(s *SitemapSplitter) {
+		s.noIndex = true
+	}
+}
+
+// WithIndexBaseURL sets the URL prefix of every index entry, e.g.
+// "https://example.com/sitemaps/", instead of deriving scheme and host from
+// the last URL of each chunk, which is wrong for chunks spanning several
+// hosts or sitemaps hosted below a path. A missing trailing slash is added.
+func WithIndexBaseURL(baseURL string) Option {
+	return func(s *SitemapSplitter) {
+		if baseURL != "" && !strings.HasSuffix(baseURL, "/") {
+			baseURL += "/"
+		}
+		s.baseURL = baseURL
+	}
+}
+
+// WithLogger sends progress logs to logger. Every record carries a phase
+// attribute (fetch, parse, filter, plan, write or upload): info records
+// summarize each phase, debug records cover single URLs, chunks, stored files
+// and HTTP requests, and warnings report failed requests and sink writes and
+// sharp URL count changes. The splitter is silent by default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *SitemapSplitter) {
+		s.logger = logger
+	}
+}
+
+// WithSink sends the chunks, delta sitemap and index to sink instead of
+// writing them next to the input. The state file and run history stay on
+// the local filesystem.
+func WithSink(sink Sink) Option {
+	return func(s *SitemapSplitter) {
+		s.sink = sink
+	}
+}
+
+// WithUploadVerification checks every file after the sink stored it and fails
+// the run if its size or SHA-256 digest differs from what was written. Sinks
+// implementing StatSink are asked for the stored size and digest, as with a
+// HEAD request; otherwise the file is read back through ReadSink. Requires a
+// sink implementing one of them.
+func WithUploadVerification() Option {
+	return func(s *SitemapSplitter) {
+		s.verifyUploads = true
+	}
+}
+
+// WithMaxErrorRate drops entries that are not valid sitemap entries (a loc
+// that is not an absolute http(s) URL, or an unparsable lastmod, changefreq
+// or priority) and fails the run if they make up more than percent of the
+// input. Without this option entries are not validated. In streaming mode the
+// rate is checked once the whole input has been read.
+func WithMaxErrorRate(percent float64) Option {
+	return func(s *SitemapSplitter) {
+		s.validate = true
+		s.maxErrorRate = percent
+	}
+}
+
+// WithMaxBytes closes a chunk when adding the next URL would make it larger
+// than maxBytes uncompressed, in addition to the URL limit. The sitemaps.org
+// protocol allows at most 50MB (52,428,800 bytes) per file.
+func WithMaxBytes(maxBytes int64) Option {
+	return func(s *SitemapSplitter) {
+		s.maxBytes = maxBytes
+	}
+}
+
+// WithByteHeadroom keeps percent of the WithMaxBytes limit free in every
+// chunk, closing chunks at 90% of the limit for a headroom of 10, so that
+// content added after the split, such as a stylesheet processing instruction
+// or comments, cannot push a file over the limit. It has no effect without
+// WithMaxBytes.
+func WithByteHeadroom(percent float64) Option {
+	return func(s *SitemapSplitter) {
+		s.byteHeadroom = percent
+	}
+}
+
+// WithDifferentialWrites only hands chunks and the index to the sink when
+// their content differs from what the previous run wrote, as recorded by
+// content hash in the state file. This saves transfers to remote sinks for
+// mostly static sitemaps, but files deleted from the destination are not
+// restored until their content changes. Requires WithStateFile.
+func WithDifferentialWrites() Option {
+	return func(s *SitemapSplitter) {
+		s.diffWrites = true
+	}
+}
+
+// WithStreaming decodes the input one <url> element at a time and writes
+// each chunk as soon as it is full, so multi-gigabyte sitemaps can be split
+// with memory bounded by a single chunk. Quotas and the file cap are checked
+// chunk by chunk, so chunks written before a limit is hit stay on disk. The
+// delta sitemap declares every registered extension namespace, and the state
+// file still keeps one fingerprint per URL in memory. Target file counts and
+// sibling lastmod backfill need the whole input and are rejected.
+func WithStreaming() Option {
+	return func(s *SitemapSplitter) {
+		s.streaming = true
+	}
+}
+
+// WithDualOutput writes every chunk both as plain XML and as a gzipped .gz
+// copy. Index entries point at the gzipped variant when preferGzip is true and
+// at the plain file otherwise.
+func WithDualOutput(preferGzip bool) Option {
+	return func(s *SitemapSplitter) {
+		s.dualOutput = true
+		s.preferGzip = preferGzip
+	}
+}
+
+// WithGzipOutput writes chunks and the sitemap index gzip compressed as
+// .xml.gz files only, with index entries pointing at them. The delta sitemap
+// is compressed when its name ends in .gz. Cannot be combined with
+// WithDualOutput.
+func WithGzipOutput(enabled bool) Option {
+	return func(s *SitemapSplitter) {
+		s.gzipOutput = enabled
+	}
+}
+
+// WithoutXMLDeclaration omits the <?xml ...?> declaration from the selected
+// outputs, e.g. WithoutXMLDeclaration(OutputChunks|OutputIndex)
+func WithoutXMLDeclaration(kinds OutputKind) Option {
+	return func(s *SitemapSplitter) {
+		s.omitHeader |= kinds
+	}
+}
+
+// WithSelfClosingTags writes elements without content in self-closing form
+// (<mobile:mobile/>) instead of the default expanded form
+// (<mobile:mobile></mobile:mobile>)
+func WithSelfClosingTags() Option {
+	return func(s *SitemapSplitter) {
+		s.selfClosing = true
+	}
+}
+
+// WithStripFragments removes #fragment components from loc values before
+// deduplication, since crawlers ignore them
+func WithStripFragments() Option {
+	return func(s *SitemapSplitter) {
+		s.noFragments = true
+	}
+}
+
+// WithLowercasePaths lowercases URL paths before deduplication, for sites
+// served by case-insensitive servers where /About and /about are the same
+// page. Paths are preserved as-is by default.
+func WithLowercasePaths() Option {
+	return func(s *SitemapSplitter) {
+		s.lowerPaths = true
+	}
+}
+
+// WithURLNormalization cleans up every loc before the other rewrites and
+// deduplication: characters not allowed in URLs are percent-encoded, scheme
+// and host are lowercased, default ports (:80 for http, :443 for https) are
+// removed and duplicate slashes in the path are collapsed. The number of
+// locs changed is reported in the Result.
+func WithURLNormalization() Option {
+	return func(s *SitemapSplitter) {
+		s.normalizeURLs = true
+	}
+}
+
+// WithTargetFileCount derives the per-file limit from the number of URLs so
+// the output lands in roughly n chunk files. The limit passed to
+// NewSitemapSplitter still caps the URLs per file, so more files are written
+// if n files would exceed it.
+func WithTargetFileCount(n int) Option {
+	return func(s *SitemapSplitter) {
+		s.targetFiles = n
+	}
+}
+
+// WithLastModBackfill fills in missing lastmod values from the given rules,
+// tried in order until one yields a value. URLs no rule covers keep an empty
+// lastmod. Index entries carry the newest lastmod of their chunk, so a chunk
+// without any has none either.
+func WithLastModBackfill(rules ...BackfillRule) Option {
+	return func(s *SitemapSplitter) {
+		s.backfillRules = append(s.backfillRules, rules...)
+	}
+}
+
+// WithMaxTotalURLs fails the run before anything is written if it would
+// publish more than n URLs in total, a business-level quota distinct from the
+// per-file limit
+func WithMaxTotalURLs(n int) Option {
+	return func(s *SitemapSplitter) {
+		s.maxTotalURLs = n
+	}
+}
+
+// WithMaxTotalBytes fails the run before anything is written if the chunk
+// files would hold more than n uncompressed bytes in total
+func WithMaxTotalBytes(n int64) Option {
+	return func(s *SitemapSplitter) {
+		s.maxTotalBytes = n
+	}
+}
+
+// WithSkipExamples keeps up to n example locs per skip reason in the Result,
+// showing why the published URL count shrank
+func WithSkipExamples(n int) Option {
+	return func(s *SitemapSplitter) {
+		s.skipExamples = n
+	}
+}
+
+// WithMaxWarnings keeps up to n data-quality warnings with the affected loc in
+// the Result. Warnings are counted per code regardless of n.
+func WithMaxWarnings(n int) Option {
+	return func(s *SitemapSplitter) {
+		s.maxWarnings = n
+	}
+}
+
+// WithOutputFormat selects the file format of the chunks, e.g. FormatText for
+// text sitemaps named -N.txt. Text sitemaps are read as input regardless of
+// this option.
+func WithOutputFormat(format OutputFormat) Option {
+	return func(s *SitemapSplitter) {
+		s.outputFormat = format
+	}
+}
+
+// WithDeduplicate removes entries whose loc occurs earlier or later in the
+// input, keeping the one with the newest lastmod. It is shorthand for
+// WithDedupPolicy(DedupNewest), or DedupKeepAll when enabled is false. The
+// number of entries dropped is reported as Result.Skipped[SkipDuplicate].
+func WithDeduplicate(enabled bool) Option {
+	if enabled {
+		return WithDedupPolicy(DedupNewest)
+	}
+	return WithDedupPolicy(DedupKeepAll)
+}
+
+// WithExcludeFile drops URLs whose loc is listed in the file at path, one URL
+// per line, with blank lines and lines starting with # ignored. Locs are
+// compared exactly after rewriting such as WithCanonicalHost. The file is
+// read at the start of every Split and held in memory as a set.
+func WithExcludeFile(path string) Option {
+	return func(s *SitemapSplitter) {
+		s.excludeFile = path
+	}
+}
+
+// WithAllowPrefixes publishes only URLs whose loc starts with one of the
+// prefixes, e.g. "https://example.com/shop/", dropping all others. Prefixes
+// are compared after rewriting such as WithCanonicalHost.
+func WithAllowPrefixes(prefixes ...string) Option {
+	return func(s *SitemapSplitter) {
+		s.allowPrefixes = append(s.allowPrefixes, prefixes...)
+	}
+}
+
+// WithAllowlistFile publishes only URLs whose loc starts with one of the
+// prefixes listed in the file at path, in addition to those given to
+// WithAllowPrefixes. The file holds one prefix per line, with blank lines and
+// lines starting with # ignored, and is read at the start of every Split. An
+// empty file publishes nothing.
+func WithAllowlistFile(path string) Option {
+	return func(s *SitemapSplitter) {
+		s.allowFile = path
+	}
+}
+
+// WithInclude publishes only URLs whose loc matches one of the patterns,
+// dropping all others. A pattern starting with "re:" is a regular expression,
+// e.g. "re:/(blog|news)/"; any other pattern is a glob in which * matches any
+// run of characters and all other characters stand for themselves, e.g.
+// "https://example.com/*/products/". Patterns match anywhere in the loc and
+// are compared after rewriting such as WithCanonicalHost.
+func WithInclude(patterns ...string) Option {
+	return func(s *SitemapSplitter) {
+		s.includes = append(s.includes, patterns...)
+	}
+}
+
+// WithExclude drops URLs whose loc matches one of the patterns, e.g.
+// "/staging/" or "?preview=". Patterns are written as for WithInclude, and
+// exclusion wins over inclusion.
+func WithExclude(patterns ...string) Option {
+	return func(s *SitemapSplitter) {
+		s.excludes = append(s.excludes, patterns...)
+	}
+}
+
+// WithLastModRange keeps only URLs whose lastmod is at or after after and
+// before before, e.g. to publish a sitemap of recent content from the full
+// input. A zero time leaves that end of the range open. URLs without a
+// parsable lastmod are dropped, and the values compared are those of the
+// input, before any backfill.
+func WithLastModRange(after, before time.Time) Option {
+	return func(s *SitemapSplitter) {
+		s.modAfter = after
+		s.modBefore = before
+	}
+}
+
+// WithLastModWindow keeps only URLs whose lastmod lies within window before
+// the start of the run, e.g. 90*24*time.Hour for the last 90 days. It can be
+// combined with WithLastModRange, in which case both bounds apply.
+func WithLastModWindow(window time.Duration) Option {
+	return func(s *SitemapSplitter) {
+		s.modWindow = window
+	}
+}
+
+// WithDedupPolicy collapses entries sharing a loc within the input into
+// one, chosen by policy. Dropped entries are counted as duplicates in the
+// Result, and duplicates with conflicting metadata are reported as
+// WarnDuplicateConflict warnings. It needs the whole input and cannot be
+// combined with WithStreaming.
+func WithDedupPolicy(policy DedupPolicy) Option {
+	return func(s *SitemapSplitter) {
+		s.dupPolicy = policy
+	}
+}
+
+// WithExpvarMap adds counters of every Split to m, such as one created with
+// expvar.NewMap("sitemap_splitter"), so that they appear under /debug/vars:
+// runs, errors and runs_up_to_date, the urls_processed, urls_written and
+// urls_skipped of successful runs with urls_skipped_<reason> per skip
+// reason, and chunks_written. Several splitters may share one map.
+func WithExpvarMap(m *expvar.Map) Option {
+	return func(s *SitemapSplitter) {
+		s.expvarMap = m
+	}
+}
+
+// WithBackup copies the previous output, the sitemap index in the output
+// directory with the sitemaps it lists and the delta sitemap, into a new
+// timestamped subdirectory of dir right before the run writes its first
+// file, keeping the newest keep backups. The previous files stay in place
+// until the run succeeds, when those it did not overwrite are removed, so a
+// failed run leaves the previous output published. Only subdirectories of
+// dir named like a backup are rotated. Requires the default file sink and a
+// dir outside the output directory.
+func WithBackup(dir string, keep int) Option {
+	return func(s *SitemapSplitter) {
+		s.backupDir = dir
+		s.backupKeep = keep
+	}
+}
+
+// WithVersionedPublish writes every run into a new directory below
+// releases/ in the output directory, verifies the files written and then
+// atomically points the current symlink in the output directory at it, so
+// the sitemaps served from current are never seen half updated. The newest
+// keep releases are retained. Serve current/ and set the index base URL
+// accordingly. Requires the default file sink and a filesystem supporting
+// symlinks.
+func WithVersionedPublish(keep int) Option {
+	return func(s *SitemapSplitter) {
+		s.keepReleases = keep
+	}
+}
+
+// WithDryRun reads, filters, plans and encodes the output like a normal
+// Split but stores nothing: no chunks, index, delta sitemap, state file,
+// history entry or JSON Lines export are written and no alert webhook is
+// called. The Result lists the files that would have been written, with
+// their sizes. A dedup store is looked up but not updated, so a later run
+// still publishes the locs.
+func WithDryRun(enabled bool) Option {
+	return func(s *SitemapSplitter) {
+		s.dryRun = enabled
+	}
+}
+
+// WithDiskSpaceCheck verifies that the filesystem of the output directory has
+// room for the output before anything is written. The output is measured
+// exactly for a regular split; a streamed split estimates it from the size
+// of the input file. Only the default file sink is checked, on Linux, macOS
+// and FreeBSD.
+func WithDiskSpaceCheck() Option {
+	return func(s *SitemapSplitter) {
+		s.diskCheck = true
+	}
+}
+
+// WithAlternateGrouping keeps URLs that link to each other through
+// <xhtml:link> alternates in the same chunk, so hreflang clusters are not
+// broken across sitemaps. Clusters become adjacent in the output, in the
+// order of their first URL. A cluster larger than a chunk is an error.
+func WithAlternateGrouping() Option {
+	return func(s *SitemapSplitter) {
+		s.clusterAlts = true
+	}
+}
+
+// WithHreflangValidation checks the hreflang alternates of the output and
+// reports URLs whose alternates carry a language tag that is not well-formed
+// BCP 47 (or x-default), or point at a URL of the input that does not link
+// back, as WarnHreflangInvalid and WarnHreflangNoReturn warnings.
+func WithHreflangValidation() Option {
+	return func(s *SitemapSplitter) {
+		s.checkLangs = true
+	}
+}
+
+// WithNewsMaxAge warns about news entries whose publication date is more than
+// maxAge in the past. Google News only considers articles from the last two
+// days.
+func WithNewsMaxAge(maxAge time.Duration) Option {
+	return func(s *SitemapSplitter) {
+		s.newsMaxAge = maxAge
+	}
+}
+
+// WithEncryption encrypts every chunk, delta sitemap and index with AES-GCM
+// under key, which must be 16, 24 or 32 bytes long, for staging on shared
+// storage before publishing. Files get the EncryptedSuffix appended to their
+// names and are decrypted with NewDecryptReader. The state file, history and
+// JSONL export are not encrypted.
+func WithEncryption(key []byte) Option {
+	return func(s *SitemapSplitter) {
+		s.encryptKey = key
+	}
+}
+
+// WithTokenizer replaces encoding/xml's tokenizer with another implementation
+// when decoding is the measured bottleneck. Decode limits still apply on top
+// of the returned tokens.
+func WithTokenizer(t Tokenizer) Option {
+	return func(s *SitemapSplitter) {
+		s.tokenizer = t
+	}
+}
+
+// WithEmptyInput sets how input without any URLs (an empty or whitespace-only
+// file, or an empty urlset) is handled. The default is EmptyInputFail.
+func WithEmptyInput(policy EmptyInputPolicy) Option {
+	return func(s *SitemapSplitter) {
+		s.emptyInput = policy
+	}
+}
+
+// WithIndexOrder sets the order of the sitemap index entries. The default
+// lists them by chunk number.
+func WithIndexOrder(order IndexOrder) Option {
+	return func(s *SitemapSplitter) {
+		s.indexOrder = order
+	}
+}
+
+// WithIndexLess orders the sitemap index entries with a custom comparator
+// reporting whether a sorts before b. It takes precedence over WithIndexOrder.
+func WithIndexLess(less func(a, b Sitemap) bool) Option {
+	return func(s *SitemapSplitter) {
+		s.indexLess = less
+	}
+}
+
+// WithHistory records the manifest and Result of every successful run as a
+// JSON file in dir, keeping the newest keep records (0 keeps all). Use
+// ReadHistory to list them.
+func WithHistory(dir string, keep int) Option {
+	return func(s *SitemapSplitter) {
+		s.historyDir = dir
+		s.historyKeep = keep
+	}
+}
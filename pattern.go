@@ -0,0 +1,47 @@
+package sitemapsplitter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// regexpPrefix marks a loc pattern as a regular expression rather than a
+// glob
+const regexpPrefix = "re:"
+
+// compilePatterns compiles loc patterns for WithInclude and WithExclude. A
+// pattern starting with "re:" is a regular expression, any other is a glob in
+// which * matches any run of characters. Both match anywhere in the loc.
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		expr, isRegexp := strings.CutPrefix(p, regexpPrefix)
+		if !isRegexp {
+			parts := strings.Split(p, "*")
+			for i, part := range parts {
+				parts[i] = regexp.QuoteMeta(part)
+			}
+			expr = strings.Join(parts, ".*")
+		}
+		if expr == "" {
+			return nil, fmt.Errorf("URL pattern must not be empty")
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URL pattern %q: %v", p, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// matchAny reports whether loc matches one of res
+func matchAny(res []*regexp.Regexp, loc string) bool {
+	for _, re := range res {
+		if re.MatchString(loc) {
+			return true
+		}
+	}
+	return false
+}
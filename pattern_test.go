@@ -0,0 +1,47 @@
+package sitemapsplitter
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestIncludeExclude(t *testing.T) {
+	locs := []string{
+		"https://example.com/blog/a",
+		"https://example.com/news/b",
+		"https://example.com/shop/products/c",
+		"https://example.com/blog/drafts/d",
+		"https://example.com/blog/e?preview=1",
+	}
+	tests := []struct {
+		name string
+		opts []Option
+		want []string
+	}{
+		{"include glob", []Option{WithInclude("/blog/")}, []string{locs[0], locs[3], locs[4]}},
+		{"include glob with star", []Option{WithInclude("https://example.com/*/products/")}, []string{locs[2]}},
+		{"includes are alternatives", []Option{WithInclude("/news/"), WithInclude("/shop/")}, []string{locs[1], locs[2]}},
+		{"glob characters are literal", []Option{WithInclude("?preview=")}, []string{locs[4]}},
+		{"include regexp", []Option{WithInclude("re:/(blog|news)/[a-z]$")}, []string{locs[0], locs[1]}},
+		{"exclude regexp", []Option{WithExclude(`re:\?preview=\d+$`)}, []string{locs[0], locs[1], locs[2], locs[3]}},
+		{"exclusion wins", []Option{WithInclude("/blog/"), WithExclude("/drafts/", "re:preview")}, []string{locs[0]}},
+		{"exclusion wins over the same pattern", []Option{WithInclude("/news/", "/shop/"), WithExclude("/news/")}, []string{locs[2]}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := keptLocs(t, locs, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("kept %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	for _, opt := range []Option{WithInclude("re:/(blog"), WithExclude("re:[z-a]"), WithExclude("re:"), WithInclude("")} {
+		if _, err := NewSitemapSplitter("in.xml", 10, opt); err == nil {
+			t.Error("invalid pattern accepted")
+		}
+	}
+}
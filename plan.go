@@ -0,0 +1,291 @@
+package sitemapsplitter
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strconv"
+)
+
+// chunk is one planned output sitemap file
+type chunk struct {
+	name string // Output path relative to the output directory, slash separated
+	urls []URL
+}
+
+// planChunks splits urls into chunks of at most s.limit URLs, and of at most
+// s.maxBytes uncompressed bytes when set, and assigns each chunk its output
+// name. With WithAlternateGrouping clusters of alternates are never split
+// across chunks.
+func (s *SitemapSplitter) planChunks(urls []URL, baseFilename string) ([]chunk, error) {
+	limit := s.chunkLimit(len(urls))
+	urls, ends := s.alternateGroups(urls)
+
+	var chunks []chunk
+	start := 0
+	closeChunk := func(end int) {
+		chunks = append(chunks, chunk{urls: urls[start:end]})
+		start = end
+	}
+
+	var sizer *chunkSizer
+	if s.maxBytes > 0 {
+		sizer = s.newChunkSizer()
+	}
+	i := 0
+	for _, end := range ends {
+		group := urls[i:end]
+		if len(group) > limit {
+			return nil, fmt.Errorf("alternates of %s form a cluster of %d URLs, more than the %d allowed per chunk", group[0].Loc, len(group), limit)
+		}
+		if end-start > limit {
+			closeChunk(i)
+			if sizer != nil {
+				sizer.reset()
+			}
+		}
+		if sizer != nil {
+			if err := sizer.fitGroup(group, i == start, func() error {
+				closeChunk(i)
+				return nil
+			}); err != nil {
+				return nil, err
+			}
+		}
+		i = end
+	}
+	if start < len(urls) {
+		closeChunk(len(urls))
+	}
+
+	for i := range chunks {
+		chunks[i].name = s.chunkName(baseFilename, i, len(chunks), chunks[i].urls)
+	}
+	return chunks, nil
+}
+
+// chunkName returns the output name of the i-th of n chunks, holding urls
+func (s *SitemapSplitter) chunkName(baseFilename string, i, n int, urls []URL) string {
+	if s.nameTemplate != "" {
+		return s.shardPath(i, n, s.expandNameTemplate(baseFilename, i, urls))
+	}
+	return s.shardPath(i, n, fmt.Sprintf("%s-%d%s", baseFilename, i+1, s.chunkExt()))
+}
+
+// chunkLimit returns the number of URLs per chunk for total URLs. With a
+// target file count the limit is derived from the input size, but never
+// exceeds the configured limit.
+func (s *SitemapSplitter) chunkLimit(total int) int {
+	if s.targetFiles <= 0 || total == 0 {
+		return s.limit
+	}
+	return min(s.limit, (total+s.targetFiles-1)/s.targetFiles)
+}
+
+// shardPath places the i-th of n files into its shard subdirectory when
+// sharding is enabled. An n of 0 means the file count is not known yet, as
+// when streaming, and shard numbers get the minimum width.
+func (s *SitemapSplitter) shardPath(i, n int, name string) string {
+	if s.filesPerShard <= 0 {
+		return name
+	}
+
+	width := 2
+	if n > 0 {
+		shards := (n + s.filesPerShard - 1) / s.filesPerShard
+		width = max(width, len(strconv.Itoa(shards-1)))
+	}
+	return fmt.Sprintf("%0*d/%s", width, i/s.filesPerShard, name)
+}
+
+// Limits the sitemap protocol places on a sitemap index. Indexes cannot list
+// other indexes, so an index over these limits cannot be rolled over.
+const (
+	maxIndexEntries = 50000
+	maxIndexBytes   = 50 * 1024 * 1024
+)
+
+// checkIndexEntries fails if an index of n entries would exceed the protocol
+// limit, suggesting larger chunks instead
+func checkIndexEntries(n int) error {
+	if n > maxIndexEntries {
+		return fmt.Errorf("sitemap index would list %d sitemaps, exceeding the protocol limit of %d; raise the URL limit per chunk or split the input by section", n, maxIndexEntries)
+	}
+	return nil
+}
+
+// checkIndexBytes fails if the encoded index would exceed the protocol's
+// uncompressed size limit, suggesting larger chunks or a shorter base URL
+func (s *SitemapSplitter) checkIndexBytes(index SitemapIndex) error {
+	counter := &byteCounter{}
+	if err := s.encodeXMLFile(counter, OutputIndex, index); err != nil {
+		return fmt.Errorf("error measuring sitemap index size: %v", err)
+	}
+	if counter.n > maxIndexBytes {
+		return fmt.Errorf("sitemap index would be %d bytes, exceeding the protocol limit of %d; raise the URL limit per chunk or use a shorter index base URL", counter.n, maxIndexBytes)
+	}
+	return nil
+}
+
+// checkPlannedIndex fails if the index listing chunks would exceed the
+// protocol's size limit, so that a planned split fails before writing anything
+func (s *SitemapSplitter) checkPlannedIndex(chunks []chunk) error {
+	index := SitemapIndex{
+		XMLNS:    "http://www.sitemaps.org/schemas/sitemap/0.9",
+		Sitemaps: make([]Sitemap, len(chunks)),
+	}
+	for i, c := range chunks {
+		entry, err := s.indexEntry(c)
+		if err != nil {
+			return err
+		}
+		index.Sitemaps[i] = entry
+	}
+	return s.checkIndexBytes(index)
+}
+
+// extraNames returns the names of the sitemaps written besides the chunks and
+// the index, such as the delta and news sitemaps
+func (s *SitemapSplitter) extraNames() []string {
+	var names []string
+	for _, name := range []string{s.deltaName, s.newsName} {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// checkFileCount fails if writing chunkCount chunks (with all their variants)
+// plus the index, delta and news sitemaps would exceed the configured file cap
+func (s *SitemapSplitter) checkFileCount(chunkCount int) error {
+	if s.maxFiles == 0 {
+		return nil
+	}
+
+	total := chunkCount * len(s.chunkPaths(""))
+	if !s.noIndex {
+		total++
+	}
+	total += len(s.extraNames())
+	if total > s.maxFiles {
+		return fmt.Errorf("split would write %d files, exceeding the limit of %d", total, s.maxFiles)
+	}
+	return nil
+}
+
+// outputNames lists every file a run writes, relative to the output directory
+func (s *SitemapSplitter) outputNames(chunks []chunk) []string {
+	names := make([]string, 0, len(chunks)+2)
+	for _, c := range chunks {
+		names = append(names, s.chunkPaths(c.name)...)
+	}
+	if !s.noIndex {
+		names = append(names, s.indexFile())
+	}
+	return append(names, s.extraNames()...)
+}
+
+// chunkPaths returns every file written for a chunk at path
+func (s *SitemapSplitter) chunkPaths(path string) []string {
+	switch {
+	case s.dualOutput:
+		return []string{path, path + ".gz"}
+	case s.gzipOutput:
+		return []string{path + ".gz"}
+	}
+	return []string{path}
+}
+
+// indexedName returns the variant of a chunk name referenced by the index
+func (s *SitemapSplitter) indexedName(name string) string {
+	if s.gzipOutput || s.dualOutput && s.preferGzip {
+		return name + ".gz"
+	}
+	return name
+}
+
+// indexFile returns the filename the sitemap index is written to
+func (s *SitemapSplitter) indexFile() string {
+	if s.gzipOutput {
+		return s.indexName + ".gz"
+	}
+	return s.indexName
+}
+
+// checkCollisions fails if two planned outputs would be written to the same
+// file, which would otherwise silently overwrite one with the other
+func checkCollisions(names ...string) error {
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		clean := path.Clean(name)
+		if seen[clean] {
+			return fmt.Errorf("output filename collision: %s would be written more than once", clean)
+		}
+		seen[clean] = true
+	}
+	return nil
+}
+
+// checkInputOverwrite fails if any output name refers to the input file,
+// which would corrupt the input while it is being split. Only outputs to a
+// FileSink can overwrite the input.
+func (s *SitemapSplitter) checkInputOverwrite(names []string) error {
+	fs, ok := s.sink.(*FileSink)
+	if !ok {
+		return nil
+	}
+
+	input, err := filepath.Abs(s.path)
+	if err != nil {
+		return fmt.Errorf("error resolving sitemap path: %v", err)
+	}
+
+	for _, name := range names {
+		output, err := filepath.Abs(fs.path(name))
+		if err != nil {
+			return fmt.Errorf("error resolving output path: %v", err)
+		}
+		if output == input {
+			return fmt.Errorf("output %s would overwrite the input sitemap; rename the input or change the output names", name)
+		}
+	}
+	return nil
+}
+
+// checkQuotas fails if the planned chunks exceed the run-level URL or byte
+// quotas. Bytes are measured by encoding every chunk once without writing it.
+func (s *SitemapSplitter) checkQuotas(chunks []chunk) error {
+	if s.maxTotalURLs > 0 {
+		total := 0
+		for _, c := range chunks {
+			total += len(c.urls)
+		}
+		if total > s.maxTotalURLs {
+			return fmt.Errorf("run would publish %d URLs, exceeding the quota of %d", total, s.maxTotalURLs)
+		}
+	}
+
+	if s.maxTotalBytes > 0 {
+		counter := &byteCounter{}
+		for _, c := range chunks {
+			if err := s.encodeChunk(counter, c.urls); err != nil {
+				return fmt.Errorf("error measuring output size: %v", err)
+			}
+		}
+		if counter.n > s.maxTotalBytes {
+			return fmt.Errorf("run would write %d bytes, exceeding the quota of %d", counter.n, s.maxTotalBytes)
+		}
+	}
+	return nil
+}
+
+// byteCounter is an io.Writer that only counts what is written to it
+type byteCounter struct {
+	n int64
+}
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,258 @@
+package sitemapsplitter
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMaxFiles(t *testing.T) {
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc></url><url><loc>https://example.com/b</loc></url><url><loc>https://example.com/c</loc></url></urlset>`
+	tests := []struct {
+		name    string
+		max     int
+		opts    []Option
+		wantErr bool
+	}{
+		{"chunks and index", 4, nil, false},
+		{"one file too many", 3, nil, true},
+		{"without index", 3, []Option{WithoutIndex()}, false},
+		{"dual output", 6, []Option{WithDualOutput(false)}, true},
+		{"news sitemap", 4, []Option{WithNewsSitemap("")}, true},
+		{"streaming", 4, []Option{WithStreaming()}, false},
+		{"streaming too many", 3, []Option{WithStreaming()}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := NewMemorySink()
+			s, err := NewSitemapSplitter("in.xml", 1, append([]Option{WithSink(sink), WithMaxFiles(tt.max)}, tt.opts...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = s.SplitFrom(strings.NewReader(in))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("split error %v, want error %v", err, tt.wantErr)
+			}
+			if got := len(sink.Names()); got > tt.max {
+				t.Errorf("split stored %d files, more than %d", got, tt.max)
+			}
+			if err != nil && !s.streaming && len(sink.Names()) > 0 {
+				t.Errorf("failed split stored %v", sink.Names())
+			}
+		})
+	}
+}
+
+func TestOutputNameCollisions(t *testing.T) {
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc></url><url><loc>https://example.com/b</loc></url><url><loc>https://example.com/c</loc></url></urlset>`
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr bool
+	}{
+		{"distinct names", []Option{WithIndexName("index.xml"), WithNewsSitemap("")}, false},
+		{"index named like a chunk", []Option{WithIndexName("in-2.xml")}, true},
+		{"news named like a chunk", []Option{WithNewsSitemap("in-3.xml")}, true},
+		{"news named like the index", []Option{WithNewsSitemap("./sitemap-index.xml")}, true},
+		{"index named like a shard", []Option{WithSharding(2), WithIndexName("01/in-3.xml")}, true},
+		{"streaming", []Option{WithStreaming(), WithIndexName("in-2.xml")}, true},
+		{"streaming fixed names", []Option{WithStreaming(), WithNewsSitemap("sitemap-index.xml")}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := NewMemorySink()
+			s, err := NewSitemapSplitter("in.xml", 1, append([]Option{WithSink(sink)}, tt.opts...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = s.SplitFrom(strings.NewReader(in))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("split error %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "collision") {
+				t.Errorf("split failed with %v, want a collision error", err)
+			}
+		})
+	}
+}
+
+func TestTargetFileCount(t *testing.T) {
+	tests := []struct {
+		name   string
+		urls   int
+		limit  int
+		target int
+		want   []int // URLs per chunk
+	}{
+		{"even", 100, 50000, 4, []int{25, 25, 25, 25}},
+		{"rounded up", 10, 50000, 3, []int{4, 4, 2}},
+		{"more files than URLs", 3, 50000, 5, []int{1, 1, 1}},
+		{"capped by limit", 30, 10, 2, []int{10, 10, 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSitemapSplitter("in.xml", tt.limit, WithTargetFileCount(tt.target))
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.reset()
+			chunks, err := s.planChunks(sizedURLs(tt.urls), "in")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []int
+			for _, c := range chunks {
+				got = append(got, len(c.urls))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("chunk sizes %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewSitemapSplitter("in.xml", 10, WithTargetFileCount(3), WithStreaming()); err == nil {
+		t.Error("target file count accepted with streaming")
+	}
+}
+
+func TestRefuseInputOverwrite(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    func(dir string) []Option
+		wantErr bool
+	}{
+		{"index named like the input", func(string) []Option { return []Option{WithIndexName("in.xml")} }, true},
+		{"news named like the input", func(string) []Option { return []Option{WithNewsSitemap("in.xml")} }, true},
+		{"other output directory", func(dir string) []Option {
+			return []Option{WithOutputDir(filepath.Join(dir, "out")), WithIndexName("in.xml")}
+		}, false},
+		{"output directory holding the input", func(dir string) []Option {
+			return []Option{WithOutputDir(filepath.Join(dir, "out", "..")), WithIndexName("in.xml")}
+		}, true},
+		{"custom sink", func(string) []Option { return []Option{WithSink(NewMemorySink()), WithIndexName("in.xml")} }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			input := filepath.Join(dir, "in.xml")
+			writeSitemap(t, input, "https://example.com/a")
+			s, err := NewSitemapSplitter(input, 1, tt.opts(dir)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = s.Split()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("split error %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "overwrite the input") {
+				t.Errorf("split failed with %v, want an input overwrite error", err)
+			}
+			if tt.wantErr && !fileContains(input, "<urlset") {
+				t.Error("failed split overwrote the input")
+			}
+		})
+	}
+}
+
+func TestQuotas(t *testing.T) {
+	in := streamInput(30)
+	split := func(opts ...Option) (*MemorySink, *Result, error) {
+		sink := NewMemorySink()
+		s, err := NewSitemapSplitter("in.xml", 10, append([]Option{WithSink(sink)}, opts...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = s.SplitFrom(strings.NewReader(in))
+		return sink, s.LastResult(), err
+	}
+	_, full, err := split()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var size int64
+	for _, f := range full.Files {
+		size += f.Bytes
+	}
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr bool
+	}{
+		{"URLs at quota", []Option{WithMaxTotalURLs(full.URLs)}, false},
+		{"URLs over quota", []Option{WithMaxTotalURLs(full.URLs - 1)}, true},
+		{"bytes at quota", []Option{WithMaxTotalBytes(size)}, false},
+		{"bytes over quota", []Option{WithMaxTotalBytes(size - 1)}, true},
+		{"streaming URLs over quota", []Option{WithStreaming(), WithMaxTotalURLs(full.URLs - 1)}, true},
+		{"streaming bytes over quota", []Option{WithStreaming(), WithMaxTotalBytes(size - 1)}, true},
+		{"streaming at quota", []Option{WithStreaming(), WithMaxTotalURLs(full.URLs), WithMaxTotalBytes(size)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink, _, err := split(tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("split error %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "quota") {
+				t.Errorf("split failed with %v, want a quota error", err)
+			}
+			if _, ok := sink.File("sitemap-index.xml"); ok == tt.wantErr {
+				t.Errorf("index written: %v", ok)
+			}
+		})
+	}
+}
+
+func TestIndexLimits(t *testing.T) {
+	long := "https://example.com/" + strings.Repeat("x", 2000) + ".xml"
+	tests := []struct {
+		name    string
+		n       int    // Sitemaps listed
+		loc     string // Loc of every sitemap
+		wantErr string // Part of the error, empty if the index is written
+	}{
+		{"entries at the limit", maxIndexEntries, "https://example.com/s.xml", ""},
+		{"too many entries", maxIndexEntries + 1, "https://example.com/s.xml", "exceeding the protocol limit of 50000"},
+		{"bytes within the limit", 20000, long, ""},
+		{"too many bytes", 26000, long, "exceeding the protocol limit of 52428800"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := NewMemorySink()
+			s, err := NewSitemapSplitter("in.xml", 10, WithSink(sink))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = s.WriteIndex(slices.Repeat([]string{tt.loc}, tt.n), IndexLastModOmit)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want it to mention %q", err, tt.wantErr)
+			}
+			if names := sink.Names(); len(names) != 0 {
+				t.Errorf("wrote %v over the limit", names)
+			}
+		})
+	}
+}
+
+func TestIndexBytesCheckedBeforeWriting(t *testing.T) {
+	sink := NewMemorySink()
+	base := "https://example.com/" + strings.Repeat("x", 2000) + "/"
+	s, err := NewSitemapSplitter("in.xml", 1, WithSink(sink), WithIndexBaseURL(base))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.SplitFrom(strings.NewReader(streamInput(26000)))
+	if err == nil || !strings.Contains(err.Error(), "exceeding the protocol limit of 52428800") {
+		t.Fatalf("got error %v, want the index size limit", err)
+	}
+	if names := sink.Names(); len(names) != 0 {
+		t.Errorf("wrote %d files before failing on the index size", len(names))
+	}
+}
@@ -0,0 +1,61 @@
+package sitemapsplitter
+
+import "fmt"
+
+// PlannedChunk is a chunk file that Split would write
+type PlannedChunk struct {
+	Name string   // Name handed to the sink, slash separated
+	Locs []string // Locs of the chunk's URLs in output order
+}
+
+// Preview reads and filters the input and plans its chunks as Split does,
+// without writing anything, to show how URLs are grouped and ordered. Remote
+// inputs are downloaded as by Split. The chunks are listed in index order. A
+// dedup store is only looked up, so the preview does not mark URLs as
+// published.
+func (s *SitemapSplitter) Preview() ([]PlannedChunk, error) {
+	if s.streaming {
+		return nil, fmt.Errorf("chunk preview needs the whole input and cannot be used with streaming")
+	}
+	s.reset()
+	if isRemote(s.path) {
+		body, modTime, err := s.fetchInput()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		s.reader, s.readerModTime = body, modTime
+		defer func() { s.reader = nil }()
+	}
+	urls, err := s.readURLs()
+	if err != nil {
+		return nil, err
+	}
+	if urls, _, err = s.filterURLs(urls); err != nil {
+		return nil, err
+	}
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("no URLs left after filtering")
+	}
+	s.fillLastMod(urls)
+
+	chunks, err := s.planChunks(urls, inputBaseName(s.path))
+	if err != nil {
+		return nil, err
+	}
+	planned := make([]PlannedChunk, len(chunks))
+	entries := make([]Sitemap, len(chunks))
+	for i, c := range chunks {
+		if entries[i], err = s.indexEntry(c); err != nil {
+			return nil, err
+		}
+		planned[i].Name = s.sinkName(c.name)
+		for _, u := range c.urls {
+			planned[i].Locs = append(planned[i].Locs, u.Loc)
+		}
+	}
+	s.sortIndexWith(entries, func(i, j int) {
+		planned[i], planned[j] = planned[j], planned[i]
+	})
+	return planned, nil
+}
@@ -0,0 +1,129 @@
+package sitemapsplitter
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestPreview(t *testing.T) {
+	dir := t.TempDir()
+	input, out := filepath.Join(dir, "in.xml"), filepath.Join(dir, "out")
+	writeSitemap(t, input,
+		"https://example.com/a", "https://example.com/b", "https://example.com/staging/c",
+		"https://example.com/d", "https://example.com/e")
+	s, err := NewSitemapSplitter(input, 2, WithOutputDir(out), WithExclude("/staging/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chunks, err := s.Preview()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PlannedChunk{
+		{Name: "in-1.xml", Locs: []string{"https://example.com/a", "https://example.com/b"}},
+		{Name: "in-2.xml", Locs: []string{"https://example.com/d", "https://example.com/e"}},
+	}
+	if !slices.EqualFunc(chunks, want, func(a, b PlannedChunk) bool {
+		return a.Name == b.Name && slices.Equal(a.Locs, b.Locs)
+	}) {
+		t.Errorf("Preview() = %v, want %v", chunks, want)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Errorf("preview created the output directory: %v", err)
+	}
+}
+
+func TestPreviewRemoteInput(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc></url></urlset>`)
+	}))
+	defer srv.Close()
+
+	s, err := NewSitemapSplitter(srv.URL+"/sitemap.xml", 10, WithOutputDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks, err := s.Preview()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 1 || chunks[0].Name != "sitemap-1.xml" || !slices.Equal(chunks[0].Locs, []string{"https://example.com/a"}) {
+		t.Errorf("Preview() = %v, want sitemap-1.xml holding https://example.com/a", chunks)
+	}
+}
+
+func TestPreviewIndexOrder(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.xml")
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` +
+		`<url><loc>https://example.com/old</loc><lastmod>2024-01-01</lastmod></url>` +
+		`<url><loc>https://example.com/new</loc><lastmod>2024-06-01</lastmod></url>` +
+		`<url><loc>https://example.com/mid</loc><lastmod>2024-03-01</lastmod></url></urlset>`
+	if err := os.WriteFile(input, []byte(in), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		order IndexOrder
+		want  []string
+	}{
+		{IndexOrderChunk, []string{"in-1.xml", "in-2.xml", "in-3.xml"}},
+		{IndexOrderNewestFirst, []string{"in-2.xml", "in-3.xml", "in-1.xml"}},
+	}
+	for _, tt := range tests {
+		s, err := NewSitemapSplitter(input, 1, WithIndexOrder(tt.order))
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks, err := s.Preview()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, c := range chunks {
+			names = append(names, c.Name)
+		}
+		if !slices.Equal(names, tt.want) {
+			t.Errorf("order %v: Preview() names = %v, want %v", tt.order, names, tt.want)
+		}
+		if tt.order == IndexOrderNewestFirst && chunks[0].Locs[0] != "https://example.com/new" {
+			t.Errorf("chunk names and locs no longer match: %v", chunks)
+		}
+	}
+}
+
+func TestPreviewLeavesDedupStore(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.xml")
+	writeSitemap(t, input, "https://example.com/a", "https://example.com/b", "https://example.com/a")
+	store := NewMemoryDedupStore()
+	store.Add("https://example.com/b")
+
+	s, err := NewSitemapSplitter(input, 10, WithOutputDir(dir), WithDedupStore(store))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks, err := s.Preview()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 1 || !slices.Equal(chunks[0].Locs, []string{"https://example.com/a"}) {
+		t.Errorf("Preview() = %v, want only https://example.com/a", chunks)
+	}
+	if seen, _ := store.Seen("https://example.com/a"); seen {
+		t.Error("preview recorded https://example.com/a in the dedup store")
+	}
+
+	if err := s.Split(); err != nil {
+		t.Fatal(err)
+	}
+	if !fileContains(filepath.Join(dir, "in-1.xml"), "https://example.com/a") {
+		t.Error("split after the preview dropped https://example.com/a")
+	}
+}
@@ -0,0 +1,9 @@
+package sitemapsplitter
+
+// ProductNamespace is the Google Merchant Center namespace used for product
+// data embedded in sitemap entries (g:price, g:availability, ...)
+const ProductNamespace = "http://base.google.com/ns/1.0"
+
+func init() {
+	RegisterExtension(RawExtension(ProductNamespace, "g"))
+}
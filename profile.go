@@ -0,0 +1,77 @@
+package sitemapsplitter
+
+import (
+	"sort"
+	"time"
+)
+
+// Profile names a preset of limits and validation settings for a common kind
+// of sitemap, see WithProfile
+type Profile string
+
+const (
+	// ProfileGoogleDefault follows the sitemaps.org limits of 50,000 URLs and
+	// 50MB per file and drops invalid entries while they stay below 1%
+	ProfileGoogleDefault Profile = "google-default"
+	// ProfileImages is ProfileGoogleDefault with at most 10,000 URLs per file,
+	// as image entries make each URL much larger
+	ProfileImages Profile = "images"
+	// ProfileNews follows the Google News limit of 1,000 URLs per file and
+	// warns about articles published more than two days ago
+	ProfileNews Profile = "news"
+	// ProfileTinyDebug writes chunks of 10 URLs and reports invalid entries,
+	// skip examples and warnings without ever failing on them, for trying
+	// settings out on a sample
+	ProfileTinyDebug Profile = "tiny-debug"
+)
+
+// profiles maps every known profile to the settings it applies
+var profiles = map[Profile]func(*SitemapSplitter){
+	ProfileGoogleDefault: func(s *SitemapSplitter) {
+		s.limit = min(s.limit, 50000)
+		s.maxBytes = 50 * 1024 * 1024
+		s.validate, s.maxErrorRate = true, 1
+	},
+	ProfileImages: func(s *SitemapSplitter) {
+		s.limit = min(s.limit, 10000)
+		s.maxBytes = 50 * 1024 * 1024
+		s.validate, s.maxErrorRate = true, 1
+	},
+	ProfileNews: func(s *SitemapSplitter) {
+		s.limit = min(s.limit, 1000)
+		s.maxBytes = 50 * 1024 * 1024
+		s.validate, s.maxErrorRate = true, 1
+		s.newsMaxAge = 48 * time.Hour
+	},
+	ProfileTinyDebug: func(s *SitemapSplitter) {
+		s.limit = min(s.limit, 10)
+		s.validate, s.maxErrorRate = true, 100
+		s.skipExamples = 20
+		s.maxWarnings = 100
+	},
+}
+
+// WithProfile applies the settings of a named preset. The limit passed to
+// NewSitemapSplitter is lowered to the profile's URL limit if it is higher,
+// and options given after WithProfile override the profile's settings. An
+// unknown profile makes NewSitemapSplitter fail.
+func WithProfile(p Profile) Option {
+	return func(s *SitemapSplitter) {
+		apply, ok := profiles[p]
+		if !ok {
+			s.profile = p
+			return
+		}
+		apply(s)
+	}
+}
+
+// Profiles returns the names of all known profiles, sorted
+func Profiles() []Profile {
+	names := make([]Profile, 0, len(profiles))
+	for p := range profiles {
+		names = append(names, p)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
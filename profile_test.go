@@ -0,0 +1,51 @@
+package sitemapsplitter
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestProfiles(t *testing.T) {
+	tests := []struct {
+		name       string
+		limit      int
+		opts       []Option
+		wantLimit  int
+		wantMaxAge time.Duration
+		wantErr    bool
+	}{
+		{"google default", 100000, []Option{WithProfile(ProfileGoogleDefault)}, 50000, 0, false},
+		{"images", 50000, []Option{WithProfile(ProfileImages)}, 10000, 0, false},
+		{"news", 50000, []Option{WithProfile(ProfileNews)}, 1000, 48 * time.Hour, false},
+		{"tiny debug", 50000, []Option{WithProfile(ProfileTinyDebug)}, 10, 0, false},
+		{"lower limit kept", 5, []Option{WithProfile(ProfileNews)}, 5, 48 * time.Hour, false},
+		{"later option wins", 50000, []Option{WithProfile(ProfileNews), WithNewsMaxAge(time.Hour)}, 1000, time.Hour, false},
+		{"unknown", 50000, []Option{WithProfile("huge")}, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSitemapSplitter("in.xml", tt.limit, tt.opts...)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("unknown profile accepted")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.limit != tt.wantLimit {
+				t.Errorf("limit %d, want %d", s.limit, tt.wantLimit)
+			}
+			if s.newsMaxAge != tt.wantMaxAge {
+				t.Errorf("news max age %v, want %v", s.newsMaxAge, tt.wantMaxAge)
+			}
+		})
+	}
+
+	want := []Profile{ProfileGoogleDefault, ProfileImages, ProfileNews, ProfileTinyDebug}
+	if got := Profiles(); !slices.Equal(got, want) {
+		t.Errorf("Profiles() = %v, want %v", got, want)
+	}
+}
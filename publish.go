@@ -0,0 +1,123 @@
+package sitemapsplitter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+const (
+	// releasesDir is the directory below the output directory holding one
+	// subdirectory per published release
+	releasesDir = "releases"
+	// currentLink is the symlink in the output directory pointing at the
+	// live release
+	currentLink = "current"
+)
+
+// publishRelease runs a split into a new release directory, verifies the
+// files it wrote and points the current symlink at the release, so that
+// readers of current never see a partially written set. commit runs only
+// once the release is live. Releases beyond the retention count are removed
+// afterwards.
+func (s *SitemapSplitter) publishRelease(split, commit func() error) error {
+	root := s.sink.(*FileSink)
+	name := time.Now().UTC().Format(stampLayout)
+	dir := filepath.Join(root.dir, releasesDir, name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating release directory: %v", err)
+	}
+
+	s.sink = &FileSink{dir: dir, perm: root.perm}
+	err := split()
+	s.sink = root
+	if err == nil {
+		if err = s.verifyRelease(); err != nil {
+			err = fmt.Errorf("error verifying release %s: %v", name, err)
+		}
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+
+	if err := flipCurrent(root.dir, name); err != nil {
+		os.RemoveAll(dir)
+		return fmt.Errorf("error publishing release %s: %v", name, err)
+	}
+	s.logger.Info("published release", "phase", "upload", "release", name, "files", len(s.result.Files))
+	if err := commit(); err != nil {
+		return err
+	}
+	return pruneDirs(filepath.Join(root.dir, releasesDir), s.keepReleases)
+}
+
+// verifyRelease checks that every file of the run is on disk with the size
+// that was written
+func (s *SitemapSplitter) verifyRelease() error {
+	files := s.result.Files
+	if s.result.Index != nil {
+		files = append(slices.Clip(files), *s.result.Index)
+	}
+	for _, f := range files {
+		info, err := os.Stat(f.Path)
+		if err != nil {
+			return err
+		}
+		if info.Size() != f.Bytes {
+			return fmt.Errorf("%s has %d bytes, expected %d", f.Name, info.Size(), f.Bytes)
+		}
+	}
+	return nil
+}
+
+// flipCurrent atomically points the current symlink below root at the
+// release name by renaming a new symlink over it
+func flipCurrent(root, name string) error {
+	tmp := filepath.Join(root, "."+currentLink+".tmp")
+	os.Remove(tmp)
+	if err := os.Symlink(filepath.Join(releasesDir, name), tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(root, currentLink)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// pruneDirs removes all but the newest keep timestamped subdirectories of
+// dir, as created for releases and backups
+func pruneDirs(dir string, keep int) error {
+	names, err := stampedDirs(dir)
+	if err != nil {
+		return err
+	}
+	for len(names) > keep {
+		if err := os.RemoveAll(filepath.Join(dir, names[0])); err != nil {
+			return fmt.Errorf("error pruning %s: %v", dir, err)
+		}
+		names = names[1:]
+	}
+	return nil
+}
+
+// stampedDirs lists the timestamped subdirectories of dir, oldest first
+func stampedDirs(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error listing %s: %v", dir, err)
+	}
+	var names []string
+	for _, e := range entries {
+		// Only directories named like a stamp are releases or backups;
+		// anything else in dir belongs to someone else
+		if _, err := time.Parse(stampLayout, e.Name()); e.IsDir() && err == nil {
+			names = append(names, e.Name())
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
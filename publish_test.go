@@ -0,0 +1,184 @@
+package sitemapsplitter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRollback(t *testing.T) {
+	tests := []struct {
+		name string
+		opts func(dir string) []Option
+		live string // Directory below out holding the live output
+	}{
+		{"release", func(string) []Option { return []Option{WithVersionedPublish(3)} }, currentLink},
+		{"backup", func(dir string) []Option { return []Option{WithBackup(filepath.Join(dir, "backups"), 3)} }, ""},
+		{"gzip backup", func(dir string) []Option {
+			return []Option{WithBackup(filepath.Join(dir, "backups"), 3), WithGzipOutput(true)}
+		}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			input, out := filepath.Join(dir, "in.xml"), filepath.Join(dir, "out")
+			s, err := NewSitemapSplitter(input, 1, append([]Option{WithOutputDir(out)}, tt.opts(dir)...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			live := filepath.Join(out, tt.live)
+			ext := s.chunkExt()
+			if s.gzipOutput {
+				ext += ".gz"
+			}
+
+			writeSitemap(t, input, "https://example.com/a", "https://example.com/b")
+			if err := s.Split(); err != nil {
+				t.Fatal(err)
+			}
+			writeSitemap(t, input, "https://example.com/c", "https://example.com/d", "https://example.com/e")
+			if err := s.Split(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(filepath.Join(live, "in-3"+ext)); err != nil {
+				t.Fatalf("second run did not publish its third chunk: %v", err)
+			}
+
+			if err := s.Rollback(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(filepath.Join(live, "in-3"+ext)); !os.IsNotExist(err) {
+				t.Errorf("rollback kept the third chunk of the second run: %v", err)
+			}
+			if !s.gzipOutput && !fileContains(filepath.Join(live, "in-1"+ext), "https://example.com/a") {
+				t.Error("rollback did not restore the first run's chunk")
+			}
+			if _, err := os.Stat(filepath.Join(live, "in-2"+ext)); err != nil {
+				t.Errorf("rollback did not restore the first run's second chunk: %v", err)
+			}
+
+			if err := s.Rollback(); err == nil {
+				t.Error("rolling back past the first run succeeded")
+			}
+		})
+	}
+}
+
+func TestVersionedPublish(t *testing.T) {
+	dir := t.TempDir()
+	input, out := filepath.Join(dir, "in.xml"), filepath.Join(dir, "out")
+	s, err := NewSitemapSplitter(input, 1, WithOutputDir(out), WithVersionedPublish(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, loc := range []string{"https://example.com/a", "https://example.com/b", "https://example.com/c"} {
+		writeSitemap(t, input, loc)
+		if err := s.Split(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	releases, err := stampedDirs(filepath.Join(out, releasesDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(releases) != 2 {
+		t.Errorf("kept %d releases, want 2", len(releases))
+	}
+	target, err := os.Readlink(filepath.Join(out, currentLink))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(target) != releases[len(releases)-1] {
+		t.Errorf("current points at %s, want the newest release %s", target, releases[len(releases)-1])
+	}
+
+	// A failed run neither publishes nor leaves a release behind
+	writeSitemap(t, input)
+	if err := s.Split(); err == nil {
+		t.Fatal("run over an empty sitemap succeeded")
+	}
+	after, err := stampedDirs(filepath.Join(out, releasesDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != len(releases) {
+		t.Errorf("failed run left %d releases, want %d", len(after), len(releases))
+	}
+	if !fileContains(filepath.Join(out, currentLink, "in-1.xml"), "https://example.com/c") {
+		t.Error("failed run changed the current release")
+	}
+}
+
+func TestFailedPublishKeepsState(t *testing.T) {
+	dir := t.TempDir()
+	input, out := filepath.Join(dir, "in.xml"), filepath.Join(dir, "out")
+	history := filepath.Join(dir, "history")
+	s, err := NewSitemapSplitter(input, 1, WithOutputDir(out), WithVersionedPublish(2),
+		WithStateFile(filepath.Join(dir, "state.json")), WithSkipUnchanged(), WithHistory(history, 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeSitemap(t, input, "https://example.com/a", "https://example.com/b")
+
+	// A non-empty directory in place of the current symlink fails the flip
+	blocker := filepath.Join(out, currentLink, "keep")
+	if err := os.MkdirAll(blocker, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Split(); err == nil {
+		t.Fatal("publishing over a directory succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "state.json")); !os.IsNotExist(err) {
+		t.Errorf("failed publish saved the run state: %v", err)
+	}
+	if entries, _ := os.ReadDir(history); len(entries) != 0 {
+		t.Errorf("failed publish recorded %d history entries", len(entries))
+	}
+	if releases, _ := os.ReadDir(filepath.Join(out, releasesDir)); len(releases) != 0 {
+		t.Errorf("failed publish left %d releases behind", len(releases))
+	}
+
+	if err := os.RemoveAll(filepath.Join(out, currentLink)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Split(); err != nil {
+		t.Fatal(err)
+	}
+	if s.LastResult().UpToDate {
+		t.Error("run after a failed publish was skipped as unchanged")
+	}
+	if _, err := os.Stat(filepath.Join(out, currentLink, "in-2.xml")); err != nil {
+		t.Errorf("retried run did not publish: %v", err)
+	}
+}
+
+func TestVersionedPublishPruneKeepsForeignDirs(t *testing.T) {
+	dir := t.TempDir()
+	input, out := filepath.Join(dir, "in.xml"), filepath.Join(dir, "out")
+	foreign := filepath.Join(out, releasesDir, "0-pinned")
+	if err := os.MkdirAll(foreign, 0755); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewSitemapSplitter(input, 1, WithOutputDir(out), WithVersionedPublish(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, loc := range []string{"https://example.com/a", "https://example.com/b", "https://example.com/c"} {
+		writeSitemap(t, input, loc)
+		if err := s.Split(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(foreign); err != nil {
+		t.Errorf("pruning removed a directory that is not a release: %v", err)
+	}
+	releases, err := stampedDirs(filepath.Join(out, releasesDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(releases) != 1 {
+		t.Errorf("kept %d releases, want 1", len(releases))
+	}
+}
@@ -0,0 +1,83 @@
+package sitemapsplitter
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// CachePurge asks a CDN to drop its cached copies of the files a run stored,
+// through a generic HTTP purge API such as Cloudflare's or Fastly's
+type CachePurge struct {
+	URL     string            // Template of the purge request URL, executed with PurgeData for every file
+	Method  string            // Method of the purge requests, POST when empty
+	Headers map[string]string // Headers added to every purge request, e.g. an API token
+}
+
+// PurgeData is the data the purge URL template is executed with
+type PurgeData struct {
+	Name    string // Name the file was handed to the sink under, e.g. "sitemap-1.xml.gz"
+	BaseURL string // Configured index base URL, empty without one
+}
+
+// purgeCache sends a purge request for every file stored by the run, in
+// name order. Files left alone by differential writes are still cached
+// correctly and are not purged.
+func (s *SitemapSplitter) purgeCache() error {
+	names := make([]string, 0, len(s.written))
+	for name := range s.written {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		var b strings.Builder
+		if err := s.purgeTemplate.Execute(&b, PurgeData{Name: name, BaseURL: s.baseURL}); err != nil {
+			return fmt.Errorf("error expanding purge URL template: %v", err)
+		}
+		if err := s.sendPurge(b.String()); err != nil {
+			return err
+		}
+	}
+	s.logger.Info("purged cached files", "phase", "upload", "files", len(names))
+	return nil
+}
+
+// sendPurge sends the purge request to target
+func (s *SitemapSplitter) sendPurge(target string) error {
+	method := s.purge.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequest(method, target, nil)
+	if err != nil {
+		return fmt.Errorf("error purging cache: %v", err)
+	}
+	for key, value := range s.purge.Headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := s.doRequest(req)
+	if err != nil {
+		return fmt.Errorf("error purging cache: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("error purging cache at %s: %s", req.URL.Redacted(), resp.Status)
+	}
+	return nil
+}
+
+// parsePurgeTemplate parses the URL template of purge
+func parsePurgeTemplate(purge *CachePurge) (*template.Template, error) {
+	if purge.URL == "" {
+		return nil, fmt.Errorf("cache purge URL must not be empty")
+	}
+	tmpl, err := template.New("purge URL").Parse(purge.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cache purge URL template: %v", err)
+	}
+	return tmpl, nil
+}
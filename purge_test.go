@@ -0,0 +1,127 @@
+package sitemapsplitter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestCachePurge(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		purged []string
+		status = http.StatusOK
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method != "PURGE" || r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("purge sent as %s with authorization %q", r.Method, r.Header.Get("Authorization"))
+		}
+		purged = append(purged, r.URL.Query().Get("url"))
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+	purge := CachePurge{
+		URL:     srv.URL + "/purge?url={{.BaseURL}}{{.Name}}",
+		Method:  "PURGE",
+		Headers: map[string]string{"Authorization": "Bearer token"},
+	}
+
+	all := []string{"https://example.com/in-1.xml", "https://example.com/in-2.xml", "https://example.com/sitemap-index.xml"}
+	tests := []struct {
+		name    string
+		opts    []Option
+		runs    int      // Splits of the same input, the last one checked
+		status  int      // Status of the purge API
+		want    []string // Purged URLs of the last run
+		wantErr bool
+	}{
+		{"every file", nil, 1, http.StatusOK, all, false},
+		{"unchanged files", []Option{WithDifferentialWrites()}, 2, http.StatusOK, nil, false},
+		{"dry run", []Option{WithDryRun(true)}, 1, http.StatusOK, nil, false},
+		{"failing API", nil, 1, http.StatusForbidden, all[:1], true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			input := filepath.Join(dir, "in.xml")
+			writeSitemap(t, input, "https://example.com/a", "https://example.com/b")
+			opts := append([]Option{WithIndexBaseURL("https://example.com/"), WithStateFile(filepath.Join(dir, "state.json")), WithCachePurge(purge)}, tt.opts...)
+			s, err := NewSitemapSplitter(input, 1, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for run := 1; run <= tt.runs; run++ {
+				mu.Lock()
+				purged, status = nil, tt.status
+				mu.Unlock()
+				err = s.Split()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if !slices.Equal(purged, tt.want) {
+				t.Errorf("purged %v, want %v", purged, tt.want)
+			}
+		})
+	}
+}
+
+func TestCachePurgeTemplate(t *testing.T) {
+	for _, tmpl := range []string{"", "https://cdn.example/{{.Name"} {
+		if _, err := NewSitemapSplitter("in.xml", 1, WithCachePurge(CachePurge{URL: tmpl})); err == nil {
+			t.Errorf("purge URL template %q was accepted", tmpl)
+		}
+	}
+}
+
+func TestCachePurgeRetry(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		purged []string
+		status = http.StatusForbidden
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		purged = append(purged, r.URL.Query().Get("url"))
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.xml")
+	writeSitemap(t, input, "https://example.com/a")
+	s, err := NewSitemapSplitter(input, 1, WithIndexBaseURL("https://example.com/"), WithStateFile(filepath.Join(dir, "state.json")),
+		WithSkipUnchanged(), WithCachePurge(CachePurge{URL: srv.URL + "/purge?url={{.BaseURL}}{{.Name}}"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Split(); err == nil {
+		t.Fatal("run with a failing purge succeeded")
+	}
+
+	// The failed purge kept the run from being recorded, so the retry is not
+	// skipped as unchanged and purges again
+	mu.Lock()
+	purged, status = nil, http.StatusOK
+	mu.Unlock()
+	if err := s.Split(); err != nil {
+		t.Fatal(err)
+	}
+	if s.LastResult().UpToDate {
+		t.Error("retry after a failed purge was skipped as unchanged")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"https://example.com/in-1.xml", "https://example.com/sitemap-index.xml"}; !slices.Equal(purged, want) {
+		t.Errorf("retry purged %v, want %v", purged, want)
+	}
+}
@@ -0,0 +1,62 @@
+package sitemapsplitter
+
+import (
+	"net/url"
+	"strings"
+)
+
+// RedactRule strips or masks a query parameter in loc values
+type RedactRule struct {
+	Param string // Query parameter name, matched case-insensitively
+	Mask  string // Replacement value; empty removes the parameter entirely
+}
+
+// redact applies the redaction rules to u, reporting whether loc changed
+func (s *SitemapSplitter) redact(u *URL) bool {
+	if len(s.redactRules) == 0 {
+		return false
+	}
+
+	parsed, err := url.Parse(u.Loc)
+	if err != nil || parsed.RawQuery == "" {
+		return false
+	}
+
+	changed := false
+	pairs := strings.Split(parsed.RawQuery, "&")
+	kept := pairs[:0]
+	for _, pair := range pairs {
+		rawKey, _, _ := strings.Cut(pair, "=")
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			key = rawKey
+		}
+
+		rule, ok := s.redactRule(key)
+		if !ok {
+			kept = append(kept, pair)
+			continue
+		}
+		changed = true
+		if rule.Mask != "" {
+			kept = append(kept, rawKey+"="+url.QueryEscape(rule.Mask))
+		}
+	}
+	if !changed {
+		return false
+	}
+
+	parsed.RawQuery = strings.Join(kept, "&")
+	u.Loc = parsed.String()
+	return true
+}
+
+// redactRule returns the rule matching the query parameter key
+func (s *SitemapSplitter) redactRule(key string) (RedactRule, bool) {
+	for _, rule := range s.redactRules {
+		if strings.EqualFold(rule.Param, key) {
+			return rule, true
+		}
+	}
+	return RedactRule{}, false
+}
@@ -0,0 +1,54 @@
+package sitemapsplitter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	rules := []RedactRule{{Param: "token"}, {Param: "email", Mask: "x@y"}}
+	tests := []struct {
+		name    string
+		loc     string
+		want    string
+		changed bool
+	}{
+		{"no query", "https://example.com/a", "https://example.com/a", false},
+		{"other parameters", "https://example.com/a?page=2", "https://example.com/a?page=2", false},
+		{"strip", "https://example.com/a?token=s3cret&page=2", "https://example.com/a?page=2", true},
+		{"strip only parameter", "https://example.com/a?token=s3cret", "https://example.com/a", true},
+		{"case-insensitive", "https://example.com/a?Token=s3cret&page=2", "https://example.com/a?page=2", true},
+		{"escaped name", "https://example.com/a?%74oken=s3cret", "https://example.com/a", true},
+		{"mask", "https://example.com/a?email=me%40example.com&page=2", "https://example.com/a?email=x%40y&page=2", true},
+		{"repeated", "https://example.com/a?token=1&page=2&token=3", "https://example.com/a?page=2", true},
+	}
+	s, err := NewSitemapSplitter("in.xml", 50000, WithRedaction(rules...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := URL{Loc: tt.loc}
+			if changed := s.redact(&u); changed != tt.changed {
+				t.Errorf("redact reported change %v, want %v", changed, tt.changed)
+			}
+			if u.Loc != tt.want {
+				t.Errorf("redacted %s to %s, want %s", tt.loc, u.Loc, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactedURLsCount(t *testing.T) {
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a?token=1</loc></url><url><loc>https://example.com/b</loc></url><url><loc>https://example.com/c?TOKEN=2&amp;page=2</loc></url></urlset>`
+	s, err := NewSitemapSplitter("in.xml", 50000, WithSink(NewMemorySink()), WithRedaction(RedactRule{Param: "token"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.LastResult().RedactedURLs; got != 2 {
+		t.Errorf("result reports %d redacted URLs, want 2", got)
+	}
+}
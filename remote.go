@@ -0,0 +1,69 @@
+package sitemapsplitter
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// isRemote reports whether the input path is an http or https URL
+func isRemote(p string) bool {
+	lower := strings.ToLower(p)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// remoteName returns the path component of a remote input, which names the
+// chunks like a local filename would. URLs without a path yield "sitemap".
+func remoteName(p string) string {
+	parsed, err := url.Parse(p)
+	if err != nil {
+		return p
+	}
+	if strings.Trim(parsed.Path, "/") == "" {
+		return "sitemap"
+	}
+	return parsed.Path
+}
+
+// splitRemote downloads the input sitemap and splits the response body.
+// Gzip content encoding is undone by the HTTP client and gzipped files are
+// detected like local ones. The Last-Modified header, if any, serves as the
+// source modification time.
+func (s *SitemapSplitter) splitRemote() error {
+	body, modTime, err := s.fetchInput()
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	return s.splitReader(body, modTime)
+}
+
+// fetchInput downloads the input sitemap, returning the response body and
+// the time of its Last-Modified header, if any
+func (s *SitemapSplitter) fetchInput() (io.ReadCloser, time.Time, error) {
+	if archiveSuffix(remoteName(s.path)) != "" {
+		return nil, time.Time{}, fmt.Errorf("sitemap archives cannot be read over HTTP")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, s.path, nil)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("error fetching sitemap: %v", err)
+	}
+	resp, err := s.doRequest(req)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("error fetching sitemap: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, time.Time{}, fmt.Errorf("error fetching sitemap %s: %s", s.path, resp.Status)
+	}
+
+	var modTime time.Time
+	if header := resp.Header.Get("Last-Modified"); header != "" {
+		modTime, _ = http.ParseTime(header)
+	}
+	return resp.Body, modTime, nil
+}
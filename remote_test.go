@@ -0,0 +1,86 @@
+package sitemapsplitter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRemoteName(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://example.com/sitemaps/news.xml", "news"},
+		{"https://example.com/sitemap.xml.gz?v=2", "sitemap"},
+		{"https://example.com/", "sitemap"},
+		{"https://example.com", "sitemap"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := inputBaseName(tt.url); got != tt.want {
+				t.Errorf("inputBaseName(%q) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoteInput(t *testing.T) {
+	const in = `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc></url></urlset>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/", "/sitemaps/news.xml":
+			w.Write([]byte(in))
+		case "/sitemap.xml.gz":
+			w.Write(gzipped(t, in))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name      string
+		path      string
+		want      string // Chunk written, empty if the split fails
+		streaming bool
+	}{
+		{"plain", "/sitemaps/news.xml", "news-1.xml", false},
+		{"gzipped", "/sitemap.xml.gz", "sitemap-1.xml", false},
+		{"root", "/", "sitemap-1.xml", false},
+		{"streaming", "/sitemaps/news.xml", "news-1.xml", true},
+		{"not found", "/missing.xml", "", false},
+		{"archive", "/sitemaps.zip", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			opts := []Option{WithOutputDir(dir)}
+			if tt.streaming {
+				opts = append(opts, WithStreaming())
+			}
+			s, err := NewSitemapSplitter(srv.URL+tt.path, 10, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = s.Split()
+			if tt.want == "" {
+				if err == nil {
+					t.Error("split succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !fileContains(filepath.Join(dir, tt.want), "<loc>https://example.com/a</loc>") {
+				t.Errorf("%s lacks the input's URL", tt.want)
+			}
+			if !fileContains(filepath.Join(dir, "sitemap-index.xml"), strings.TrimSuffix(tt.want, ".xml")) {
+				t.Errorf("index does not list %s", tt.want)
+			}
+		})
+	}
+}
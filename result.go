@@ -0,0 +1,206 @@
+package sitemapsplitter
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Result reports what the most recent Split did
+type Result struct {
+	UpToDate     bool `json:"up_to_date,omitempty"`    // Input and options were unchanged since the last run, so nothing was written
+	CountAlerted bool `json:"count_alerted,omitempty"` // The URL count changed by more than the count alert allows
+	DryRun       bool `json:"dry_run,omitempty"`       // Nothing was written, the files describe what would have been
+
+	NormalizedURLs        int `json:"normalized_urls"`         // URLs whose loc was changed by normalization
+	CanonicalHostRewrites int `json:"canonical_host_rewrites"` // URLs whose host was rewritten to the canonical host
+	RedactedURLs          int `json:"redacted_urls"`           // URLs whose loc had query parameters stripped or masked
+	FragmentsStripped     int `json:"fragments_stripped"`      // URLs whose loc had a #fragment removed
+	LastModBackfilled     int `json:"lastmod_backfilled"`      // URLs whose missing lastmod was filled in by a backfill rule
+	UnchangedFiles        int `json:"unchanged_files"`         // Chunks and index not written because they match the previous run
+
+	URLs  int          `json:"urls"`            // URLs written to chunks
+	Files []OutputFile `json:"files,omitempty"` // Chunk files in index order, every variant of a chunk listed
+	Index *OutputFile  `json:"index,omitempty"` // Sitemap index, nil with WithoutIndex
+	News  *OutputFile  `json:"news,omitempty"`  // News sitemap, nil without WithNewsSitemap
+
+	Hosts    map[string]int `json:"hosts,omitempty"`    // Output URLs per host, see OtherKey
+	Sections map[string]int `json:"sections,omitempty"` // Output URLs per first path segment, e.g. "/blog", see OtherKey
+
+	Skipped      map[SkipReason]int      `json:"skipped,omitempty"`       // Dropped URLs per reason
+	SkipExamples map[SkipReason][]string `json:"skip_examples,omitempty"` // Sampled locs of dropped URLs per reason, see WithSkipExamples
+
+	WarningCounts map[WarningCode]int `json:"warning_counts,omitempty"` // Data-quality warnings per code
+	Warnings      []Warning           `json:"warnings,omitempty"`       // First warnings with their loc, see WithMaxWarnings
+}
+
+// OutputFile describes a file produced by a Split
+type OutputFile struct {
+	Name      string `json:"name"`                // Name handed to the sink, slash separated
+	Path      string `json:"path,omitempty"`      // Path on disk when the default file sink is used
+	URLs      int    `json:"urls,omitempty"`      // URLs in the file
+	Bytes     int64  `json:"bytes"`               // Size as stored, after compression and encryption, 0 if unchanged
+	Unchanged bool   `json:"unchanged,omitempty"` // Not written because it matches the previous run
+}
+
+// outputFile returns the description of the file name, which holds urls
+func (s *SitemapSplitter) outputFile(name string, urls int, unchanged bool) OutputFile {
+	f := OutputFile{Name: s.sinkName(name), URLs: urls, Unchanged: unchanged}
+	f.Bytes = s.written[f.Name]
+	if fs, ok := s.sink.(*FileSink); ok {
+		f.Path = fs.path(f.Name)
+	}
+	return f
+}
+
+// WarningCode identifies a data-quality problem found in an entry. Codes are
+// stable so they can be trended across runs.
+type WarningCode string
+
+const (
+	// WarnLastModNormalized marks lastmod values rewritten to the configured
+	// lastmod format or timezone
+	WarnLastModNormalized WarningCode = "W_LASTMOD_NORMALIZED"
+	// WarnLastModUnparsable marks lastmod values that could not be parsed and
+	// were passed through as is
+	WarnLastModUnparsable WarningCode = "W_LASTMOD_UNPARSABLE"
+	// WarnDuplicateDropped marks URLs dropped because their loc was already
+	// seen
+	WarnDuplicateDropped WarningCode = "W_DUPLICATE_DROPPED"
+	// WarnNewsStale marks news entries published longer ago than
+	// WithNewsMaxAge allows
+	WarnNewsStale WarningCode = "W_NEWS_STALE"
+	// WarnDuplicateConflict marks duplicate entries whose lastmod, changefreq
+	// or priority disagree with the entry kept for their loc, see
+	// WithDedupPolicy
+	WarnDuplicateConflict WarningCode = "W_DUPLICATE_CONFLICT"
+	// WarnHreflangInvalid marks URLs with an hreflang alternate whose
+	// language tag is not well-formed BCP 47, see WithHreflangValidation
+	WarnHreflangInvalid WarningCode = "W_HREFLANG_INVALID"
+	// WarnHreflangNoReturn marks URLs with an hreflang alternate whose
+	// target is in the input but does not link back, see
+	// WithHreflangValidation
+	WarnHreflangNoReturn WarningCode = "W_HREFLANG_NO_RETURN"
+)
+
+// Warning is a data-quality problem found in the entry with the given loc
+type Warning struct {
+	Code WarningCode `json:"code"`
+	Loc  string      `json:"loc"`
+}
+
+// SkipReason identifies why a URL was dropped from the output
+type SkipReason string
+
+const (
+	// SkipSampled marks URLs left out by sampling
+	SkipSampled SkipReason = "sampled_out"
+	// SkipRobots marks URLs disallowed by robots.txt
+	SkipRobots SkipReason = "robots_disallowed"
+	// SkipDuplicate marks URLs whose loc was already seen
+	SkipDuplicate SkipReason = "duplicate"
+	// SkipInvalid marks URLs that are not valid sitemap entries, see
+	// WithMaxErrorRate
+	SkipInvalid SkipReason = "invalid"
+	// SkipExcluded marks URLs listed in the exclude file, see
+	// WithExcludeFile
+	SkipExcluded SkipReason = "excluded"
+	// SkipNotAllowed marks URLs matching no allowlisted prefix, see
+	// WithAllowPrefixes
+	SkipNotAllowed SkipReason = "not_allowed"
+	// SkipPatternExcluded marks URLs matching an exclude pattern, see
+	// WithExclude
+	SkipPatternExcluded SkipReason = "pattern_excluded"
+	// SkipNotIncluded marks URLs matching no include pattern, see
+	// WithInclude
+	SkipNotIncluded SkipReason = "not_included"
+	// SkipLastModRange marks URLs whose lastmod is missing or outside the
+	// range, see WithLastModRange and WithLastModWindow
+	SkipLastModRange SkipReason = "lastmod_out_of_range"
+)
+
+// Dropped returns the total number of URLs dropped for any reason
+func (r *Result) Dropped() int {
+	total := 0
+	for _, n := range r.Skipped {
+		total += n
+	}
+	return total
+}
+
+// LastResult returns the report of the most recent Split, including the
+// files it produced, or nil if Split has not been called yet
+func (s *SitemapSplitter) LastResult() *Result {
+	return s.result
+}
+
+// OtherKey is the key of Result.Hosts and Result.Sections counting the URLs
+// of every key seen after the first 1,000, which keeps the result small for
+// inputs with a distinct host or section per URL
+const OtherKey = "(other)"
+
+// distributionKeys is the most keys Hosts and Sections hold besides OtherKey
+const distributionKeys = 1000
+
+// KeyCount is a key of a distribution together with its number of URLs
+type KeyCount struct {
+	Key   string
+	Count int
+}
+
+// TopHosts returns the n hosts with the most URLs, most frequent first
+func (r *Result) TopHosts(n int) []KeyCount {
+	return topCounts(r.Hosts, n)
+}
+
+// TopSections returns the n first path segments with the most URLs, most
+// frequent first
+func (r *Result) TopSections(n int) []KeyCount {
+	return topCounts(r.Sections, n)
+}
+
+// topCounts sorts counts descending, breaking ties by key, and returns the
+// first n entries
+func topCounts(counts map[string]int, n int) []KeyCount {
+	top := make([]KeyCount, 0, len(counts))
+	for key, count := range counts {
+		top = append(top, KeyCount{Key: key, Count: count})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Count != top[j].Count {
+			return top[i].Count > top[j].Count
+		}
+		return top[i].Key < top[j].Key
+	})
+	if n >= 0 && n < len(top) {
+		top = top[:n]
+	}
+	return top
+}
+
+// countDistribution tallies the output URLs per host and per first path
+// segment
+func (r *Result) countDistribution(urls []URL) {
+	for _, u := range urls {
+		parsed, err := url.Parse(u.Loc)
+		if err != nil {
+			continue
+		}
+		tally(r.Hosts, parsed.Host)
+
+		section := "/"
+		if segment, _, _ := strings.Cut(strings.TrimPrefix(parsed.Path, "/"), "/"); segment != "" {
+			section = "/" + segment
+		}
+		tally(r.Sections, section)
+	}
+}
+
+// tally counts a URL for key, or for OtherKey once counts holds
+// distributionKeys other keys
+func tally(counts map[string]int, key string) {
+	if _, ok := counts[key]; !ok && len(counts) >= distributionKeys {
+		key = OtherKey
+	}
+	counts[key]++
+}
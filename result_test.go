@@ -0,0 +1,153 @@
+package sitemapsplitter
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCountDistribution(t *testing.T) {
+	tests := []struct {
+		name      string
+		hosts     int // Distinct hosts, each with one URL per section
+		sections  int // Distinct first path segments per host
+		wantHosts int // Keys of Hosts, OtherKey included
+		wantOther int // URLs counted under OtherKey in Hosts
+	}{
+		{"few keys", 3, 2, 3, 0},
+		{"at the limit", distributionKeys, 1, distributionKeys, 0},
+		{"beyond the limit", distributionKeys + 5, 2, distributionKeys + 1, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var urls []URL
+			for h := range tt.hosts {
+				for p := range tt.sections {
+					urls = append(urls, URL{Loc: fmt.Sprintf("https://host-%d.example.com/s%d-%d/page", h, h, p)})
+				}
+			}
+			r := &Result{Hosts: make(map[string]int), Sections: make(map[string]int)}
+			r.countDistribution(urls)
+
+			if len(r.Hosts) != tt.wantHosts || r.Hosts[OtherKey] != tt.wantOther {
+				t.Errorf("got %d hosts with %d other URLs, want %d with %d", len(r.Hosts), r.Hosts[OtherKey], tt.wantHosts, tt.wantOther)
+			}
+			if len(r.Sections) > distributionKeys+1 {
+				t.Errorf("got %d sections, want at most %d", len(r.Sections), distributionKeys+1)
+			}
+			for name, counts := range map[string]map[string]int{"hosts": r.Hosts, "sections": r.Sections} {
+				total := 0
+				for _, n := range counts {
+					total += n
+				}
+				if total != len(urls) {
+					t.Errorf("%s count %d URLs, want %d", name, total, len(urls))
+				}
+			}
+			if top := r.TopHosts(1); tt.wantOther > 0 && top[0].Key != OtherKey {
+				t.Errorf("top host is %v, want the other bucket", top[0])
+			}
+		})
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` +
+		`<url><loc>https://example.com/a</loc><lastmod>2024-05-01T10:00:00Z</lastmod></url>` +
+		`<url><loc>https://example.com/b</loc><lastmod>yesterday</lastmod></url>` +
+		`<url><loc>https://example.com/c</loc><lastmod>2024-05-01</lastmod></url>` +
+		`<url><loc>https://example.com/a</loc></url>` +
+		`</urlset>`
+	tests := []struct {
+		name        string
+		maxWarnings int
+		want        []Warning // Filtering warnings come before those of writing
+	}{
+		{"none kept", 0, nil},
+		{"first kept", 2, []Warning{{WarnDuplicateDropped, "https://example.com/a"}, {WarnLastModNormalized, "https://example.com/a"}}},
+		{"all kept", 10, []Warning{{WarnDuplicateDropped, "https://example.com/a"}, {WarnLastModNormalized, "https://example.com/a"}, {WarnLastModUnparsable, "https://example.com/b"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSitemapSplitter("in.xml", 10, WithSink(NewMemorySink()), WithDeduplicate(true),
+				WithLastModFormat(LastModDate), WithMaxWarnings(tt.maxWarnings))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+				t.Fatal(err)
+			}
+
+			r := s.LastResult()
+			wantCounts := map[WarningCode]int{WarnLastModNormalized: 1, WarnLastModUnparsable: 1, WarnDuplicateDropped: 1}
+			if !maps.Equal(r.WarningCounts, wantCounts) {
+				t.Errorf("warning counts %v, want %v", r.WarningCounts, wantCounts)
+			}
+			if !slices.Equal(r.Warnings, tt.want) {
+				t.Errorf("warnings %v, want %v", r.Warnings, tt.want)
+			}
+		})
+	}
+}
+
+func TestResult(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []Option
+		fileSink  bool
+		wantFiles []OutputFile // Bytes only checked for being positive
+		wantIndex string       // Name of the index, empty without one
+	}{
+		{"memory sink", nil, false, []OutputFile{{Name: "in-1.xml", URLs: 2}, {Name: "in-2.xml", URLs: 1}}, "sitemap-index.xml"},
+		{"file sink", nil, true, []OutputFile{{Name: "in-1.xml", URLs: 2}, {Name: "in-2.xml", URLs: 1}}, "sitemap-index.xml"},
+		{"dual output", []Option{WithDualOutput(true)}, false, []OutputFile{
+			{Name: "in-1.xml", URLs: 2}, {Name: "in-1.xml.gz", URLs: 2}, {Name: "in-2.xml", URLs: 1}, {Name: "in-2.xml.gz", URLs: 1}}, "sitemap-index.xml"},
+		{"without index", []Option{WithoutIndex()}, false, []OutputFile{{Name: "in-1.xml", URLs: 2}, {Name: "in-2.xml", URLs: 1}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			opts := append([]Option{WithMaxErrorRate(50)}, tt.opts...)
+			if tt.fileSink {
+				opts = append(opts, WithOutputDir(dir))
+			} else {
+				opts = append(opts, WithSink(NewMemorySink()))
+			}
+			s, err := NewSitemapSplitter(filepath.Join(dir, "in.xml"), 2, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc></url>` +
+				`<url><loc>https://example.com/b</loc></url><url><loc>https://example.com/c</loc></url><url><loc>not a url</loc></url></urlset>`
+			if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+				t.Fatal(err)
+			}
+
+			r := s.LastResult()
+			if r.URLs != 3 || r.Skipped[SkipInvalid] != 1 {
+				t.Errorf("result counts %d URLs and %d invalid, want 3 and 1", r.URLs, r.Skipped[SkipInvalid])
+			}
+			if len(r.Files) != len(tt.wantFiles) {
+				t.Fatalf("result lists %v, want %v", r.Files, tt.wantFiles)
+			}
+			for i, f := range r.Files {
+				want := tt.wantFiles[i]
+				if tt.fileSink {
+					want.Path = filepath.Join(dir, want.Name)
+				}
+				if f.Name != want.Name || f.URLs != want.URLs || f.Path != want.Path || f.Bytes <= 0 {
+					t.Errorf("file %d is %+v, want %+v", i, f, want)
+				}
+			}
+			switch {
+			case tt.wantIndex == "" && r.Index != nil:
+				t.Errorf("result lists index %+v", r.Index)
+			case tt.wantIndex != "" && (r.Index == nil || r.Index.Name != tt.wantIndex || r.Index.Bytes <= 0):
+				t.Errorf("result lists index %+v, want %s", r.Index, tt.wantIndex)
+			}
+		})
+	}
+}
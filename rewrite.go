@@ -0,0 +1,78 @@
+package sitemapsplitter
+
+import (
+	"net/url"
+	"strings"
+)
+
+// rewrite applies every configured loc rewrite to u and to the hrefs of its
+// alternates, so that alternates keep pointing at the rewritten locs, and
+// records what changed in u's loc
+func (s *SitemapSplitter) rewrite(u *URL) {
+	old := u.Loc
+	normalized, canonical, redacted, stripped := s.rewriteLoc(u)
+	if normalized {
+		s.result.NormalizedURLs++
+	}
+	if canonical {
+		s.result.CanonicalHostRewrites++
+		s.logger.Debug("rewrote host", "phase", "filter", "from", old, "to", u.Loc)
+	}
+	if redacted {
+		s.result.RedactedURLs++
+	}
+	if stripped {
+		s.result.FragmentsStripped++
+	}
+
+	for i, ext := range u.Extensions {
+		alt, ok := ext.Value.(Alternate)
+		if !ok {
+			continue
+		}
+		href := URL{Loc: alt.Href}
+		s.rewriteLoc(&href)
+		alt.Href = href.Loc
+		u.Extensions[i].Value = alt
+	}
+}
+
+// rewriteLoc applies every configured loc rewrite to u's loc, reporting which
+// of them changed it
+func (s *SitemapSplitter) rewriteLoc(u *URL) (normalized, canonical, redacted, stripped bool) {
+	normalized = s.normalizeURLs && normalizeLoc(u)
+	canonical = s.canonicalizeHost(u)
+	redacted = s.redact(u)
+	stripped = s.noFragments && stripFragment(u)
+	if s.lowerPaths {
+		lowercasePath(u)
+	}
+	return normalized, canonical, redacted, stripped
+}
+
+// stripFragment removes the #fragment from loc, reporting whether it had one
+func stripFragment(u *URL) bool {
+	loc, _, found := strings.Cut(u.Loc, "#")
+	if !found {
+		return false
+	}
+	u.Loc = loc
+	return true
+}
+
+// lowercasePath lowercases the path of loc, leaving scheme, host and query
+// untouched
+func lowercasePath(u *URL) {
+	parsed, err := url.Parse(u.Loc)
+	if err != nil {
+		return
+	}
+
+	lower := strings.ToLower(parsed.Path)
+	if lower == parsed.Path {
+		return
+	}
+	parsed.Path = lower
+	parsed.RawPath = strings.ToLower(parsed.RawPath)
+	u.Loc = parsed.String()
+}
@@ -0,0 +1,87 @@
+package sitemapsplitter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStripFragments(t *testing.T) {
+	tests := []struct {
+		loc  string
+		want string
+	}{
+		{"https://example.com/a", "https://example.com/a"},
+		{"https://example.com/a#top", "https://example.com/a"},
+		{"https://example.com/a?page=2#top", "https://example.com/a?page=2"},
+		{"https://example.com/#!/app/route", "https://example.com/"},
+		{"https://example.com/a#", "https://example.com/a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.loc, func(t *testing.T) {
+			u := URL{Loc: tt.loc}
+			if stripped := stripFragment(&u); stripped != strings.Contains(tt.loc, "#") {
+				t.Errorf("stripFragment reported %v", stripped)
+			}
+			if u.Loc != tt.want {
+				t.Errorf("stripped to %s, want %s", u.Loc, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripFragmentsBeforeDedup(t *testing.T) {
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a#intro</loc></url><url><loc>https://example.com/a</loc></url><url><loc>https://example.com/b#x</loc></url></urlset>`
+	s, err := NewSitemapSplitter("in.xml", 50000, WithSink(NewMemorySink()), WithStripFragments(), WithDeduplicate(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	r := s.LastResult()
+	if r.URLs != 2 || r.FragmentsStripped != 2 || r.Skipped[SkipDuplicate] != 1 {
+		t.Errorf("wrote %d URLs with %d fragments stripped and %d duplicates, want 2, 2 and 1", r.URLs, r.FragmentsStripped, r.Skipped[SkipDuplicate])
+	}
+}
+
+func TestLowercasePaths(t *testing.T) {
+	tests := []struct {
+		loc  string
+		want string
+	}{
+		{"https://example.com/about", "https://example.com/about"},
+		{"https://example.com/About/Team", "https://example.com/about/team"},
+		{"https://Example.com/About?Ref=Home", "https://Example.com/about?Ref=Home"},
+		{"https://example.com/Caf%C3%A9", "https://example.com/caf%C3%A9"},
+		{"https://example.com/A%2FB", "https://example.com/a%2fb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.loc, func(t *testing.T) {
+			u := URL{Loc: tt.loc}
+			lowercasePath(&u)
+			if u.Loc != tt.want {
+				t.Errorf("lowercased to %s, want %s", u.Loc, tt.want)
+			}
+		})
+	}
+}
+
+func TestLowercasePathsOption(t *testing.T) {
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/About</loc></url><url><loc>https://example.com/about</loc></url></urlset>`
+	tests := []struct {
+		name string
+		opts []Option
+		want int
+	}{
+		{"preserved by default", nil, 2},
+		{"lowercased", []Option{WithLowercasePaths()}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := splitString(t, in, append(tt.opts, WithDeduplicate(true))...)
+			if got := strings.Count(out, "<loc>"); got != tt.want {
+				t.Errorf("wrote %d URLs, want %d:\n%s", got, tt.want, out)
+			}
+		})
+	}
+}
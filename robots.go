@@ -0,0 +1,211 @@
+package sitemapsplitter
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// robotsRule is a single Allow or Disallow line of a robots.txt group
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// robotsRules holds the rules that apply to the configured user-agent
+type robotsRules []robotsRule
+
+// parseRobots extracts the rules of the group that best matches userAgent,
+// falling back to the "*" group
+func parseRobots(r io.Reader, userAgent string) (robotsRules, error) {
+	agent := strings.ToLower(userAgent)
+
+	groups := make(map[string]robotsRules)
+	var current []string
+	inRules := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if inRules {
+				current = nil
+				inRules = false
+			}
+			name := strings.ToLower(value)
+			current = append(current, name)
+			if _, ok := groups[name]; !ok {
+				groups[name] = nil
+			}
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				continue
+			}
+			for _, name := range current {
+				groups[name] = append(groups[name], robotsRule{allow: key == "allow", pattern: value})
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	best := "*"
+	for name := range groups {
+		if name == "*" || !strings.Contains(agent, name) {
+			continue
+		}
+		if best == "*" || len(name) > len(best) {
+			best = name
+		}
+	}
+	return groups[best], nil
+}
+
+// allowed reports whether path is allowed. The longest matching pattern wins
+// and Allow wins ties.
+func (rules robotsRules) allowed(path string) bool {
+	allow, best := true, -1
+	for _, rule := range rules {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		n := len(rule.pattern)
+		if n > best || (n == best && rule.allow) {
+			allow, best = rule.allow, n
+		}
+	}
+	return allow
+}
+
+// robotsMatch matches path against a robots.txt pattern supporting the *
+// wildcard and the $ end anchor
+func robotsMatch(pattern, path string) bool {
+	if strings.HasSuffix(pattern, "$") {
+		pattern = pattern[:len(pattern)-1]
+	} else {
+		pattern += "*"
+	}
+
+	// Iterative wildcard matching, backtracking to the last *
+	p, i := 0, 0
+	star, mark := -1, 0
+	for i < len(path) {
+		switch {
+		case p < len(pattern) && pattern[p] == '*':
+			star, mark = p, i
+			p++
+		case p < len(pattern) && pattern[p] == path[i]:
+			p++
+			i++
+		case star >= 0:
+			p = star + 1
+			mark++
+			i = mark
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// robotsAllowed reports whether loc may be included according to the site's
+// robots.txt. Rules are fetched once per host and cached for the run.
+func (s *SitemapSplitter) robotsAllowed(loc string, cache map[string]robotsRules) (bool, error) {
+	parsed, err := url.Parse(loc)
+	if err != nil || parsed.Host == "" {
+		return true, nil
+	}
+
+	origin := parsed.Scheme + "://" + parsed.Host
+	rules, ok := cache[origin]
+	if !ok {
+		rules, err = s.fetchRobots(origin)
+		if err != nil {
+			return false, err
+		}
+		cache[origin] = rules
+	}
+
+	path := parsed.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if parsed.RawQuery != "" {
+		path += "?" + parsed.RawQuery
+	}
+	return rules.allowed(path), nil
+}
+
+// fetchRobots downloads and parses origin's robots.txt, recording a digest of
+// it for the state file. A missing file (4xx) allows everything.
+func (s *SitemapSplitter) fetchRobots(origin string) (robotsRules, error) {
+	req, err := http.NewRequest(http.MethodGet, origin+"/robots.txt", nil)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching robots.txt: %v", err)
+	}
+	req.Header.Set("User-Agent", s.robotsAgent)
+
+	resp, err := s.doRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching robots.txt: %v", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		s.robotsSeen[origin] = resp.Status
+		return nil, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("error fetching robots.txt from %s: %s", origin, resp.Status)
+	}
+
+	h := sha256.New()
+	body := io.TeeReader(resp.Body, h)
+	rules, err := parseRobots(body, s.robotsAgent)
+	if err == nil {
+		_, err = io.Copy(io.Discard, body)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading robots.txt from %s: %v", origin, err)
+	}
+	s.robotsSeen[origin] = hex.EncodeToString(h.Sum(nil))
+	return rules, nil
+}
+
+// robotsUnchanged reports whether every robots.txt obeyed by the previous
+// run still has the content it had then
+func (s *SitemapSplitter) robotsUnchanged(prev *runState) (bool, error) {
+	if s.robotsAgent == "" {
+		return true, nil
+	}
+	for origin, digest := range prev.Robots {
+		if _, err := s.fetchRobots(origin); err != nil {
+			return false, err
+		}
+		if s.robotsSeen[origin] != digest {
+			return false, nil
+		}
+	}
+	return true, nil
+}
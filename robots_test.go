@@ -0,0 +1,128 @@
+package sitemapsplitter
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSkipUnchangedRechecksRobots(t *testing.T) {
+	robots := "User-agent: *\nDisallow:\n"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, robots)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.xml")
+	sitemap := fmt.Sprintf(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`+
+		`<url><loc>%[1]s/a</loc></url><url><loc>%[1]s/private/b</loc></url></urlset>`, srv.URL)
+	if err := os.WriteFile(input, []byte(sitemap), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewSitemapSplitter(input, 10, WithOutputDir(filepath.Join(dir, "out")),
+		WithStateFile(filepath.Join(dir, "state.json")), WithSkipUnchanged(), WithRobotsTxt("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		robots   string
+		upToDate bool
+		urls     int
+	}{
+		{robots, false, 2},
+		{robots, true, 0},
+		{"User-agent: *\nDisallow: /private/\n", false, 1},
+		{"User-agent: *\nDisallow: /private/\n", true, 0},
+	}
+	for i, step := range steps {
+		robots = step.robots
+		if err := s.Split(); err != nil {
+			t.Fatalf("run %d: %v", i+1, err)
+		}
+		if r := s.LastResult(); r.UpToDate != step.upToDate || r.URLs != step.urls {
+			t.Errorf("run %d: up to date %v with %d URLs, want %v with %d", i+1, r.UpToDate, r.URLs, step.upToDate, step.urls)
+		}
+	}
+}
+
+func TestRobotsMatch(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"/", "/anything", true},
+		{"/private", "/private/page", true},
+		{"/private", "/privacy", false},
+		{"/*.pdf", "/docs/a.pdf", true},
+		{"/*.pdf", "/docs/a.pdf?download=1", true},
+		{"/*.pdf$", "/docs/a.pdf?download=1", false},
+		{"/*.pdf$", "/docs/a.pdf", true},
+		{"/a*b*c", "/axxbyyc", true},
+		{"/a*b*c", "/axxcyyb", false},
+		{"/page$", "/page", true},
+		{"/page$", "/page/", false},
+		{"*", "/", true},
+	}
+	for _, tt := range tests {
+		if got := robotsMatch(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("robotsMatch(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRobotsAllowed(t *testing.T) {
+	const robots = `# comment
+User-agent: *
+Disallow: /private
+Allow: /private/public
+
+User-agent: examplebot
+User-agent: otherbot
+Disallow: /bots # shared group
+Allow: /bots/welcome
+
+User-agent: examplebot-news
+Disallow: /
+Allow: /news
+`
+	tests := []struct {
+		agent, path string
+		want        bool
+	}{
+		{"anybot", "/", true},
+		{"anybot", "/private/x", false},
+		{"anybot", "/private/public/x", true},
+		{"anybot", "/bots", true},
+		{"ExampleBot/2.1", "/bots/x", false},
+		{"ExampleBot/2.1", "/bots/welcome", true},
+		{"ExampleBot/2.1", "/private/x", true},
+		{"otherbot", "/bots/x", false},
+		{"examplebot-news", "/bots/x", false},
+		{"examplebot-news", "/news/today", true},
+	}
+	for _, tt := range tests {
+		rules, err := parseRobots(strings.NewReader(robots), tt.agent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := rules.allowed(tt.path); got != tt.want {
+			t.Errorf("agent %q: allowed(%q) = %v, want %v", tt.agent, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRobotsAllowWinsTies(t *testing.T) {
+	rules, err := parseRobots(strings.NewReader("User-agent: *\nDisallow: /page\nAllow: /page\nDisallow:\n"), "bot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rules.allowed("/page") {
+		t.Error("equally long Disallow beat Allow")
+	}
+}
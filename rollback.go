@@ -0,0 +1,103 @@
+package sitemapsplitter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Rollback restores the output published before the current one. With
+// WithVersionedPublish the current symlink is pointed at the previous
+// release. With WithBackup the current output is removed and the most recent
+// backup is moved back into the output directory, consuming it, so that
+// repeated rollbacks step further back. The state file is left as is, so a
+// later run with WithSkipUnchanged or a delta sitemap compares against the
+// rolled back run.
+func (s *SitemapSplitter) Rollback() error {
+	fs, ok := s.sink.(*FileSink)
+	switch {
+	case !ok:
+		return fmt.Errorf("rollback requires the default file sink")
+	case s.keepReleases > 0:
+		return s.rollbackRelease(fs)
+	case s.backupDir != "":
+		return s.restoreBackup(fs)
+	}
+	return fmt.Errorf("rollback requires backups or versioned publishing")
+}
+
+// rollbackRelease points the current symlink at the release before the one
+// it points at
+func (s *SitemapSplitter) rollbackRelease(fs *FileSink) error {
+	names, err := stampedDirs(filepath.Join(fs.dir, releasesDir))
+	if err != nil {
+		return err
+	}
+	target, err := os.Readlink(filepath.Join(fs.dir, currentLink))
+	if err != nil {
+		return fmt.Errorf("error reading current release: %v", err)
+	}
+	current := filepath.Base(target)
+
+	for i, name := range names {
+		if name != current {
+			continue
+		}
+		if i == 0 {
+			return fmt.Errorf("no release before %s to roll back to", current)
+		}
+		if err := flipCurrent(fs.dir, names[i-1]); err != nil {
+			return fmt.Errorf("error rolling back to release %s: %v", names[i-1], err)
+		}
+		s.logger.Info("rolled back release", "phase", "upload", "from", current, "to", names[i-1])
+		return nil
+	}
+	return fmt.Errorf("current release %s not found in %s", current, releasesDir)
+}
+
+// restoreBackup replaces the output with the most recent backup
+func (s *SitemapSplitter) restoreBackup(fs *FileSink) error {
+	backups, err := stampedDirs(s.backupDir)
+	if err != nil {
+		return err
+	}
+	if len(backups) == 0 {
+		return fmt.Errorf("no backup to roll back to in %s", s.backupDir)
+	}
+	latest := filepath.Join(s.backupDir, backups[len(backups)-1])
+
+	current, err := s.previousOutputs(fs)
+	if err != nil {
+		return fmt.Errorf("error reading current sitemap index: %v", err)
+	}
+	for _, name := range current {
+		if err := os.Remove(fs.path(name)); err != nil {
+			return fmt.Errorf("error removing %s: %v", name, err)
+		}
+	}
+
+	restored := 0
+	err = filepath.WalkDir(latest, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(latest, p)
+		if err != nil {
+			return err
+		}
+		dst := fs.path(filepath.ToSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		restored++
+		return os.Rename(p, dst)
+	})
+	if err != nil {
+		return fmt.Errorf("error restoring backup %s: %v", latest, err)
+	}
+	if err := os.RemoveAll(latest); err != nil {
+		return fmt.Errorf("error removing restored backup: %v", err)
+	}
+	s.logger.Info("restored backup", "phase", "write", "backup", latest, "files", restored)
+	return nil
+}
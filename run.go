@@ -0,0 +1,484 @@
+package sitemapsplitter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"slices"
+	"time"
+)
+
+// run is the output side of a single Split: it writes chunks as they are
+// handed to it and collects the index entries, manifest and state that are
+// written once all chunks are done
+type run struct {
+	s    *SitemapSplitter
+	prev *runState // State of the previous run, nil without a state file
+	next *runState // State recorded for the next run, nil without a state file
+
+	entries []Sitemap      // Index entries of the chunks written so far
+	files   []ManifestFile // Chunk files written so far
+	urls    int            // URLs written to chunks so far
+
+	published []string // Locs written so far, added to the dedup store on commit
+
+	changed []URL         // New or changed URLs awaiting the delta sitemap
+	delta   *urlsetStream // Delta sitemap written while streaming
+	news    []newsURL     // Newest URLs with fresh news, awaiting the news sitemap
+	newsOut int           // URLs with fresh news left out of the full news sitemap
+
+	names map[string]bool // Output names claimed so far, used while streaming
+	bytes int64           // Uncompressed chunk bytes, counted while streaming
+
+	backedUp bool     // Previous output has been copied to the backup directory
+	previous []string // Files of the previous output, removed once the run succeeds
+
+	leave func() // Ends the phase in progress, nil outside a timed phase
+}
+
+// newRun creates the run writing to the configured sink
+func (s *SitemapSplitter) newRun(prev *runState, inputHash string) *run {
+	r := &run{s: s, prev: prev}
+	if s.stateFile != "" {
+		r.next = &runState{
+			InputHash: inputHash,
+			Options:   s.optionsFingerprint(),
+			URLs:      make(map[string]string),
+			Files:     make(map[string]string),
+			Robots:    s.robotsSeen,
+		}
+	}
+	return r
+}
+
+// writeChunk writes the files of chunk c and records its index entry
+func (r *run) writeChunk(c chunk) error {
+	s := r.s
+	if err := s.canceled(); err != nil {
+		return err
+	}
+	if err := r.backup(); err != nil {
+		return err
+	}
+
+	entry, err := s.indexEntry(c)
+	if err != nil {
+		return err
+	}
+
+	// Write sitemap file unless it is unchanged since the previous run
+	encode := func(w io.Writer) error {
+		return s.encodeChunk(w, c.urls)
+	}
+	unchanged, err := r.unchanged(c.name, encode)
+	if err != nil {
+		return fmt.Errorf("error hashing sitemap file: %v", err)
+	}
+	if unchanged {
+		s.result.UnchangedFiles++
+		s.logger.Debug("chunk unchanged, not written", "phase", "write", "name", c.name)
+	} else {
+		if err := s.writeOutputs(s.chunkPaths(c.name), encode); err != nil {
+			return fmt.Errorf("error writing sitemap file: %v", err)
+		}
+		s.logger.Debug("wrote chunk", "phase", "write", "name", c.name, "urls", len(c.urls))
+	}
+
+	r.entries = append(r.entries, entry)
+	for _, name := range s.chunkPaths(c.name) {
+		r.files = append(r.files, ManifestFile{Name: name, URLs: len(c.urls)})
+		s.result.Files = append(s.result.Files, s.outputFile(name, len(c.urls), unchanged))
+	}
+	r.urls += len(c.urls)
+	s.result.URLs = r.urls
+	if s.dedupStore != nil {
+		for _, u := range c.urls {
+			r.published = append(r.published, u.Loc)
+		}
+	}
+
+	if err := r.addNews(c.urls); err != nil {
+		return err
+	}
+	return r.track(c.urls)
+}
+
+// indexEntry returns the index entry of chunk c
+func (s *SitemapSplitter) indexEntry(c chunk) (Sitemap, error) {
+	// Get base URL from the last URL in chunk unless one is configured
+	lastURL := c.urls[len(c.urls)-1]
+	baseURL := s.baseURL
+	if baseURL == "" {
+		parsedURL, err := url.Parse(lastURL.Loc)
+		if err != nil {
+			return Sitemap{}, fmt.Errorf("error parsing URL: %v", err)
+		}
+		baseURL = fmt.Sprintf("%s://%s/", parsedURL.Scheme, parsedURL.Host)
+	}
+
+	// Date the entry by the newest lastmod in the chunk, else by the
+	// Last-Modified header of the remote child sitemaps it was read from. The
+	// date is omitted rather than invented when neither is known.
+	lastMod := s.newestLastMod(c.urls)
+	if lastMod == "" {
+		if t := newestChildModTime(c.urls); !t.IsZero() {
+			lastMod = s.formatTime(t.In(s.location))
+		}
+	}
+
+	loc, err := s.indexLoc(c, baseURL, lastMod)
+	if err != nil {
+		return Sitemap{}, err
+	}
+	return Sitemap{Loc: loc, LastMod: lastMod}, nil
+}
+
+// newestLastMod returns the newest parsable lastmod of urls as written, or
+// an empty string if none has one
+func (s *SitemapSplitter) newestLastMod(urls []URL) string {
+	var newest time.Time
+	lastMod := ""
+	for _, u := range urls {
+		if t, ok := parseLastMod(u.LastMod, s.location); ok && (lastMod == "" || t.After(newest)) {
+			newest, lastMod = t, u.LastMod
+		}
+	}
+	return lastMod
+}
+
+// newestChildModTime returns the newest modification time of the remote child
+// sitemaps urls were fetched from, or the zero time if none is known
+func newestChildModTime(urls []URL) time.Time {
+	var newest time.Time
+	for _, u := range urls {
+		if u.childModTime.After(newest) {
+			newest = u.childModTime
+		}
+	}
+	return newest
+}
+
+// backup copies the previous output aside before the first file of the run is
+// written, when backups are enabled
+func (r *run) backup() error {
+	s := r.s
+	if r.backedUp || s.backupDir == "" || s.dryRun {
+		return nil
+	}
+	r.backedUp = true
+	var err error
+	r.previous, err = s.backupOutput()
+	return err
+}
+
+// unchanged reports whether the content that encode writes for the file name
+// matches what the previous run wrote, when differential writes are enabled,
+// and records the content's hash for the next run
+func (r *run) unchanged(name string, encode func(w io.Writer) error) (bool, error) {
+	if !r.s.diffWrites {
+		return false, nil
+	}
+
+	h := sha256.New()
+	if err := encode(h); err != nil {
+		return false, err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	r.next.Files[name] = sum
+	return r.prev.Files[name] == sum, nil
+}
+
+// track records the fingerprints of urls for the next run and collects the
+// ones that are new or changed for the delta sitemap
+func (r *run) track(urls []URL) error {
+	if r.next == nil {
+		return nil
+	}
+
+	for _, u := range urls {
+		fp := urlFingerprint(u)
+		if r.prev.URLs[u.Loc] != fp && r.s.deltaName != "" {
+			if err := r.addChanged(u); err != nil {
+				return err
+			}
+		}
+		r.next.URLs[u.Loc] = fp
+	}
+	return nil
+}
+
+// addChanged adds u to the delta sitemap. While streaming the delta sitemap
+// is written as changes are found instead of being collected in memory.
+func (r *run) addChanged(u URL) error {
+	if !r.s.streaming {
+		r.changed = append(r.changed, u)
+		return nil
+	}
+
+	if r.delta == nil {
+		st, err := r.s.createURLSetStream(r.s.deltaName)
+		if err != nil {
+			return fmt.Errorf("error writing delta sitemap: %v", err)
+		}
+		r.delta = st
+	}
+	if err := r.delta.add(u); err != nil {
+		return fmt.Errorf("error writing delta sitemap: %v", err)
+	}
+	return nil
+}
+
+// addNews adds the URLs with fresh news to the news sitemap. Once it holds
+// newsLimit URLs, the one with the oldest news is left out for every further
+// URL, so that memory stays bounded while streaming.
+func (r *run) addNews(urls []URL) error {
+	s := r.s
+	if s.newsName == "" {
+		return nil
+	}
+
+	cutoff := s.now().Add(-newsFreshness)
+	for _, u := range urls {
+		published := s.newestNews(u)
+		if published.Before(cutoff) {
+			continue
+		}
+		r.news = append(r.news, newsURL{url: u, published: published})
+		if len(r.news) > newsLimit {
+			oldest := 0
+			for i, n := range r.news {
+				if n.published.Before(r.news[oldest].published) {
+					oldest = i
+				}
+			}
+			r.news = slices.Delete(r.news, oldest, oldest+1)
+			r.newsOut++
+		}
+	}
+	return nil
+}
+
+// checkStreamed applies the checks a planned split makes up front to the
+// next streamed chunk c, before it is written. Earlier chunks are already on
+// disk when a check fails.
+func (r *run) checkStreamed(c chunk) error {
+	s := r.s
+	if r.names == nil {
+		fixed := s.extraNames()
+		if !s.noIndex {
+			fixed = append(fixed, s.indexFile())
+		}
+		if err := checkCollisions(fixed...); err != nil {
+			return err
+		}
+		r.names = make(map[string]bool)
+		for _, name := range fixed {
+			r.names[path.Clean(name)] = true
+		}
+	}
+
+	if !s.noIndex {
+		if err := checkIndexEntries(len(r.entries) + 1); err != nil {
+			return err
+		}
+	}
+	if err := s.checkFileCount(len(r.entries) + 1); err != nil {
+		return err
+	}
+	names := s.chunkPaths(c.name)
+	for _, name := range names {
+		clean := path.Clean(name)
+		if r.names[clean] {
+			return fmt.Errorf("output filename collision: %s would be written more than once", clean)
+		}
+		r.names[clean] = true
+	}
+	if err := s.checkInputOverwrite(names); err != nil {
+		return err
+	}
+
+	if s.maxTotalURLs > 0 && r.urls+len(c.urls) > s.maxTotalURLs {
+		return fmt.Errorf("run would publish more than %d URLs, exceeding the quota", s.maxTotalURLs)
+	}
+	if s.maxTotalBytes > 0 {
+		counter := &byteCounter{}
+		if err := s.encodeChunk(counter, c.urls); err != nil {
+			return fmt.Errorf("error measuring output size: %v", err)
+		}
+		r.bytes += counter.n
+		if r.bytes > s.maxTotalBytes {
+			return fmt.Errorf("run would write more than %d bytes, exceeding the quota", s.maxTotalBytes)
+		}
+	}
+	return nil
+}
+
+// finish writes the sitemap index unless disabled, the news and delta
+// sitemaps, the state file and the run history once every chunk has been
+// written
+func (r *run) finish(started time.Time) error {
+	s := r.s
+	if err := s.canceled(); err != nil {
+		return err
+	}
+
+	if err := r.backup(); err != nil {
+		return err
+	}
+
+	if s.noIndex {
+		s.logger.Info("sitemap index disabled, not written", "phase", "write", "chunks", len(r.entries), "urls", r.urls, "duration", time.Since(started))
+	} else if err := r.writeIndex(started); err != nil {
+		return err
+	}
+	if err := r.writeNews(); err != nil {
+		return err
+	}
+
+	if r.next != nil {
+		if err := r.writeDelta(); err != nil {
+			return err
+		}
+	}
+	if len(r.previous) > 0 {
+		return s.removeReplaced(r.previous)
+	}
+	return nil
+}
+
+// startPhase ends the phase in progress, failing if it ran out of time, and
+// starts the named phase with the given timeout
+func (r *run) startPhase(name string, timeout time.Duration) error {
+	err := r.s.canceled()
+	r.endPhase()
+	r.leave = r.s.enterPhase(name, timeout)
+	return err
+}
+
+// endPhase ends the phase in progress, if any
+func (r *run) endPhase() {
+	if r.leave != nil {
+		r.leave()
+		r.leave = nil
+	}
+}
+
+// commit purges the CDN cache once the run's output is live, then saves the
+// run's state and history entry and records the written locs in the dedup
+// store, so that a run that failed, its purge included, is neither skipped as
+// unchanged, listed as done nor deduplicated against
+func (r *run) commit(started time.Time) error {
+	s := r.s
+	if s.dryRun {
+		return nil
+	}
+	if s.purge != nil {
+		if err := s.purgeCache(); err != nil {
+			return err
+		}
+	}
+	if r.next != nil {
+		r.next.Count = r.urls
+		if err := r.next.save(s.stateFile); err != nil {
+			return err
+		}
+	}
+	if s.historyDir != "" {
+		if err := s.recordHistory(started, r.files, r.urls); err != nil {
+			return err
+		}
+	}
+	if s.dedupStore != nil {
+		return s.addPublished(r.published)
+	}
+	return nil
+}
+
+// writeIndex writes the sitemap index of the run's chunks unless it is
+// unchanged since the previous run
+func (r *run) writeIndex(started time.Time) error {
+	s := r.s
+	sitemapIndex := SitemapIndex{
+		XMLNS:    "http://www.sitemaps.org/schemas/sitemap/0.9",
+		Sitemaps: r.entries,
+	}
+	// Keep the chunk files of the Result and manifest in index order
+	variants := len(s.chunkPaths(""))
+	s.sortIndexWith(sitemapIndex.Sitemaps, func(i, j int) {
+		for k := range variants {
+			a, b := i*variants+k, j*variants+k
+			s.result.Files[a], s.result.Files[b] = s.result.Files[b], s.result.Files[a]
+			r.files[a], r.files[b] = r.files[b], r.files[a]
+		}
+	})
+	if err := s.checkIndexBytes(sitemapIndex); err != nil {
+		return err
+	}
+
+	unchanged, err := r.unchanged(s.indexFile(), func(w io.Writer) error {
+		return s.encodeXMLFile(w, OutputIndex, sitemapIndex)
+	})
+	if err != nil {
+		return fmt.Errorf("error hashing sitemap index: %v", err)
+	}
+	if unchanged {
+		s.result.UnchangedFiles++
+		s.logger.Info("sitemap index unchanged, not written", "phase", "write", "name", s.indexFile())
+	} else {
+		if err := s.writeXMLFile(s.indexFile(), OutputIndex, sitemapIndex); err != nil {
+			return fmt.Errorf("error writing sitemap index: %v", err)
+		}
+		msg := "wrote sitemap index"
+		if s.dryRun {
+			msg = "planned sitemap index"
+		}
+		s.logger.Info(msg, "phase", "write", "name", s.indexFile(), "chunks", len(r.entries), "urls", r.urls, "duration", time.Since(started))
+	}
+	index := s.outputFile(s.indexFile(), 0, unchanged)
+	s.result.Index = &index
+	return nil
+}
+
+// writeDelta completes the sitemap of URLs that are new or changed compared
+// to the previous run's state
+func (r *run) writeDelta() error {
+	if r.s.deltaName == "" {
+		return nil
+	}
+
+	var err error
+	if r.delta != nil {
+		err = r.delta.close()
+	} else {
+		err = r.s.writeURLSet(newURLSet(r.changed), r.s.deltaName)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing delta sitemap: %v", err)
+	}
+	return nil
+}
+
+// writeNews completes the sitemap of URLs with fresh news
+func (r *run) writeNews() error {
+	if r.s.newsName == "" {
+		return nil
+	}
+
+	s := r.s
+	if r.newsOut > 0 {
+		s.logger.Warn("news sitemap full, left out older news", "phase", "write", "name", s.newsName, "limit", newsLimit, "left_out", r.newsOut)
+	}
+	urls := make([]URL, len(r.news))
+	for i, n := range r.news {
+		urls[i] = n.url
+	}
+	if err := s.writeURLSet(newURLSet(urls), s.newsName); err != nil {
+		return fmt.Errorf("error writing news sitemap: %v", err)
+	}
+	news := s.outputFile(s.newsName, len(urls), false)
+	s.result.News = &news
+	return nil
+}
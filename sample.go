@@ -0,0 +1,27 @@
+package sitemapsplitter
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+)
+
+// sampled reports whether the URL with loc at position pos of the input is
+// kept by sampling
+func (s *SitemapSplitter) sampled(pos int, loc string) bool {
+	if s.sampleEvery > 1 && pos%s.sampleEvery != 0 {
+		return false
+	}
+	return s.samplePercent <= 0 || s.inSample(loc)
+}
+
+// inSample reports whether loc falls into the percentage sample. The decision
+// depends only on the seed and loc, so it is stable across runs and input order.
+func (s *SitemapSplitter) inSample(loc string) bool {
+	h := fnv.New64a()
+	var seed [8]byte
+	binary.LittleEndian.PutUint64(seed[:], uint64(s.sampleSeed))
+	h.Write(seed[:])
+	h.Write([]byte(loc))
+
+	return float64(h.Sum64()%10000) < s.samplePercent*100
+}
@@ -0,0 +1,79 @@
+package sitemapsplitter
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestSampleEvery(t *testing.T) {
+	tests := []struct {
+		every int
+		want  []int // Positions kept of the first ten
+	}{
+		{0, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{1, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{3, []int{0, 3, 6, 9}},
+		{20, []int{0}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.every), func(t *testing.T) {
+			s, err := NewSitemapSplitter("in.xml", 50000, WithSampleEvery(tt.every))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []int
+			for pos := range 10 {
+				if s.sampled(pos, fmt.Sprintf("https://example.com/%d", pos)) {
+					got = append(got, pos)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("kept positions %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSamplePercent(t *testing.T) {
+	const n = 10000
+	sample := func(t *testing.T, percent float64, seed int64) []string {
+		t.Helper()
+		s, err := NewSitemapSplitter("in.xml", 50000, WithSamplePercent(percent, seed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var kept []string
+		for pos := range n {
+			if loc := fmt.Sprintf("https://example.com/%d", pos); s.sampled(pos, loc) {
+				kept = append(kept, loc)
+			}
+		}
+		return kept
+	}
+
+	tests := []struct {
+		percent  float64
+		min, max int // Bounds of the number of URLs kept
+	}{
+		{0, n, n},
+		{10, n * 9 / 100, n * 11 / 100},
+		{50, n * 48 / 100, n * 52 / 100},
+		{100, n, n},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.percent), func(t *testing.T) {
+			kept := sample(t, tt.percent, 7)
+			if len(kept) < tt.min || len(kept) > tt.max {
+				t.Errorf("kept %d URLs, want %d to %d", len(kept), tt.min, tt.max)
+			}
+			if again := sample(t, tt.percent, 7); !slices.Equal(again, kept) {
+				t.Error("same seed kept different URLs")
+			}
+		})
+	}
+
+	if slices.Equal(sample(t, 10, 1), sample(t, 10, 2)) {
+		t.Error("different seeds kept the same URLs")
+	}
+}
@@ -0,0 +1,153 @@
+package sitemapsplitter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Sink stores the files generated by a split: chunks, the delta sitemap and
+// the index. Implementations can publish to object storage, keep files in
+// memory for tests, or write to disk like the default FileSink.
+type Sink interface {
+	// Write stores the content read from r under name, a slash separated path
+	// relative to the output root. A read error means the content is
+	// incomplete and must not be published.
+	Write(name string, r io.Reader) error
+}
+
+// FileSink writes files below a directory on the local filesystem, creating
+// subdirectories as needed
+type FileSink struct {
+	dir  string
+	perm os.FileMode
+}
+
+// NewFileSink creates a FileSink writing files with mode 0644 below dir
+func NewFileSink(dir string) *FileSink {
+	return &FileSink{dir: dir, perm: 0644}
+}
+
+// Write stores the content of r in the file name below the sink's directory.
+// An incomplete file is removed.
+func (fs *FileSink) Write(name string, r io.Reader) error {
+	path := fs.path(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating output directory: %v", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fs.perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+// path returns the filesystem path of name
+func (fs *FileSink) path(name string) string {
+	return filepath.Join(fs.dir, filepath.FromSlash(name))
+}
+
+// MemorySink keeps written files in memory. It is safe for concurrent use.
+type MemorySink struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+// NewMemorySink creates an empty MemorySink
+func NewMemorySink() *MemorySink {
+	return &MemorySink{files: make(map[string][]byte)}
+}
+
+// Write stores the content of r under name, replacing any previous content
+func (m *MemorySink) Write(name string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[name] = data
+	return nil
+}
+
+// Open returns a reader of the content stored under name
+func (m *MemorySink) Open(name string) (io.ReadCloser, error) {
+	data, ok := m.File(name)
+	if !ok {
+		return nil, fmt.Errorf("%s not stored", name)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// File returns the content stored under name
+func (m *MemorySink) File(name string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.files[name]
+	return data, ok
+}
+
+// Names returns the names of all stored files, sorted
+func (m *MemorySink) Names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.files))
+	for name := range m.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SinkTarget is one destination of a FanOutSink with its own file layout
+type SinkTarget struct {
+	Sink   Sink
+	Rename func(name string) string // Name the file is stored under in Sink, nil to keep the split's name
+}
+
+// FanOutSink stores every file in several sinks from one split pass, each
+// target with its own naming and directory layout, e.g. dated directories
+// for a local archive and plain names for a CDN bucket. Renaming does not
+// rewrite the index, whose locs keep the split's names.
+type FanOutSink struct {
+	targets []SinkTarget
+}
+
+// NewFanOutSink creates a FanOutSink writing to targets in order
+func NewFanOutSink(targets ...SinkTarget) *FanOutSink {
+	return &FanOutSink{targets: targets}
+}
+
+// Write stores the content of r in every target in turn. The content is
+// held in memory until the last target has stored it, and a failing target
+// stops the write.
+func (f *FanOutSink) Write(name string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	for i, t := range f.targets {
+		target := name
+		if t.Rename != nil {
+			if target = t.Rename(name); target == "" {
+				return fmt.Errorf("sink %d renamed %s to an empty name", i+1, name)
+			}
+		}
+		if err := t.Sink.Write(target, bytes.NewReader(data)); err != nil {
+			return fmt.Errorf("error writing %s to sink %d: %v", target, i+1, err)
+		}
+	}
+	return nil
+}
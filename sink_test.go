@@ -0,0 +1,217 @@
+package sitemapsplitter
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// failingSink fails every write
+type failingSink struct{}
+
+func (failingSink) Write(string, io.Reader) error { return errors.New("bucket unavailable") }
+
+func TestFanOutSink(t *testing.T) {
+	archive := func(name string) string { return path.Join("2024-06-01", name) }
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc></url><url><loc>https://example.com/b</loc></url></urlset>`
+	names := []string{"in-1.xml", "in-2.xml", "sitemap-index.xml"}
+
+	tests := []struct {
+		name    string
+		rename  func(string) string // Layout of the second target
+		fail    bool                // Make the second target fail
+		want    []string            // Names stored by the second target
+		wantErr bool
+	}{
+		{"same layout", nil, false, names, false},
+		{"dated directories", archive, false, []string{"2024-06-01/in-1.xml", "2024-06-01/in-2.xml", "2024-06-01/sitemap-index.xml"}, false},
+		{"empty name", func(string) string { return "" }, false, nil, true},
+		{"failing target", nil, true, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, second := NewMemorySink(), NewMemorySink()
+			target := SinkTarget{Sink: second, Rename: tt.rename}
+			if tt.fail {
+				target.Sink = failingSink{}
+			}
+			s, err := NewSitemapSplitter("in.xml", 1, WithSink(NewFanOutSink(SinkTarget{Sink: first}, target)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = s.SplitFrom(strings.NewReader(in))
+			if tt.wantErr {
+				if err == nil {
+					t.Error("split succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := first.Names(); !slices.Equal(got, names) {
+				t.Errorf("first target stored %v, want %v", got, names)
+			}
+			if got := second.Names(); !slices.Equal(got, tt.want) {
+				t.Errorf("second target stored %v, want %v", got, tt.want)
+			}
+			for i, name := range tt.want {
+				a, _ := first.File(names[i])
+				b, _ := second.File(name)
+				if string(a) != string(b) {
+					t.Errorf("%s differs between the targets", name)
+				}
+			}
+		})
+	}
+}
+
+// errReader returns its content and then fails, like a broken upload pipe
+type errReader struct {
+	content string
+	read    bool
+}
+
+func (r *errReader) Read(p []byte) (int, error) {
+	if r.read {
+		return 0, errors.New("pipe broken")
+	}
+	r.read = true
+	return copy(p, r.content), nil
+}
+
+func TestFileSink(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		r       io.Reader
+		wantErr bool
+	}{
+		{"top level", "in-1.xml", strings.NewReader("<urlset/>"), false},
+		{"subdirectory", "00/in-1.xml", strings.NewReader("<urlset/>"), false},
+		{"failed read", "in-1.xml", &errReader{content: "<urlset>"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			sink := NewFileSink(dir)
+			err := sink.Write(tt.file, tt.r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("write error %v, want error %v", err, tt.wantErr)
+			}
+
+			data, readErr := os.ReadFile(filepath.Join(dir, filepath.FromSlash(tt.file)))
+			if tt.wantErr {
+				if !os.IsNotExist(readErr) {
+					t.Errorf("incomplete file kept: %v", readErr)
+				}
+				return
+			}
+			if string(data) != "<urlset/>" {
+				t.Errorf("stored %q", data)
+			}
+		})
+	}
+}
+
+func TestSinkReceivesOutputs(t *testing.T) {
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc></url><url><loc>https://example.com/b</loc></url><url><loc>https://example.com/c</loc></url></urlset>`
+	tests := []struct {
+		name string
+		opts []Option
+		want []string
+	}{
+		{"chunks and index", nil, []string{"in-1.xml", "in-2.xml", "in-3.xml", "sitemap-index.xml"}},
+		{"sharded", []Option{WithSharding(2)}, []string{"00/in-1.xml", "00/in-2.xml", "01/in-3.xml", "sitemap-index.xml"}},
+		{"streaming", []Option{WithStreaming()}, []string{"in-1.xml", "in-2.xml", "in-3.xml", "sitemap-index.xml"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			sink := NewMemorySink()
+			input := filepath.Join(dir, "in.xml")
+			s, err := NewSitemapSplitter(input, 1, append([]Option{WithSink(sink), WithOutputDir(dir)}, tt.opts...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+				t.Fatal(err)
+			}
+			if got := sink.Names(); !slices.Equal(got, tt.want) {
+				t.Errorf("sink stored %v, want %v", got, tt.want)
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+				t.Errorf("split wrote to the output directory despite the sink")
+			}
+		})
+	}
+
+	s, err := NewSitemapSplitter("in.xml", 1, WithSink(failingSink{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SplitFrom(strings.NewReader(in)); err == nil || !strings.Contains(err.Error(), "bucket unavailable") {
+		t.Errorf("split error %v, want the sink's error", err)
+	}
+}
+
+func TestOutputDir(t *testing.T) {
+	tests := []struct {
+		name   string
+		outDir string // Relative to the test's directory, empty for none
+		sink   bool   // Whether a sink is set besides the output directory
+		want   string // Directory expected to hold the chunks, relative to the test's directory
+	}{
+		{"next to input", "", false, "in"},
+		{"output dir", "out", false, "out"},
+		{"nested output dir", "out/sitemaps", false, "out/sitemaps"},
+		{"sink wins", "out", true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			input := filepath.Join(dir, "in", "in.xml")
+			if err := os.Mkdir(filepath.Dir(input), 0755); err != nil {
+				t.Fatal(err)
+			}
+			writeSitemap(t, input, "https://example.com/a")
+
+			var opts []Option
+			if tt.outDir != "" {
+				opts = append(opts, WithOutputDir(filepath.Join(dir, tt.outDir)))
+			}
+			sink := NewMemorySink()
+			if tt.sink {
+				opts = append(opts, WithSink(sink))
+			}
+			s, err := NewSitemapSplitter(input, 10, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Split(); err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.want == "" {
+				if _, err := os.Stat(filepath.Join(dir, tt.outDir)); !os.IsNotExist(err) {
+					t.Errorf("output dir created with a sink: %v", err)
+				}
+				if !slices.Equal(sink.Names(), []string{"in-1.xml", "sitemap-index.xml"}) {
+					t.Errorf("sink holds %v", sink.Names())
+				}
+				return
+			}
+			for _, name := range []string{"in-1.xml", "sitemap-index.xml"} {
+				if !fileContains(filepath.Join(dir, tt.want, name), "<loc>") {
+					t.Errorf("%s not written to %s", name, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package sitemapsplitter
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"maps"
+)
+
+// chunkSizer tracks the uncompressed size a chunk will have when written, so
+// chunks can be closed before they exceed the byte limit
+type chunkSizer struct {
+	s      *SitemapSplitter
+	budget int64           // Byte limit less the headroom
+	fixed  int64           // XML declaration, urlset tags and core namespaces
+	urls   int64           // Encoded URL entries
+	ns     map[string]bool // Extension namespace declarations already counted
+	buf    bytes.Buffer
+}
+
+// newChunkSizer creates a sizer for an empty chunk
+func (s *SitemapSplitter) newChunkSizer() *chunkSizer {
+	budget := s.maxBytes - int64(float64(s.maxBytes)*s.byteHeadroom/100)
+	if s.outputFormat == FormatText {
+		return &chunkSizer{s: s, budget: budget, ns: make(map[string]bool)}
+	}
+
+	var buf bytes.Buffer
+	s.writeHeader(&buf, OutputChunks)
+	writeStartTag(&buf, newURLSet(nil).startElement())
+	buf.WriteString("\n</urlset>")
+
+	return &chunkSizer{s: s, budget: budget, fixed: int64(buf.Len()), ns: make(map[string]bool)}
+}
+
+// add counts u into the chunk if the chunk stays within the byte limit and
+// reports whether it did
+func (c *chunkSizer) add(u URL) (bool, error) {
+	if c.s.outputFormat == FormatText {
+		size := int64(len(u.Loc) + 1)
+		if c.urls+size > c.budget {
+			return false, nil
+		}
+		c.urls += size
+		return true, nil
+	}
+
+	c.buf.Reset()
+	c.buf.WriteByte('\n')
+	if err := c.s.encodeURL(&c.buf, u); err != nil {
+		return false, fmt.Errorf("error measuring URL size: %v", err)
+	}
+	size := int64(c.buf.Len())
+
+	var added []string
+	for _, attr := range extensionNamespaceAttrs([]URL{u}) {
+		if c.ns[attr.Name.Local] {
+			continue
+		}
+		c.buf.Reset()
+		writeStartTag(&c.buf, xml.StartElement{Attr: []xml.Attr{attr}})
+		size += int64(c.buf.Len() - len("<>"))
+		added = append(added, attr.Name.Local)
+	}
+
+	if c.fixed+c.urls+size > c.budget {
+		return false, nil
+	}
+	c.urls += size
+	for _, name := range added {
+		c.ns[name] = true
+	}
+	return true, nil
+}
+
+// reset empties the chunk
+func (c *chunkSizer) reset() {
+	c.urls = 0
+	clear(c.ns)
+}
+
+// fitGroup adds the URLs of group to the chunk tracked by c, keeping them
+// together: if they do not all fit, closeChunk is called and the group is
+// counted into the next chunk. A group that does not fit into an empty chunk
+// is an error.
+func (c *chunkSizer) fitGroup(group []URL, empty bool, closeChunk func() error) error {
+	if len(group) == 1 {
+		return c.fitURL(group[0], empty, closeChunk)
+	}
+
+	urls, ns := c.urls, maps.Clone(c.ns)
+	ok, err := c.addAll(group)
+	if err != nil || ok {
+		return err
+	}
+
+	c.urls, c.ns = urls, ns
+	if !empty {
+		if err := closeChunk(); err != nil {
+			return err
+		}
+		c.reset()
+		if ok, err = c.addAll(group); err != nil || ok {
+			return err
+		}
+	}
+	return fmt.Errorf("alternates of %s do not fit into the chunk byte budget of %d together", group[0].Loc, c.budget)
+}
+
+// addAll counts every URL of group into the chunk and reports whether all of
+// them fit
+func (c *chunkSizer) addAll(group []URL) (bool, error) {
+	for _, u := range group {
+		if ok, err := c.add(u); err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// fitURL adds u to the chunk tracked by c. If u does not fit, closeChunk is
+// called and u is counted into the next chunk. A URL that does not fit into
+// an empty chunk is an error.
+func (c *chunkSizer) fitURL(u URL, empty bool, closeChunk func() error) error {
+	ok, err := c.add(u)
+	if err != nil || ok {
+		return err
+	}
+
+	if !empty {
+		if err := closeChunk(); err != nil {
+			return err
+		}
+		c.reset()
+		if ok, err = c.add(u); err != nil || ok {
+			return err
+		}
+	}
+	return fmt.Errorf("URL %s does not fit into the chunk byte budget of %d on its own", u.Loc, c.budget)
+}
@@ -0,0 +1,138 @@
+package sitemapsplitter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// sizedURLs returns n URLs of varying length, every third with an image
+func sizedURLs(n int) []URL {
+	urls := make([]URL, n)
+	for i := range urls {
+		urls[i] = URL{Loc: fmt.Sprintf("https://example.com/%s%d", strings.Repeat("p", i%17), i), LastMod: "2024-06-01"}
+		if i%3 == 0 {
+			urls[i].AddImage(Image{Loc: fmt.Sprintf("https://example.com/img/%d.jpg", i)})
+		}
+	}
+	return urls
+}
+
+// encodedSize returns the uncompressed size of a chunk holding urls
+func encodedSize(t *testing.T, s *SitemapSplitter, urls []URL) int64 {
+	t.Helper()
+	counter := &byteCounter{}
+	if err := s.encodeChunk(counter, urls); err != nil {
+		t.Fatal(err)
+	}
+	return counter.n
+}
+
+// checkChunkBytes fails unless every chunk is within budget and no chunk
+// but the last could have taken the first URL of the next one
+func checkChunkBytes(t *testing.T, s *SitemapSplitter, chunks []chunk, budget int64) {
+	t.Helper()
+	for i, c := range chunks {
+		if size := encodedSize(t, s, c.urls); size > budget {
+			t.Errorf("chunk %d is %d bytes, over the budget of %d", i+1, size, budget)
+		}
+		if i+1 < len(chunks) {
+			more := append(c.urls[:len(c.urls):len(c.urls)], chunks[i+1].urls[0])
+			if size := encodedSize(t, s, more); size <= budget {
+				t.Errorf("chunk %d was closed at %d URLs although the next URL fits (%d bytes)", i+1, len(c.urls), size)
+			}
+		}
+	}
+}
+
+func TestChunkSizerByteLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    int
+		maxBytes int64
+		opts     []Option
+	}{
+		{"xml", 50000, 2000, nil},
+		{"xml tight", 50000, 700, nil},
+		{"xml with URL limit", 3, 4000, nil},
+		{"text", 50000, 300, []Option{WithOutputFormat(FormatText)}},
+		{"no declaration", 50000, 1500, []Option{WithoutXMLDeclaration(OutputChunks)}},
+		{"self-closing", 50000, 1500, []Option{WithSelfClosingTags()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{WithMaxBytes(tt.maxBytes), WithSink(NewMemorySink())}, tt.opts...)
+			s, err := NewSitemapSplitter("in.xml", tt.limit, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			urls := sizedURLs(40)
+			chunks, err := s.planChunks(urls, "in")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(chunks) < 2 {
+				t.Fatalf("planned %d chunk, want several", len(chunks))
+			}
+
+			total := 0
+			for _, c := range chunks {
+				if len(c.urls) > tt.limit {
+					t.Errorf("chunk %s holds %d URLs, over the limit of %d", c.name, len(c.urls), tt.limit)
+				}
+				total += len(c.urls)
+			}
+			if total != len(urls) {
+				t.Errorf("chunks hold %d URLs, want %d", total, len(urls))
+			}
+			if tt.limit >= len(urls) {
+				checkChunkBytes(t, s, chunks, tt.maxBytes)
+			}
+		})
+	}
+}
+
+func TestChunkSizerRejectsOversizedURL(t *testing.T) {
+	s, err := NewSitemapSplitter("in.xml", 50000, WithMaxBytes(200), WithSink(NewMemorySink()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	urls := []URL{{Loc: "https://example.com/" + strings.Repeat("x", 300)}}
+	if _, err := s.planChunks(urls, "in"); err == nil || !strings.Contains(err.Error(), "does not fit") {
+		t.Errorf("planChunks = %v, want an error for the oversized URL", err)
+	}
+}
+
+func TestByteHeadroom(t *testing.T) {
+	tests := []struct {
+		headroom   float64
+		wantBudget int64
+	}{
+		{0, 2000},
+		{10, 1800},
+		{25, 1500},
+		{12.5, 1750},
+	}
+	for _, tt := range tests {
+		s, err := NewSitemapSplitter("in.xml", 50000, WithMaxBytes(2000), WithByteHeadroom(tt.headroom), WithSink(NewMemorySink()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := s.newChunkSizer().budget; got != tt.wantBudget {
+			t.Errorf("headroom %v: budget = %d, want %d", tt.headroom, got, tt.wantBudget)
+		}
+		chunks, err := s.planChunks(sizedURLs(40), "in")
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkChunkBytes(t, s, chunks, tt.wantBudget)
+	}
+}
+
+func TestByteHeadroomValidation(t *testing.T) {
+	for _, headroom := range []float64{-1, 100, 150} {
+		if _, err := NewSitemapSplitter("in.xml", 1, WithMaxBytes(2000), WithByteHeadroom(headroom)); err == nil {
+			t.Errorf("headroom %v was accepted", headroom)
+		}
+	}
+}
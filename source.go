@@ -0,0 +1,95 @@
+package sitemapsplitter
+
+import (
+	"fmt"
+	"io"
+)
+
+// URLSource supplies the URLs to split, one at a time, so inputs other than
+// sitemap files (databases, crawlers, channels) can feed the same pipeline
+type URLSource interface {
+	// Next returns the next URL, or io.EOF once the source is exhausted
+	Next() (URL, error)
+}
+
+// URLSourceFunc adapts a function to the URLSource interface
+type URLSourceFunc func() (URL, error)
+
+// Next calls f
+func (f URLSourceFunc) Next() (URL, error) {
+	return f()
+}
+
+// SliceSource is a URLSource returning the URLs of a slice in order
+type SliceSource struct {
+	urls []URL
+}
+
+// NewSliceSource creates a SliceSource over urls
+func NewSliceSource(urls []URL) *SliceSource {
+	return &SliceSource{urls: urls}
+}
+
+// Next returns the next URL of the slice
+func (s *SliceSource) Next() (URL, error) {
+	if len(s.urls) == 0 {
+		return URL{}, io.EOF
+	}
+	u := s.urls[0]
+	s.urls = s.urls[1:]
+	return u, nil
+}
+
+// ChannelSource is a URLSource receiving URLs from a channel until it is
+// closed, letting a producer goroutine feed a split
+type ChannelSource struct {
+	ch <-chan URL
+}
+
+// NewChannelSource creates a ChannelSource reading from ch
+func NewChannelSource(ch <-chan URL) *ChannelSource {
+	return &ChannelSource{ch: ch}
+}
+
+// Next returns the next URL sent on the channel
+func (c *ChannelSource) Next() (URL, error) {
+	u, ok := <-c.ch
+	if !ok {
+		return URL{}, io.EOF
+	}
+	return u, nil
+}
+
+// SplitSource splits the URLs supplied by src instead of the file at the
+// splitter's path, which still determines the output directory and chunk
+// names. Sources have no content to hash, so WithSkipUnchanged is rejected.
+func (s *SitemapSplitter) SplitSource(src URLSource) error {
+	if src == nil {
+		return fmt.Errorf("URL source must not be nil")
+	}
+	if s.skipUnchanged {
+		return fmt.Errorf("skipping unchanged input cannot be used with a URL source")
+	}
+
+	s.source = src
+	defer func() { s.source = nil }()
+	err := s.split()
+	s.observe(err)
+	return err
+}
+
+// eachSourceURL calls fn with every URL of src
+func eachSourceURL(src URLSource, fn func(URL) error) error {
+	for {
+		u, err := src.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading URL source: %v", err)
+		}
+		if err := fn(u); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package sitemapsplitter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSplitSource(t *testing.T) {
+	urls := []URL{{Loc: "https://example.com/a"}, {Loc: "https://example.com/b"}, {Loc: "https://example.com/c"}}
+	tests := []struct {
+		name    string
+		source  func() URLSource
+		opts    []Option
+		wantErr string // Part of the error, empty for none
+	}{
+		{"slice", func() URLSource { return NewSliceSource(urls) }, nil, ""},
+		{"channel", func() URLSource {
+			ch := make(chan URL)
+			go func() {
+				for _, u := range urls {
+					ch <- u
+				}
+				close(ch)
+			}()
+			return NewChannelSource(ch)
+		}, nil, ""},
+		{"func", func() URLSource {
+			i := 0
+			return URLSourceFunc(func() (URL, error) {
+				if i == len(urls) {
+					return URL{}, io.EOF
+				}
+				i++
+				return urls[i-1], nil
+			})
+		}, nil, ""},
+		{"streaming", func() URLSource { return NewSliceSource(urls) }, []Option{WithStreaming()}, ""},
+		{"failing source", func() URLSource {
+			return URLSourceFunc(func() (URL, error) { return URL{}, errors.New("database gone") })
+		}, nil, "database gone"},
+		{"nil source", func() URLSource { return nil }, nil, "must not be nil"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := NewMemorySink()
+			s, err := NewSitemapSplitter("feed.xml", 2, append([]Option{WithSink(sink)}, tt.opts...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = s.SplitSource(tt.source())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("split error %v, want one mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if want := []string{"feed-1.xml", "feed-2.xml", "sitemap-index.xml"}; !slices.Equal(sink.Names(), want) {
+				t.Fatalf("split stored %v, want %v", sink.Names(), want)
+			}
+			var out strings.Builder
+			for _, name := range []string{"feed-1.xml", "feed-2.xml"} {
+				data, _ := sink.File(name)
+				out.Write(data)
+			}
+			for i, u := range urls {
+				if !strings.Contains(out.String(), fmt.Sprintf("<loc>%s</loc>", u.Loc)) {
+					t.Errorf("output lacks URL %d", i)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitSourceRejectsSkipUnchanged(t *testing.T) {
+	s, err := NewSitemapSplitter("feed.xml", 2, WithSink(NewMemorySink()), WithStateFile(filepath.Join(t.TempDir(), "state.json")), WithSkipUnchanged())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SplitSource(NewSliceSource(nil)); err == nil {
+		t.Error("URL source accepted with skip-if-unchanged")
+	}
+}
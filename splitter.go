@@ -1,22 +1,37 @@
 package sitemapsplitter
 
 import (
+	"bytes"
+	"context"
+	"crypto/cipher"
 	"encoding/xml"
+	"expvar"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"log/slog"
+	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sync"
+	"text/template"
 	"time"
 )
 
 // URL represents a single URL entry in the sitemap
 type URL struct {
-	XMLName    xml.Name `xml:"url"`
-	Loc        string   `xml:"loc"`
-	LastMod    string   `xml:"lastmod,omitempty"`
-	ChangeFreq string   `xml:"changefreq,omitempty"`
-	Priority   string   `xml:"priority,omitempty"`
+	XMLName    xml.Name    `xml:"url" json:"-"`
+	Loc        string      `xml:"loc" json:"loc"`
+	LastMod    string      `xml:"lastmod,omitempty" json:"lastmod,omitempty"`
+	ChangeFreq string      `xml:"changefreq,omitempty" json:"changefreq,omitempty"`
+	Priority   string      `xml:"priority,omitempty" json:"priority,omitempty"`
+	Extensions []Extension `xml:"-" json:"extensions,omitempty"` // Elements from registered extension namespaces
+	Attrs      []xml.Attr  `xml:"-" json:"attrs,omitempty"`      // Attributes of the <url> element, namespaced ones as prefix:name after their xmlns declaration
+	LocAttrs   []xml.Attr  `xml:"-" json:"loc_attrs,omitempty"`  // Attributes of the <loc> element, in the form of Attrs
+
+	sourceModTime time.Time // Modification time of the file the URL was read from
+	childModTime  time.Time // Last-Modified of the remote child sitemap the URL was read from, zero for other inputs
 }
 
 // URLSet represents the root element of a sitemap
@@ -27,6 +42,15 @@ type URLSet struct {
 	URLs    []URL    `xml:"url"`
 }
 
+// newURLSet creates a sitemap URLSet holding urls
+func newURLSet(urls []URL) URLSet {
+	return URLSet{
+		XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9",
+		XHTML: XHTMLNamespace,
+		URLs:  urls,
+	}
+}
+
 // SitemapIndex represents the root element of a sitemap index
 type SitemapIndex struct {
 	XMLName  xml.Name  `xml:"sitemapindex"`
@@ -38,17 +62,127 @@ type SitemapIndex struct {
 type Sitemap struct {
 	XMLName xml.Name `xml:"sitemap"`
 	Loc     string   `xml:"loc"`
-	LastMod string   `xml:"lastmod"`
+	LastMod string   `xml:"lastmod,omitempty"`
 }
 
+// defaultIndexName is the filename of the generated sitemap index unless
+// WithIndexName overrides it
+const defaultIndexName = "sitemap-index.xml"
+
 // SitemapSplitter handles splitting large sitemaps into smaller ones
 type SitemapSplitter struct {
 	path  string // Absolute or relative path to sitemap file
 	limit int    // Maximum number of URLs per sitemap file
+
+	lastModFormat LastModFormat  // Output format for lastmod values
+	location      *time.Location // Timezone for generated timestamps
+	stateFile     string         // Path of the state file shared between runs
+	deltaName     string         // Filename of the changed-URLs sitemap, empty to disable
+	newsName      string         // Filename of the fresh news sitemap, empty to disable
+	filesPerShard int            // Chunk files per shard subdirectory, 0 to disable
+	maxFiles      int            // Maximum number of files a run may write, 0 for no limit
+	sampleEvery   int            // Keep every Nth URL, 0 or 1 to keep all
+	samplePercent float64        // Percentage of URLs to keep, 0 to keep all
+	sampleSeed    int64          // Seed for percentage sampling
+	redactRules   []RedactRule   // Query parameters to strip or mask in loc values
+	jsonlExport   io.Writer      // Destination for a JSON Lines export of the output URLs
+	dedupStore    DedupStore     // Store of already published locs, nil to disable
+	decodeLimits  DecodeLimits   // Structural caps applied while decoding input
+	phaseTimeouts PhaseTimeouts  // Time limits of the phases of a split
+	tokenizer     Tokenizer      // Alternative raw XML tokenizer, nil for encoding/xml
+	skipUnchanged bool           // Skip the run when input and options match the previous run
+	diffWrites    bool           // Only write chunks and index whose content changed since the previous run
+	validate      bool           // Drop invalid entries, failing the run above maxErrorRate
+	maxErrorRate  float64        // Tolerated percentage of invalid entries
+	streaming     bool           // Decode the input URL by URL and write chunks as they fill
+	dualOutput    bool           // Write every chunk as both .xml and .xml.gz
+	preferGzip    bool           // Point index entries at the .xml.gz variant in dual output mode
+	gzipOutput    bool           // Write chunks and the index gzip compressed only
+	omitHeader    OutputKind     // Outputs written without the XML declaration
+	selfClosing   bool           // Write empty elements as <name/> instead of <name></name>
+	noFragments   bool           // Remove #fragment components from loc values
+	lowerPaths    bool           // Lowercase URL paths for case-insensitive servers
+	targetFiles   int            // Desired number of chunk files, 0 to use limit as is
+	backfillRules []BackfillRule // Sources for lastmod values missing from the input
+	maxTotalURLs  int            // Run-level quota of published URLs, 0 for no quota
+	maxTotalBytes int64          // Run-level quota of uncompressed chunk bytes, 0 for no quota
+	maxBytes      int64          // Maximum uncompressed bytes per chunk, 0 for no limit
+	byteHeadroom  float64        // Percentage of maxBytes kept free in every chunk
+	skipExamples  int            // Example locs kept per skip reason
+	maxWarnings   int            // Warnings kept with their loc in the Result
+	indexOrder    IndexOrder     // Order of the sitemap index entries
+	historyDir    string         // Directory receiving a record of every run, empty to disable
+	historyKeep   int            // Number of history records to retain, 0 for all
+	robotsAgent   string         // User-agent whose robots.txt rules filter URLs, empty to disable
+	canonicalHost string         // Host that www and apex variants are rewritten to
+	indexName     string         // Filename of the sitemap index
+	baseURL       string         // URL prefix of index entries, empty to derive it from each chunk
+	outputDir     string         // Directory of the default file sink, empty for the input's directory
+	destination   string         // rclone-style destination resolved into the sink or outputDir, empty for none
+	filePerm      os.FileMode    // Permissions of files written by the default file sink
+	httpClient    *http.Client   // Client used for outbound HTTP requests
+	userAgent     string         // User-Agent header of outbound HTTP requests, empty for the client default
+	requestDelay  time.Duration  // Minimum time between two HTTP requests to the same host
+	rateLimit     float64        // Maximum HTTP requests per second to any host, 0 for no limit
+	childFetches  int            // Child sitemaps of an index input read at once, 0 or 1 to read them in turn
+	hostFetches   int            // HTTP requests in flight per host, 0 for no limit
+	profile       Profile        // Unknown profile passed to WithProfile, reported by the constructor
+	encryptKey    []byte         // AES key of output encryption, nil to write plaintext
+	encryption    cipher.AEAD    // Cipher built from encryptKey
+	newsMaxAge    time.Duration  // Age of news entries that draws a warning, 0 for no check
+	clusterAlts   bool           // Keep URLs linked as alternates in the same chunk
+	checkLangs    bool           // Warn about malformed and one-way hreflang alternates
+	outputFormat  OutputFormat   // File format of the chunks
+	diskCheck     bool           // Check free disk space of the file sink before writing
+	dupPolicy     DedupPolicy    // Which entry of a repeated loc is kept
+	nameTemplate  string         // Template for chunk file names, empty for <base>-N
+	excludeFile   string         // File of locs to drop from the output, empty to disable
+	noIndex       bool           // Skip writing the sitemap index
+	allowPrefixes []string       // Loc prefixes to publish, empty to publish all
+	allowFile     string         // File of further allowed loc prefixes, empty for none
+	dryRun        bool           // Plan and encode the output without storing anything
+	expvarMap     *expvar.Map    // Counters of processed URLs and runs, nil to disable
+	indexLocTmpl  string         // Template of index entry locs, empty for base URL and name
+	keepReleases  int            // Releases kept with versioned publishing, 0 to write in place
+	backupDir     string         // Directory of backups of the previous output, empty to disable
+	backupKeep    int            // Backups retained in backupDir
+	normalizeURLs bool           // Normalize every loc before the other rewrites
+	includes      []string       // Loc patterns of which one must match, empty to keep all
+	excludes      []string       // Loc patterns dropping every URL they match
+	modAfter      time.Time      // Earliest lastmod kept, zero for no lower bound
+	modBefore     time.Time      // Lastmod values from this time on are dropped, zero for no upper bound
+	modWindow     time.Duration  // Age of the oldest lastmod kept, 0 for no window
+	verifyUploads bool           // Check every stored file against what was written
+
+	emptyInput EmptyInputPolicy        // How input without any URLs is handled
+	indexLess  func(a, b Sitemap) bool // Custom index entry comparator, nil to use indexOrder
+	countAlert *CountAlert             // Alert on large URL count changes, nil to disable
+	purge      *CachePurge             // CDN purge of the stored files after a run, nil to disable
+	sink       Sink                    // Destination of generated files
+	logger     *slog.Logger            // Destination of progress logs
+	clock      func() time.Time        // Source of the current time, replaced in tests
+
+	reader        io.Reader            // Input of the SplitFrom in progress, nil to read path
+	readerModTime time.Time            // Modification time of reader's content, if known
+	source        URLSource            // Input of the SplitSource in progress, nil to read path
+	result        *Result              // Report of the most recent Split
+	ctx           context.Context      // Context of the SplitContext in progress, nil for none
+	lastRequest   map[string]time.Time // Time of the latest HTTP request per host
+	lastSent      time.Time            // Start of the latest HTTP request to any host
+	pacing        sync.Mutex           // Guards lastRequest, lastSent and hostSlots while child sitemaps are fetched concurrently
+	hostSlots     map[string]semaphore // Semaphore of the requests in flight per host, used with hostFetches
+	locTemplate   *template.Template   // Parsed indexLocTmpl, nil without one
+	purgeTemplate *template.Template   // Parsed purge URL, nil without a cache purge
+	written       map[string]int64     // Bytes stored per file by the current Split
+	runDate       string               // Date the current Split started, in the configured timezone
+	robotsSeen    map[string]string    // Digest of each robots.txt fetched by the current Split, by origin
+	includeRes    []*regexp.Regexp     // Compiled includes
+	excludeRes    []*regexp.Regexp     // Compiled excludes
+	dedupSeen     map[string]struct{}  // Locs kept so far by the current Split or Preview, nil without a dedup store
 }
 
 // NewSitemapSplitter creates a new SitemapSplitter instance
-func NewSitemapSplitter(path string, limit int) (*SitemapSplitter, error) {
+func NewSitemapSplitter(path string, limit int, opts ...Option) (*SitemapSplitter, error) {
 	if path == "" {
 		return nil, fmt.Errorf("sitemap path is required")
 	}
@@ -56,127 +190,496 @@ func NewSitemapSplitter(path string, limit int) (*SitemapSplitter, error) {
 		return nil, fmt.Errorf("limit must be greater than 0")
 	}
 
-	return &SitemapSplitter{
-		path:  path,
-		limit: limit,
-	}, nil
+	s := &SitemapSplitter{
+		path:       path,
+		limit:      limit,
+		location:   time.Local,
+		clock:      time.Now,
+		httpClient: http.DefaultClient,
+		indexName:  defaultIndexName,
+		filePerm:   0644,
+		logger:     slog.New(discardHandler{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.profile != "" {
+		return nil, fmt.Errorf("unknown profile %q", s.profile)
+	}
+	if s.encryptKey != nil {
+		var err error
+		if s.encryption, err = newAEAD(s.encryptKey); err != nil {
+			return nil, fmt.Errorf("invalid encryption key: %v", err)
+		}
+	}
+	if s.destination != "" && s.sink == nil {
+		if err := s.openDestination(); err != nil {
+			return nil, err
+		}
+	}
+	if s.sink == nil {
+		dir := s.outputDir
+		if dir == "" && !isRemote(path) {
+			dir = filepath.Dir(path)
+		}
+		s.sink = &FileSink{dir: dir, perm: s.filePerm}
+	}
+	if s.verifyUploads {
+		switch s.sink.(type) {
+		case StatSink, ReadSink:
+		default:
+			return nil, fmt.Errorf("upload verification requires a sink implementing StatSink or ReadSink")
+		}
+	}
+	if s.gzipOutput && s.dualOutput {
+		return nil, fmt.Errorf("gzip output and dual output cannot be combined")
+	}
+	if s.indexName == "" {
+		return nil, fmt.Errorf("index name must not be empty")
+	}
+	if s.modWindow < 0 {
+		return nil, fmt.Errorf("lastmod window must not be negative")
+	}
+	if !s.modAfter.IsZero() && !s.modBefore.IsZero() && !s.modAfter.Before(s.modBefore) {
+		return nil, fmt.Errorf("start of lastmod range must be before its end")
+	}
+	var err error
+	if s.indexLocTmpl != "" {
+		if s.locTemplate, err = template.New("index loc").Parse(s.indexLocTmpl); err != nil {
+			return nil, fmt.Errorf("invalid index loc template: %v", err)
+		}
+	}
+	if s.purge != nil {
+		if s.purgeTemplate, err = parsePurgeTemplate(s.purge); err != nil {
+			return nil, err
+		}
+	}
+	if s.includeRes, err = compilePatterns(s.includes); err != nil {
+		return nil, err
+	}
+	if s.excludeRes, err = compilePatterns(s.excludes); err != nil {
+		return nil, err
+	}
+	if s.nameTemplate != "" {
+		if err := checkNameTemplate(s.nameTemplate); err != nil {
+			return nil, err
+		}
+	}
+	if s.baseURL != "" {
+		if u, err := url.Parse(s.baseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("index base URL must be an absolute URL")
+		}
+	}
+	if s.logger == nil {
+		return nil, fmt.Errorf("logger must not be nil")
+	}
+	if s.location == nil {
+		return nil, fmt.Errorf("timezone must not be nil")
+	}
+	if s.requestDelay < 0 {
+		return nil, fmt.Errorf("request delay must not be negative")
+	}
+	if s.rateLimit < 0 {
+		return nil, fmt.Errorf("rate limit must not be negative")
+	}
+	if s.childFetches < 0 {
+		return nil, fmt.Errorf("child concurrency must not be negative")
+	}
+	if s.hostFetches < 0 {
+		return nil, fmt.Errorf("host concurrency must not be negative")
+	}
+	if t := s.phaseTimeouts; t.Fetch < 0 || t.Parse < 0 || t.Filter < 0 || t.Plan < 0 || t.Write < 0 || t.Upload < 0 {
+		return nil, fmt.Errorf("phase timeouts must not be negative")
+	}
+	if s.httpClient == nil {
+		return nil, fmt.Errorf("HTTP client must not be nil")
+	}
+	if s.sampleEvery < 0 {
+		return nil, fmt.Errorf("sample interval must not be negative")
+	}
+	if s.samplePercent < 0 || s.samplePercent > 100 {
+		return nil, fmt.Errorf("sample percentage must be between 0 and 100")
+	}
+	if s.byteHeadroom < 0 || s.byteHeadroom >= 100 {
+		return nil, fmt.Errorf("byte headroom must be at least 0 and below 100 percent")
+	}
+	if s.maxErrorRate < 0 || s.maxErrorRate > 100 {
+		return nil, fmt.Errorf("max error rate must be between 0 and 100")
+	}
+	if s.targetFiles < 0 {
+		return nil, fmt.Errorf("target file count must not be negative")
+	}
+	if s.maxBytes < 0 {
+		return nil, fmt.Errorf("max bytes per chunk must not be negative")
+	}
+	if s.maxTotalURLs < 0 || s.maxTotalBytes < 0 {
+		return nil, fmt.Errorf("quotas must not be negative")
+	}
+	if s.maxFiles < 0 {
+		return nil, fmt.Errorf("max files must not be negative")
+	}
+	if s.filesPerShard < 0 {
+		return nil, fmt.Errorf("files per shard must not be negative")
+	}
+	if s.deltaName != "" && s.stateFile == "" {
+		return nil, fmt.Errorf("delta sitemap requires a state file")
+	}
+	if s.skipUnchanged && s.stateFile == "" {
+		return nil, fmt.Errorf("skipping unchanged input requires a state file")
+	}
+	if s.diffWrites && s.stateFile == "" {
+		return nil, fmt.Errorf("differential writes require a state file")
+	}
+	if s.backupDir != "" {
+		if s.backupKeep < 1 {
+			return nil, fmt.Errorf("backup retention must be at least 1")
+		}
+		fs, ok := s.sink.(*FileSink)
+		if !ok {
+			return nil, fmt.Errorf("backups require the default file sink")
+		}
+		if within, err := isWithin(s.backupDir, fs.dir); err != nil || within {
+			return nil, fmt.Errorf("backup directory must not be the output directory or inside it")
+		}
+		if s.diffWrites || s.keepReleases > 0 {
+			return nil, fmt.Errorf("backups cannot be combined with differential writes or versioned publishing")
+		}
+	}
+	if s.keepReleases < 0 {
+		return nil, fmt.Errorf("release count must not be negative")
+	}
+	if s.keepReleases > 0 {
+		if _, ok := s.sink.(*FileSink); !ok {
+			return nil, fmt.Errorf("versioned publishing requires the default file sink")
+		}
+		if s.diffWrites {
+			return nil, fmt.Errorf("versioned publishing cannot be combined with differential writes")
+		}
+	}
+	if s.countAlert != nil {
+		if s.stateFile == "" {
+			return nil, fmt.Errorf("count alert requires a state file")
+		}
+		if s.countAlert.MaxChange < 0 {
+			return nil, fmt.Errorf("count alert change must not be negative")
+		}
+	}
+	if s.streaming {
+		if s.targetFiles > 0 {
+			return nil, fmt.Errorf("target file count needs the whole input and cannot be used with streaming")
+		}
+		for _, rule := range s.backfillRules {
+			if rule.kind == backfillSiblings {
+				return nil, fmt.Errorf("sibling lastmod backfill needs the whole input and cannot be used with streaming")
+			}
+		}
+		if s.clusterAlts {
+			return nil, fmt.Errorf("alternate grouping needs the whole input and cannot be used with streaming")
+		}
+		if s.checkLangs {
+			return nil, fmt.Errorf("hreflang validation needs the whole input and cannot be used with streaming")
+		}
+		if s.dupPolicy != DedupKeepAll {
+			return nil, fmt.Errorf("duplicate resolution needs the whole input and cannot be used with streaming")
+		}
+		if t := s.phaseTimeouts; t.Parse > 0 || t.Filter > 0 || t.Plan > 0 || t.Write > 0 {
+			return nil, fmt.Errorf("parse, filter, plan and write timeouts need separate phases and cannot be used with streaming")
+		}
+	}
+
+	return s, nil
+}
+
+// skip records that u was dropped for reason, keeping its loc as an example
+// while fewer than the configured number have been collected
+func (s *SitemapSplitter) skip(u URL, reason SkipReason) {
+	s.logger.Debug("dropped URL", "phase", "filter", "loc", u.Loc, "reason", reason)
+	s.result.Skipped[reason]++
+	if len(s.result.SkipExamples[reason]) < s.skipExamples {
+		s.result.SkipExamples[reason] = append(s.result.SkipExamples[reason], u.Loc)
+	}
 }
 
-// Split reads the sitemap and splits it into multiple files
+// Split reads the sitemap and splits it into multiple files. A path that is
+// an http or https URL is downloaded first, and its outputs go to the current
+// directory unless WithOutputDir or WithSink says otherwise.
 func (s *SitemapSplitter) Split() error {
-	// Read and parse the original sitemap
-	data, err := ioutil.ReadFile(s.path)
-	if err != nil {
-		return fmt.Errorf("error reading sitemap file: %v", err)
+	return s.SplitContext(context.Background())
+}
+
+// SplitFrom splits the sitemap document read from r instead of the file at
+// the splitter's path, e.g. an HTTP response body or a pipe. The path still
+// determines the output directory and chunk names but does not need to
+// exist. With WithSkipUnchanged the input is buffered in memory to hash it
+// before anything is written, so that combination is rejected in streaming
+// mode.
+func (s *SitemapSplitter) SplitFrom(r io.Reader) error {
+	if r == nil {
+		return fmt.Errorf("input reader must not be nil")
 	}
+	err := s.splitReader(r, time.Time{})
+	s.observe(err)
+	return err
+}
 
-	var urlset URLSet
-	if err := xml.Unmarshal(data, &urlset); err != nil {
-		return fmt.Errorf("error parsing XML: %v", err)
+// splitReader splits the sitemap read from r, recording modTime as the
+// source modification time of its URLs
+func (s *SitemapSplitter) splitReader(r io.Reader, modTime time.Time) error {
+	if s.skipUnchanged {
+		if s.streaming {
+			return fmt.Errorf("skipping unchanged input cannot be used with streaming from a reader")
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("error reading sitemap: %v", err)
+		}
+		r = bytes.NewReader(data)
 	}
 
-	if len(urlset.URLs) == 0 {
-		return fmt.Errorf("no URLs found in sitemap")
+	s.reader, s.readerModTime = r, modTime
+	defer func() { s.reader = nil }()
+	return s.split()
+}
+
+// warn records a data-quality warning about the entry with the given loc,
+// keeping the loc while fewer than the configured number have been collected
+func (s *SitemapSplitter) warn(loc string, code WarningCode) {
+	s.logger.Debug("data-quality warning", "phase", "filter", "loc", loc, "code", code)
+	s.result.WarningCounts[code]++
+	if len(s.result.Warnings) < s.maxWarnings {
+		s.result.Warnings = append(s.result.Warnings, Warning{Code: code, Loc: loc})
 	}
+}
 
-	// Get directory and filename from path
-	dir := filepath.Dir(s.path)
-	filename := filepath.Base(s.path)
-	baseFilename := filename[:len(filename)-len(filepath.Ext(filename))]
+// reset clears the per-run state and result before a run
+func (s *SitemapSplitter) reset() {
+	s.written = make(map[string]int64)
+	s.robotsSeen = make(map[string]string)
+	s.runDate = s.now().Format("2006-01-02")
+	s.dedupSeen = nil
+	if s.dedupStore != nil {
+		s.dedupSeen = make(map[string]struct{})
+	}
+	s.result = &Result{
+		DryRun:        s.dryRun,
+		Skipped:       make(map[SkipReason]int),
+		SkipExamples:  make(map[SkipReason][]string),
+		WarningCounts: make(map[WarningCode]int),
+	}
+}
 
-	var sitemapFiles []struct {
-		BaseURL     string
-		Name        string
-		LastModDate string
+// split runs a split of the configured input
+func (s *SitemapSplitter) split() error {
+	started := time.Now()
+	s.reset()
+	if s.dryRun {
+		s.logger.Info("dry run, nothing is written", "phase", "plan", "input", s.path)
 	}
 
-	// Split URLs into chunks
-	for i := 0; i*s.limit < len(urlset.URLs); i++ {
-		start := i * s.limit
-		end := (i + 1) * s.limit
-		if end > len(urlset.URLs) {
-			end = len(urlset.URLs)
+	var prev *runState
+	if s.stateFile != "" {
+		var err error
+		if prev, err = loadState(s.stateFile); err != nil {
+			return err
 		}
+	}
 
-		chunk := urlset.URLs[start:end]
-		if len(chunk) == 0 {
-			break
+	var inputHash string
+	if s.skipUnchanged {
+		var err error
+		if inputHash, err = s.hashInput(); err != nil {
+			return fmt.Errorf("error reading sitemap file: %v", err)
 		}
-
-		// Create new URLSet for this chunk
-		newURLSet := URLSet{
-			XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9",
-			XHTML: "http://www.w3.org/1999/xhtml",
-			URLs:  chunk,
+		unchanged := inputHash == prev.InputHash && s.optionsFingerprint() == prev.Options
+		if unchanged {
+			if unchanged, err = s.robotsUnchanged(prev); err != nil {
+				return err
+			}
 		}
-
-		// Generate sitemap name
-		sitemapName := fmt.Sprintf("%s-%d.xml", baseFilename, i+1)
-
-		// Get base URL from the last URL in chunk
-		lastURL := chunk[len(chunk)-1]
-		parsedURL, err := url.Parse(lastURL.Loc)
-		if err != nil {
-			return fmt.Errorf("error parsing URL: %v", err)
+		if unchanged {
+			s.result.UpToDate = true
+			s.logger.Info("input unchanged since the previous run, skipping", "phase", "parse", "input", s.path)
+			return nil
 		}
-		baseURL := fmt.Sprintf("%s://%s/", parsedURL.Scheme, parsedURL.Host)
+	}
 
-		// Get last modification date
-		lastMod := lastURL.LastMod
-		if lastMod == "" {
-			lastMod = time.Now().Format(time.RFC3339)
-		}
+	s.result.Hosts = make(map[string]int)
+	s.result.Sections = make(map[string]int)
 
-		sitemapFiles = append(sitemapFiles, struct {
-			BaseURL     string
-			Name        string
-			LastModDate string
-		}{
-			BaseURL:     baseURL,
-			Name:        sitemapName,
-			LastModDate: lastMod,
-		})
+	r := s.newRun(prev, inputHash)
+	if s.keepReleases > 0 && !s.dryRun {
+		return s.publishRelease(func() error { return r.execute(started) }, func() error { return r.commit(started) })
+	}
+	if err := r.execute(started); err != nil {
+		return err
+	}
+	return r.commit(started)
+}
 
-		// Write sitemap file
-		xmlData, err := xml.MarshalIndent(newURLSet, "", "  ")
-		if err != nil {
-			return fmt.Errorf("error marshaling XML: %v", err)
+// execute splits the input and writes every output of the run
+func (r *run) execute(started time.Time) error {
+	s := r.s
+	defer r.endPhase()
+	if s.streaming {
+		if err := s.splitStream(r); err != nil {
+			return err
 		}
-
-		xmlHeader := []byte(xml.Header)
-		xmlData = append(xmlHeader, xmlData...)
-
-		outputPath := filepath.Join(dir, sitemapName)
-		if err := os.WriteFile(outputPath, xmlData, 0644); err != nil {
-			return fmt.Errorf("error writing sitemap file: %v", err)
+	} else {
+		if err := s.splitAll(r); err != nil {
+			return err
 		}
 	}
+	return r.finish(started)
+}
 
-	// Create sitemap index
-	sitemapIndex := SitemapIndex{
-		XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9",
+// splitAll reads the whole input, plans every chunk and checks the plan
+// before writing anything
+func (s *SitemapSplitter) splitAll(r *run) error {
+	// Read and parse the original sitemap
+	if err := r.startPhase("parse", s.phaseTimeouts.Parse); err != nil {
+		return err
+	}
+	parseStarted := time.Now()
+	urls, err := s.readURLs()
+	if err != nil {
+		return err
 	}
+	s.logger.Info("read sitemap", "phase", "parse", "input", s.path, "urls", len(urls), "duration", time.Since(parseStarted))
 
-	for _, file := range sitemapFiles {
-		sitemapIndex.Sitemaps = append(sitemapIndex.Sitemaps, Sitemap{
-			Loc:     file.BaseURL + file.Name,
-			LastMod: file.LastModDate,
-		})
+	inputEmpty := len(urls) == 0
+	if inputEmpty && s.emptyInput != EmptyInputAllow {
+		return fmt.Errorf("no URLs found in sitemap")
 	}
 
-	// Write sitemap index
-	xmlData, err := xml.MarshalIndent(sitemapIndex, "", "  ")
+	if err := r.startPhase("filter", s.phaseTimeouts.Filter); err != nil {
+		return err
+	}
+	urls, filter, err := s.filterURLs(urls)
 	if err != nil {
-		return fmt.Errorf("error marshaling XML: %v", err)
+		return err
+	}
+	s.logger.Info("filtered URLs", "phase", "filter", "kept", len(urls), "dropped", s.result.Dropped())
+
+	if err := s.checkErrorRate(filter.read); err != nil {
+		return err
+	}
+	if len(urls) == 0 && !inputEmpty {
+		return fmt.Errorf("no URLs left after filtering")
+	}
+	if err := s.checkCount(r.prev, len(urls)); err != nil {
+		return err
 	}
 
-	xmlHeader := []byte(xml.Header)
-	xmlData = append(xmlHeader, xmlData...)
+	if err := s.prepare(urls); err != nil {
+		return err
+	}
 
-	indexPath := filepath.Join(dir, "sitemap-index.xml")
-	if err := os.WriteFile(indexPath, xmlData, 0644); err != nil {
-		return fmt.Errorf("error writing sitemap index: %v", err)
+	// Split URLs into chunks
+	if err := r.startPhase("plan", s.phaseTimeouts.Plan); err != nil {
+		return err
+	}
+	chunks, err := s.planChunks(urls, inputBaseName(s.path))
+	if err != nil {
+		return err
+	}
+	s.logger.Info("planned chunks", "phase", "plan", "chunks", len(chunks), "urls", len(urls))
+	if !s.noIndex {
+		if err := checkIndexEntries(len(chunks)); err != nil {
+			return err
+		}
+		if err := s.checkPlannedIndex(chunks); err != nil {
+			return err
+		}
+	}
+	if err := s.checkFileCount(len(chunks)); err != nil {
+		return err
+	}
+	names := s.outputNames(chunks)
+	if err := checkCollisions(names...); err != nil {
+		return err
+	}
+	if err := s.checkInputOverwrite(names); err != nil {
+		return err
+	}
+	if err := s.checkQuotas(chunks); err != nil {
+		return err
+	}
+	if s.diskCheck {
+		sizes, err := s.estimateOutputSizes(chunks)
+		if err != nil {
+			return err
+		}
+		if err := s.checkDiskSpace(sizes); err != nil {
+			return err
+		}
 	}
 
+	// The write phase lasts until the run finishes
+	if err := r.startPhase("write", s.phaseTimeouts.Write); err != nil {
+		return err
+	}
+	for _, c := range chunks {
+		if err := r.writeChunk(c); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// filterURLs applies the URL filter to urls and resolves duplicate locs. The
+// dedup store sees the entries chosen by the duplicate policy, so a loc it
+// already holds is dropped only after the policy has merged its entries.
+func (s *SitemapSplitter) filterURLs(urls []URL) ([]URL, *urlFilter, error) {
+	filter, err := s.newURLFilter()
+	if err != nil {
+		return nil, nil, err
+	}
+	kept := urls[:0]
+	for _, u := range urls {
+		ok, err := filter.keep(&u)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			kept = append(kept, u)
+		}
+	}
+	kept = s.resolveDuplicates(kept)
+	if s.dupPolicy != DedupKeepAll {
+		resolved := kept
+		kept = kept[:0]
+		for _, u := range resolved {
+			ok, err := s.unpublished(u)
+			if err != nil {
+				return nil, nil, err
+			}
+			if ok {
+				kept = append(kept, u)
+			}
+		}
+	}
+	if s.checkLangs {
+		s.checkHreflang(kept)
+	}
+	return kept, filter, nil
+}
+
+// prepare fills in and normalizes the lastmod values of output URLs, counts
+// them into the result and exports them
+func (s *SitemapSplitter) prepare(urls []URL) error {
+	s.fillLastMod(urls)
+	for _, u := range urls {
+		s.checkNewsAge(u)
+	}
+
+	s.result.countDistribution(urls)
+	return s.exportJSONL(urls)
+}
+
+// fillLastMod backfills and normalizes the lastmod values of urls
+func (s *SitemapSplitter) fillLastMod(urls []URL) {
+	s.backfillLastMod(urls)
+	for i := range urls {
+		s.normalizeLastMod(&urls[i])
+	}
+}
@@ -0,0 +1,137 @@
+package sitemapsplitter
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitFromMatchesSplit(t *testing.T) {
+	in := streamInput(30)
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"plain", nil},
+		{"streaming", []Option{WithStreaming()}},
+		{"gzip output", []Option{WithGzipOutput(true)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			fromFile, fromReader := filepath.Join(dir, "file"), filepath.Join(dir, "reader")
+			input := filepath.Join(dir, "in.xml")
+			if err := os.WriteFile(input, []byte(in), 0644); err != nil {
+				t.Fatal(err)
+			}
+			s, err := NewSitemapSplitter(input, 10, append([]Option{WithOutputDir(fromFile)}, tt.opts...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Split(); err != nil {
+				t.Fatal(err)
+			}
+			// The path names the output but does not need to exist
+			s, err = NewSitemapSplitter(filepath.Join(dir, "missing", "in.xml"), 10, append([]Option{WithOutputDir(fromReader)}, tt.opts...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+				t.Fatal(err)
+			}
+
+			want, err := os.ReadDir(fromFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range want {
+				a, _ := os.ReadFile(filepath.Join(fromFile, e.Name()))
+				b, err := os.ReadFile(filepath.Join(fromReader, e.Name()))
+				if err != nil {
+					t.Errorf("SplitFrom did not write %s", e.Name())
+					continue
+				}
+				if !bytes.Equal(a, b) {
+					t.Errorf("%s differs between Split and SplitFrom", e.Name())
+				}
+			}
+		})
+	}
+
+	s, err := NewSitemapSplitter("in.xml", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SplitFrom(nil); err == nil {
+		t.Error("SplitFrom accepted a nil reader")
+	}
+}
+
+func TestNewSitemapSplitterValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		limit   int
+		opts    []Option
+		wantErr string // Part of the error, empty for none
+	}{
+		{"defaults", "in.xml", 10, nil, ""},
+		{"all of them", "in.xml", 10, []Option{WithLastModFormat(LastModDate), WithTimezone(time.UTC), WithMaxFiles(5), WithSampleEvery(2), WithMaxBytes(1 << 20), WithOutputDir("out"), WithFilePermissions(0600), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))}, ""},
+		{"missing path", "", 10, nil, "path is required"},
+		{"zero limit", "in.xml", 0, nil, "limit must be greater than 0"},
+		{"nil timezone", "in.xml", 10, []Option{WithTimezone(nil)}, "timezone must not be nil"},
+		{"nil logger", "in.xml", 10, []Option{WithLogger(nil)}, "logger must not be nil"},
+		{"nil HTTP client", "in.xml", 10, []Option{WithHTTPClient(nil)}, "HTTP client must not be nil"},
+		{"empty index name", "in.xml", 10, []Option{WithIndexName("")}, "index name must not be empty"},
+		{"negative max files", "in.xml", 10, []Option{WithMaxFiles(-1)}, "max files must not be negative"},
+		{"sample percentage", "in.xml", 10, []Option{WithSamplePercent(101, 0)}, "between 0 and 100"},
+		{"relative base URL", "in.xml", 10, []Option{WithIndexBaseURL("/sitemaps")}, "absolute URL"},
+		{"delta without state", "in.xml", 10, []Option{WithDeltaSitemap("")}, "requires a state file"},
+		{"gzip and dual output", "in.xml", 10, []Option{WithGzipOutput(true), WithDualOutput(false)}, "cannot be combined"},
+		{"later option wins", "in.xml", 10, []Option{WithIndexName(""), WithIndexName("index.xml")}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSitemapSplitter(tt.path, tt.limit, tt.opts...)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("constructor failed: %v", err)
+				}
+				if s == nil {
+					t.Fatal("constructor returned no splitter")
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("constructor error %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFilePermissions(t *testing.T) {
+	dir := t.TempDir()
+	input, out := filepath.Join(dir, "in.xml"), filepath.Join(dir, "out")
+	writeSitemap(t, input, "https://example.com/a", "https://example.com/b")
+	s, err := NewSitemapSplitter(input, 1, WithOutputDir(out), WithFilePermissions(0600))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Split(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"in-1.xml", "in-2.xml", "sitemap-index.xml"} {
+		info, err := os.Stat(filepath.Join(out, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mode := info.Mode().Perm(); mode != 0600 {
+			t.Errorf("%s has mode %v, want %v", name, mode, os.FileMode(0600))
+		}
+	}
+}
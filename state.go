@@ -0,0 +1,134 @@
+package sitemapsplitter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// runState is persisted between runs so that changes can be detected
+type runState struct {
+	InputHash string            `json:"input_hash,omitempty"` // SHA-256 of the input file
+	Options   string            `json:"options,omitempty"`    // Fingerprint of the options that shape the output
+	URLs      map[string]string `json:"urls"`                 // Fingerprint of each URL keyed by loc
+	Count     int               `json:"count,omitempty"`      // URLs published, duplicates included
+	Files     map[string]string `json:"files,omitempty"`      // SHA-256 of each written chunk and index, by name
+	Robots    map[string]string `json:"robots,omitempty"`     // Digest of each robots.txt obeyed, by origin
+}
+
+// loadState reads the state file at path. A missing file yields an empty state.
+func loadState(path string) (*runState, error) {
+	state := &runState{URLs: make(map[string]string)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading state file: %v", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("error parsing state file: %v", err)
+	}
+	if state.URLs == nil {
+		state.URLs = make(map[string]string)
+	}
+
+	return state, nil
+}
+
+// save writes the state to path
+func (st *runState) save(path string) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding state file: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing state file: %v", err)
+	}
+	return nil
+}
+
+// urlFingerprint returns a digest of the URL as it is written to the output,
+// including extension elements
+func urlFingerprint(u URL) string {
+	data, err := xml.Marshal(u)
+	if err != nil {
+		data = []byte(u.Loc)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hashFile returns the hex SHA-256 digest of the file at path
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return hashReader(f)
+}
+
+// hashReader returns the hex-encoded SHA-256 digest of everything read from r
+func hashReader(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// optionsFingerprint returns a digest of every setting that influences the
+// output, so a change of options invalidates the skip-if-unchanged cache
+func (s *SitemapSplitter) optionsFingerprint() string {
+	h := sha256.New()
+	fmt.Fprintln(h, s.limit, s.lastModFormat, s.location, s.deltaName, s.newsName, s.filesPerShard, s.maxFiles)
+	fmt.Fprintln(h, s.sampleEvery, s.samplePercent, s.sampleSeed, s.robotsAgent, s.canonicalHost)
+	fmt.Fprintln(h, s.jsonlExport != nil, s.dedupStore != nil, s.decodeLimits, s.dualOutput, s.preferGzip, s.omitHeader, s.selfClosing, s.noFragments, s.lowerPaths, s.normalizeURLs, s.targetFiles, s.indexOrder, s.indexLess != nil, s.streaming, s.validate, s.maxErrorRate, s.maxBytes, s.byteHeadroom, s.clusterAlts, s.outputFormat, s.dupPolicy, s.nameTemplate)
+	fmt.Fprintln(h, s.indexName, s.baseURL, s.gzipOutput, s.noIndex, s.indexLocTmpl, s.keepReleases)
+	if s.encryptKey != nil {
+		fmt.Fprintln(h, sha256.Sum256(s.encryptKey))
+	}
+	if s.robotsAgent != "" {
+		// Marks states that record robots.txt digests, so that older ones
+		// are rebuilt once
+		fmt.Fprintln(h, "robots digests")
+	}
+	if fs, ok := s.sink.(*FileSink); ok {
+		fmt.Fprintln(h, fs.dir, fs.perm)
+	} else {
+		fmt.Fprintf(h, "%T\n", s.sink)
+	}
+	for _, rule := range s.backfillRules {
+		fmt.Fprintln(h, rule.kind, rule.lookup != nil, rule.value.UnixNano())
+	}
+	for _, rule := range s.redactRules {
+		fmt.Fprintf(h, "%q=%q\n", rule.Param, rule.Mask)
+	}
+	fmt.Fprintf(h, "%q\n", s.allowPrefixes)
+	fmt.Fprintf(h, "%q %q\n", s.includes, s.excludes)
+	fmt.Fprintln(h, s.modAfter.UnixNano(), s.modBefore.UnixNano(), s.modWindow)
+	// Windows and date-stamped names move with the calendar
+	if s.modWindow > 0 || strings.Contains(s.nameTemplate, "{date}") {
+		fmt.Fprintln(h, s.runDate)
+	}
+	// News freshness moves with the clock
+	if s.newsName != "" {
+		fmt.Fprintln(h, s.now().Truncate(time.Hour).Unix())
+	}
+	// The lists are maintained outside the input, so their content counts
+	for _, name := range []string{s.excludeFile, s.allowFile} {
+		if name != "" {
+			data, err := os.ReadFile(name)
+			fmt.Fprintln(h, name, sha256.Sum256(data), err != nil)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,154 @@
+package sitemapsplitter
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDeltaSitemap(t *testing.T) {
+	urlset := func(urls ...string) string {
+		return `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` + strings.Join(urls, "") + `</urlset>`
+	}
+	first := urlset(
+		`<url><loc>https://example.com/a</loc><lastmod>2024-06-01</lastmod></url>`,
+		`<url><loc>https://example.com/b</loc><lastmod>2024-06-01</lastmod></url>`,
+		`<url><loc>https://example.com/c</loc></url>`,
+	)
+	second := urlset(
+		`<url><loc>https://example.com/a</loc><lastmod>2024-06-01</lastmod></url>`,
+		`<url><loc>https://example.com/b</loc><lastmod>2024-06-02</lastmod></url>`,
+		`<url><loc>https://example.com/d</loc></url>`,
+	)
+
+	tests := []struct {
+		name      string
+		streaming bool
+		runs      []string // Inputs of consecutive runs
+		want      []string // Locs of the last run's delta sitemap
+	}{
+		{"first run", false, []string{first}, []string{"/a", "/b", "/c"}},
+		{"changed and added", false, []string{first, second}, []string{"/b", "/d"}},
+		{"unchanged", false, []string{first, first}, nil},
+		{"streaming", true, []string{first, second}, []string{"/b", "/d"}},
+		{"streaming unchanged", true, []string{first, first}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := NewMemorySink()
+			opts := []Option{WithSink(sink), WithStateFile(filepath.Join(t.TempDir(), "state.json")), WithDeltaSitemap("")}
+			if tt.streaming {
+				opts = append(opts, WithStreaming())
+			}
+			s, err := NewSitemapSplitter("in.xml", 2, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, in := range tt.runs {
+				if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			data, ok := sink.File("changed.xml")
+			if !ok {
+				t.Fatal("delta sitemap not written")
+			}
+			delta := string(data)
+			for _, loc := range []string{"/a", "/b", "/c", "/d"} {
+				want := slices.Contains(tt.want, loc)
+				if got := strings.Contains(delta, "<loc>https://example.com"+loc+"</loc>"); got != want {
+					t.Errorf("delta sitemap lists %s: %v, want %v:\n%s", loc, got, want, delta)
+				}
+			}
+		})
+	}
+}
+
+func TestSkipUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	input, state := filepath.Join(dir, "in.xml"), filepath.Join(dir, "state.json")
+	steps := []struct {
+		name     string
+		locs     []string // Input of the run, nil to keep the previous one
+		limit    int
+		upToDate bool
+	}{
+		{"first run", []string{"https://example.com/a", "https://example.com/b"}, 10, false},
+		{"same input", nil, 10, true},
+		{"rewritten with the same content", []string{"https://example.com/a", "https://example.com/b"}, 10, true},
+		{"changed input", []string{"https://example.com/a", "https://example.com/c"}, 10, false},
+		{"changed options", nil, 1, false},
+		{"unchanged again", nil, 1, true},
+	}
+	for _, step := range steps {
+		if step.locs != nil {
+			writeSitemap(t, input, step.locs...)
+		}
+		s, err := NewSitemapSplitter(input, step.limit, WithOutputDir(filepath.Join(dir, "out")), WithStateFile(state), WithSkipUnchanged())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Split(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if got := s.LastResult().UpToDate; got != step.upToDate {
+			t.Errorf("%s: up to date %v, want %v", step.name, got, step.upToDate)
+		}
+	}
+}
+
+// recordingSink is a MemorySink that remembers the names written since the
+// last reset
+type recordingSink struct {
+	*MemorySink
+	written []string
+}
+
+func (r *recordingSink) Write(name string, rd io.Reader) error {
+	r.written = append(r.written, name)
+	return r.MemorySink.Write(name, rd)
+}
+
+func TestDifferentialWrites(t *testing.T) {
+	urlset := func(lastMods ...string) string {
+		var b strings.Builder
+		b.WriteString(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+		for i, lastMod := range lastMods {
+			fmt.Fprintf(&b, "<url><loc>https://example.com/%d</loc><lastmod>%s</lastmod></url>", i, lastMod)
+		}
+		b.WriteString("</urlset>")
+		return b.String()
+	}
+	steps := []struct {
+		name      string
+		in        string
+		written   []string
+		unchanged int
+	}{
+		{"first run", urlset("2024-06-01", "2024-06-01", "2024-06-01"), []string{"in-1.xml", "in-2.xml", "sitemap-index.xml"}, 0},
+		{"same input", urlset("2024-06-01", "2024-06-01", "2024-06-01"), nil, 3},
+		{"second chunk changed", urlset("2024-06-01", "2024-06-01", "2024-06-02"), []string{"in-2.xml", "sitemap-index.xml"}, 1},
+		{"index entry unchanged", urlset("2024-05-01", "2024-06-01", "2024-06-02"), []string{"in-1.xml"}, 2},
+	}
+	sink := &recordingSink{MemorySink: NewMemorySink()}
+	s, err := NewSitemapSplitter("in.xml", 2, WithSink(sink), WithStateFile(filepath.Join(t.TempDir(), "state.json")), WithDifferentialWrites())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range steps {
+		sink.written = nil
+		if err := s.SplitFrom(strings.NewReader(step.in)); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if !slices.Equal(sink.written, step.written) {
+			t.Errorf("%s: wrote %v, want %v", step.name, sink.written, step.written)
+		}
+		if got := s.LastResult().UnchangedFiles; got != step.unchanged {
+			t.Errorf("%s: %d unchanged files, want %d", step.name, got, step.unchanged)
+		}
+	}
+}
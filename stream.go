@@ -0,0 +1,92 @@
+package sitemapsplitter
+
+import "fmt"
+
+// splitStream decodes the input one <url> element at a time and writes each
+// chunk as soon as it is full, so memory use is bounded by a single chunk
+// rather than by the input. Checks that a planned split makes up front are
+// made chunk by chunk, and chunks written before a failing check remain on
+// disk.
+func (s *SitemapSplitter) splitStream(r *run) error {
+	filter, err := s.newURLFilter()
+	if err != nil {
+		return err
+	}
+	baseFilename := inputBaseName(s.path)
+	read := 0
+	pending := make([]URL, 0, s.limit)
+
+	if err := s.checkDiskSpace(s.estimateStreamedSizes()); err != nil {
+		return err
+	}
+
+	var sizer *chunkSizer
+	if s.maxBytes > 0 {
+		sizer = s.newChunkSizer()
+	}
+
+	flush := func() error {
+		c := chunk{name: s.chunkName(baseFilename, len(r.entries), 0, pending), urls: pending}
+		if err := r.checkStreamed(c); err != nil {
+			return err
+		}
+		if err := r.writeChunk(c); err != nil {
+			return err
+		}
+		pending = pending[:0]
+		if sizer != nil {
+			sizer.reset()
+		}
+		return nil
+	}
+
+	err = s.eachURL(func(u URL) error {
+		read++
+		ok, err := filter.keep(&u)
+		if err != nil || !ok {
+			return err
+		}
+
+		// URLs are prepared one by one so that their final size is known
+		one := []URL{u}
+		if err := s.prepare(one); err != nil {
+			return err
+		}
+		u = one[0]
+		if sizer != nil {
+			if err := sizer.fitURL(u, len(pending) == 0, flush); err != nil {
+				return err
+			}
+		}
+
+		pending = append(pending, u)
+		if len(pending) < s.limit {
+			return nil
+		}
+		return flush()
+	})
+	if err != nil {
+		return err
+	}
+	s.logger.Info("read sitemap", "phase", "parse", "input", s.path, "urls", read)
+
+	if read == 0 && s.emptyInput != EmptyInputAllow {
+		return fmt.Errorf("no URLs found in sitemap")
+	}
+	if len(pending) > 0 {
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+	s.logger.Info("filtered URLs", "phase", "filter", "kept", r.urls, "dropped", s.result.Dropped())
+	if err := s.checkErrorRate(read); err != nil {
+		return err
+	}
+	if r.urls == 0 && read > 0 {
+		return fmt.Errorf("no URLs left after filtering")
+	}
+
+	// The count is only known once every chunk is written, but failing here
+	// still keeps the index and state of the previous run in place
+	return s.checkCount(r.prev, r.urls)
+}
@@ -0,0 +1,90 @@
+package sitemapsplitter
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// streamInput returns a sitemap of n URLs with images, repeated locs, mixed
+// case hosts and some invalid entries
+func streamInput(n int) string {
+	var b strings.Builder
+	b.WriteString(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:image="` + ImageNamespace + `">`)
+	for i := range n {
+		fmt.Fprintf(&b, "<url><loc>https://Example.com/%s/page-%d</loc><lastmod>2024-06-%02d</lastmod>", []string{"blog", "shop", "staging"}[i%3], i%(n-5), i%28+1)
+		if i%4 == 0 {
+			fmt.Fprintf(&b, "<image:image><image:loc>https://example.com/img/%d.jpg</image:loc></image:image>", i)
+		}
+		b.WriteString("</url>")
+		if i%9 == 0 {
+			b.WriteString("<url><loc>not a url</loc></url>")
+		}
+	}
+	b.WriteString("</urlset>")
+	return b.String()
+}
+
+func TestStreamingMatchesPlannedSplit(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+		opts  func() []Option // Called per split, so that stores are not shared
+	}{
+		{"limit", 7, nil},
+		{"byte limit", 50000, func() []Option { return []Option{WithMaxBytes(2500)} }},
+		{"byte limit with headroom", 50000, func() []Option { return []Option{WithMaxBytes(3000), WithByteHeadroom(20)} }},
+		{"gzip", 10, func() []Option { return []Option{WithGzipOutput(true)} }},
+		{"dual output", 10, func() []Option { return []Option{WithDualOutput(true)} }},
+		{"text", 12, func() []Option { return []Option{WithOutputFormat(FormatText)} }},
+		{"sharding", 3, func() []Option { return []Option{WithSharding(4)} }},
+		{"filters", 8, func() []Option {
+			return []Option{WithExclude("/staging/"), WithURLNormalization(), WithMaxErrorRate(50)}
+		}},
+		{"dedup store", 8, func() []Option { return []Option{WithDedupStore(NewMemoryDedupStore())} }},
+		{"sampling", 5, func() []Option { return []Option{WithSampleEvery(2)} }},
+		{"name template", 6, func() []Option { return []Option{WithFileNameTemplate("{base}-{section}-{index}.xml")} }},
+		{"no index", 9, func() []Option { return []Option{WithoutIndex()} }},
+	}
+	in := streamInput(60)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			split := func(streaming bool) *MemorySink {
+				sink := NewMemorySink()
+				opts := []Option{WithSink(sink), WithIndexBaseURL("https://example.com/sitemaps/")}
+				if tt.opts != nil {
+					opts = append(opts, tt.opts()...)
+				}
+				if streaming {
+					opts = append(opts, WithStreaming())
+				}
+				s, err := NewSitemapSplitter(filepath.Join(t.TempDir(), "in.xml"), tt.limit, opts...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+					t.Fatalf("streaming=%v: %v", streaming, err)
+				}
+				return sink
+			}
+			planned, streamed := split(false), split(true)
+
+			names := planned.Names()
+			if len(names) < 3 {
+				t.Fatalf("planned split wrote %v, want several files", names)
+			}
+			if got := streamed.Names(); strings.Join(got, " ") != strings.Join(names, " ") {
+				t.Fatalf("streaming wrote %v, planned split wrote %v", got, names)
+			}
+			for _, name := range names {
+				want, _ := planned.File(name)
+				got, _ := streamed.File(name)
+				if !bytes.Equal(got, want) {
+					t.Errorf("%s differs:\nstreamed:\n%s\nplanned:\n%s", name, got, want)
+				}
+			}
+		})
+	}
+}
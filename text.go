@@ -0,0 +1,88 @@
+package sitemapsplitter
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// OutputFormat selects the file format of the chunks
+type OutputFormat int
+
+const (
+	// FormatXML writes chunks as XML urlsets
+	FormatXML OutputFormat = iota
+	// FormatText writes chunks as text sitemaps with one loc per line. All
+	// other fields and extensions are dropped. The index and the delta
+	// sitemap remain XML.
+	FormatText
+)
+
+// chunkExt returns the file extension of chunks in the configured format
+func (s *SitemapSplitter) chunkExt() string {
+	if s.outputFormat == FormatText {
+		return ".txt"
+	}
+	return ".xml"
+}
+
+// encodeChunk writes the chunk file holding urls to w in the configured
+// format
+func (s *SitemapSplitter) encodeChunk(w io.Writer, urls []URL) error {
+	if s.outputFormat == FormatText {
+		return encodeText(w, urls)
+	}
+	return s.encodeURLSet(w, newURLSet(urls))
+}
+
+// encodeText writes the loc of every URL on a line of its own
+func encodeText(w io.Writer, urls []URL) error {
+	bw := bufio.NewWriter(w)
+	for _, u := range urls {
+		bw.WriteString(u.Loc)
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// utf8BOM is the byte order mark some editors put before UTF-8 text
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// sniffText reports whether r holds a text sitemap rather than XML, judged by
+// its first non-blank character, and returns a reader of r's full content.
+// Blank documents count as XML.
+func sniffText(r io.Reader) (io.Reader, bool) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(512)
+	head = bytes.TrimLeft(bytes.TrimPrefix(head, utf8BOM), " \t\r\n")
+	return br, len(head) > 0 && head[0] != '<'
+}
+
+// decodeText reads a text sitemap from r, calling fn with a URL for every
+// non-blank line
+func (s *SitemapSplitter) decodeText(r io.Reader, name string, modTime time.Time, fn func(URL) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	first := true
+	for scanner.Scan() {
+		line := scanner.Text()
+		if first {
+			line = strings.TrimPrefix(line, string(utf8BOM))
+			first = false
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if err := fn(URL{Loc: line, sourceModTime: modTime}); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading text sitemap %s: %v", name, err)
+	}
+	return nil
+}
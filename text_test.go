@@ -0,0 +1,51 @@
+package sitemapsplitter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTextSitemaps(t *testing.T) {
+	const xmlInput = `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc><lastmod>2024-05-01</lastmod></url><url><loc>https://example.com/b</loc></url><url><loc>https://example.com/c</loc></url></urlset>`
+	tests := []struct {
+		name   string
+		in     string
+		format OutputFormat
+		want   map[string]string // Content of each chunk, checked for a substring with XML output
+	}{
+		{"text to text", "https://example.com/a\nhttps://example.com/b\nhttps://example.com/c\n", FormatText,
+			map[string]string{"in-1.txt": "https://example.com/a\nhttps://example.com/b\n", "in-2.txt": "https://example.com/c\n"}},
+		{"blank lines and BOM", "\ufeff  https://example.com/a\r\n\n\thttps://example.com/b \nhttps://example.com/c", FormatText,
+			map[string]string{"in-1.txt": "https://example.com/a\nhttps://example.com/b\n", "in-2.txt": "https://example.com/c\n"}},
+		{"xml to text", xmlInput, FormatText,
+			map[string]string{"in-1.txt": "https://example.com/a\nhttps://example.com/b\n", "in-2.txt": "https://example.com/c\n"}},
+		{"text to xml", "https://example.com/a\nhttps://example.com/b\nhttps://example.com/c\n", FormatXML,
+			map[string]string{"in-1.xml": "<loc>https://example.com/b</loc>", "in-2.xml": "<loc>https://example.com/c</loc>"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := NewMemorySink()
+			s, err := NewSitemapSplitter("in.xml", 2, WithSink(sink), WithOutputFormat(tt.format))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SplitFrom(strings.NewReader(tt.in)); err != nil {
+				t.Fatal(err)
+			}
+
+			for name, want := range tt.want {
+				data, ok := sink.File(name)
+				if !ok {
+					t.Fatalf("%s not written, got %v", name, sink.Names())
+				}
+				if tt.format == FormatText && string(data) != want || !strings.Contains(string(data), want) {
+					t.Errorf("%s holds %q, want %q", name, data, want)
+				}
+			}
+			index, _ := sink.File("sitemap-index.xml")
+			if ext := s.chunkExt(); !strings.Contains(string(index), "/in-1"+ext+"</loc>") {
+				t.Errorf("index does not list the %s chunks:\n%s", ext, index)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package sitemapsplitter
+
+import "encoding/xml"
+
+// UnmarshalXML decodes a <url> element, handing children in registered
+// extension namespaces to their ExtensionHandler. Unknown elements are
+// skipped, while attributes of <url> and <loc> are kept.
+func (u *URL) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	u.XMLName = start.Name
+	sc := nsScope(nil).declare(start.Attr)
+	u.Attrs = prefixAttrs(start.Attr, sc)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if h := lookupExtension(t.Name.Space); h != nil {
+				v, err := h.Decode(d, t)
+				if err != nil {
+					return err
+				}
+				u.Extensions = append(u.Extensions, Extension{Namespace: t.Name.Space, Value: v})
+				continue
+			}
+
+			var field *string
+			switch t.Name.Local {
+			case "loc":
+				field = &u.Loc
+				u.LocAttrs = prefixAttrs(t.Attr, sc.declare(t.Attr))
+			case "lastmod":
+				field = &u.LastMod
+			case "changefreq":
+				field = &u.ChangeFreq
+			case "priority":
+				field = &u.Priority
+			}
+			if field == nil {
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := d.DecodeElement(field, &t); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// resolvePrefixes names the attributes of u and of its raw extension elements
+// that are in a namespace declared on an enclosing element, such as the
+// <urlset> root, after their prefix in sc
+func (u *URL) resolvePrefixes(sc nsScope) {
+	u.Attrs = prefixAttrs(u.Attrs, sc)
+	u.LocAttrs = prefixAttrs(u.LocAttrs, sc)
+	for i, ext := range u.Extensions {
+		if el, ok := ext.Value.(Element); ok {
+			el.resolvePrefixes(sc)
+			u.Extensions[i].Value = el
+		}
+	}
+}
+
+// MarshalXML encodes a <url> element followed by its extension elements.
+// Namespaced attributes are declared on the element that carries them.
+func (u URL) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start = xml.StartElement{Name: xml.Name{Local: "url"}, Attr: u.Attrs}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	if err := e.EncodeElement(u.Loc, xml.StartElement{Name: xml.Name{Local: "loc"}, Attr: u.LocAttrs}); err != nil {
+		return err
+	}
+	if err := encodeFields(e, "", []field{
+		{"lastmod", u.LastMod},
+		{"changefreq", u.ChangeFreq},
+		{"priority", u.Priority},
+	}); err != nil {
+		return err
+	}
+
+	for _, ext := range u.Extensions {
+		if err := encodeExtension(e, ext); err != nil {
+			return err
+		}
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+// field is a simple child element of an entry
+type field struct {
+	name  string
+	value string
+}
+
+// encodeFields writes each non-empty field as an element named prefix plus
+// its name
+func encodeFields(e *xml.Encoder, prefix string, fields []field) error {
+	for _, f := range fields {
+		if f.value == "" {
+			continue
+		}
+		if err := e.EncodeElement(f.value, xml.StartElement{Name: xml.Name{Local: prefix + f.name}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalXML encodes the <urlset> root, declaring the namespaces of every
+// extension used by its URLs
+func (us URLSet) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start = us.startElement()
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, u := range us.URLs {
+		if err := e.Encode(u); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// startElement returns the <urlset> start tag with all namespace declarations
+func (us URLSet) startElement() xml.StartElement {
+	start := xml.StartElement{
+		Name: xml.Name{Local: "urlset"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: us.XMLNS}},
+	}
+	if us.XHTML != "" {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xmlns:xhtml"}, Value: us.XHTML})
+	}
+	start.Attr = append(start.Attr, extensionNamespaceAttrs(us.URLs)...)
+	return start
+}
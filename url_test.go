@@ -0,0 +1,70 @@
+package sitemapsplitter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// splitString splits the sitemap in and returns the content of its first
+// chunk
+func splitString(t *testing.T, in string, opts ...Option) string {
+	t.Helper()
+	dir := t.TempDir()
+	s, err := NewSitemapSplitter(filepath.Join(dir, "in.xml"), 50000, append([]Option{WithOutputDir(dir)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	out, err := os.ReadFile(filepath.Join(dir, "in-1.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestURLAttrsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{
+			name: "declared on url",
+			in:   `<url xmlns:v="urn:vendor" v:id="7"><loc v:src="cms">https://example.com/a</loc></url>`,
+			want: []string{`<url xmlns:v="urn:vendor" v:id="7">`, `<loc xmlns:v="urn:vendor" v:src="cms">`},
+		},
+		{
+			name: "declared on urlset",
+			in:   `<url acme:id="42" data-src="cms"><loc acme:canonical="true">https://example.com/a</loc></url>`,
+			want: []string{`<url xmlns:acme="urn:acme" acme:id="42" data-src="cms">`, `<loc xmlns:acme="urn:acme" acme:canonical="true">`},
+		},
+		{
+			name: "xml prefix",
+			in:   `<url xml:lang="en"><loc>https://example.com/a</loc></url>`,
+			want: []string{`<url xml:lang="en">`},
+		},
+		{
+			name: "redeclared on loc",
+			in:   `<url><loc xmlns:acme="urn:inner" acme:k="1 &amp; 2">https://example.com/a</loc></url>`,
+			want: []string{`<loc xmlns:acme="urn:inner" acme:k="1 &amp; 2">`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:acme="urn:acme">` + tt.in + `</urlset>`
+			out := splitString(t, in)
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("output lacks %s:\n%s", want, out)
+				}
+			}
+			if again := splitString(t, out); again != out {
+				t.Errorf("second pass changed the output:\n%s\nwant:\n%s", again, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package sitemapsplitter
+
+import (
+	"bufio"
+	"os"
+	"slices"
+	"strings"
+)
+
+// readURLList reads a file of URLs, one per line, into a set. Blank lines
+// and lines starting with # are ignored.
+func readURLList(path string) (map[string]struct{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	set := make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), string(utf8BOM)))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		set[line] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// prefixSet matches locs against a set of prefixes in logarithmic time
+type prefixSet []string
+
+// newPrefixSet sorts prefixes and drops those covered by a shorter prefix,
+// so that the only candidate for a loc is the greatest prefix not after it
+func newPrefixSet(prefixes []string) prefixSet {
+	sorted := slices.Clone(prefixes)
+	slices.Sort(sorted)
+	var set prefixSet
+	for _, p := range sorted {
+		if len(set) == 0 || !strings.HasPrefix(p, set[len(set)-1]) {
+			set = append(set, p)
+		}
+	}
+	return set
+}
+
+// match reports whether loc starts with one of the prefixes
+func (p prefixSet) match(loc string) bool {
+	i, found := slices.BinarySearch(p, loc)
+	if found {
+		return true
+	}
+	return i > 0 && strings.HasPrefix(loc, p[i-1])
+}
@@ -0,0 +1,56 @@
+package sitemapsplitter
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// changeFreqs lists the changefreq values allowed by the sitemap protocol
+var changeFreqs = map[string]bool{
+	"always": true, "hourly": true, "daily": true, "weekly": true,
+	"monthly": true, "yearly": true, "never": true,
+}
+
+// validateURL reports why u is not a valid sitemap entry, or nil if it is
+func (s *SitemapSplitter) validateURL(u URL) error {
+	parsed, err := url.Parse(u.Loc)
+	if err != nil {
+		return fmt.Errorf("invalid loc %q: %v", u.Loc, err)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("loc %q is not an absolute http(s) URL", u.Loc)
+	}
+
+	if u.LastMod != "" {
+		if _, ok := parseLastMod(u.LastMod, s.location); !ok {
+			return fmt.Errorf("invalid lastmod %q", u.LastMod)
+		}
+	}
+	if u.ChangeFreq != "" && !changeFreqs[strings.ToLower(u.ChangeFreq)] {
+		return fmt.Errorf("invalid changefreq %q", u.ChangeFreq)
+	}
+	if u.Priority != "" {
+		p, err := strconv.ParseFloat(u.Priority, 64)
+		if err != nil || p < 0 || p > 1 {
+			return fmt.Errorf("invalid priority %q", u.Priority)
+		}
+	}
+	return nil
+}
+
+// checkErrorRate fails the run if more than the tolerated percentage of the
+// read URLs were invalid
+func (s *SitemapSplitter) checkErrorRate(read int) error {
+	if !s.validate || read == 0 {
+		return nil
+	}
+
+	invalid := s.result.Skipped[SkipInvalid]
+	rate := float64(invalid) / float64(read) * 100
+	if rate > s.maxErrorRate {
+		return fmt.Errorf("%d of %d URLs (%.1f%%) are invalid, exceeding the tolerated %.1f%%", invalid, read, rate, s.maxErrorRate)
+	}
+	return nil
+}
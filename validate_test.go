@@ -0,0 +1,79 @@
+package sitemapsplitter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateURL(t *testing.T) {
+	tests := []struct {
+		name  string
+		url   URL
+		valid bool
+	}{
+		{"minimal", URL{Loc: "https://example.com/a"}, true},
+		{"all fields", URL{Loc: "http://example.com/a", LastMod: "2024-06-01T10:00:00+02:00", ChangeFreq: "Weekly", Priority: "0.5"}, true},
+		{"relative loc", URL{Loc: "/a"}, false},
+		{"ftp loc", URL{Loc: "ftp://example.com/a"}, false},
+		{"no host", URL{Loc: "https:///a"}, false},
+		{"bad lastmod", URL{Loc: "https://example.com/a", LastMod: "June 1st"}, false},
+		{"bad changefreq", URL{Loc: "https://example.com/a", ChangeFreq: "sometimes"}, false},
+		{"priority above 1", URL{Loc: "https://example.com/a", Priority: "1.5"}, false},
+		{"priority not a number", URL{Loc: "https://example.com/a", Priority: "high"}, false},
+	}
+	s, err := NewSitemapSplitter("in.xml", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.validateURL(tt.url); (err == nil) != tt.valid {
+				t.Errorf("validateURL returned %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestMaxErrorRate(t *testing.T) {
+	// One of four entries is invalid
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` +
+		`<url><loc>https://example.com/a</loc></url><url><loc>https://example.com/b</loc></url>` +
+		`<url><loc>https://example.com/c</loc></url><url><loc>https://example.com/d</loc><priority>2</priority></url></urlset>`
+	tests := []struct {
+		name      string
+		rate      float64
+		streaming bool
+		wantErr   bool
+	}{
+		{"at threshold", 25, false, false},
+		{"below threshold", 24.9, false, true},
+		{"none tolerated", 0, false, true},
+		{"streaming at threshold", 25, true, false},
+		{"streaming below threshold", 10, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := []Option{WithSink(NewMemorySink()), WithMaxErrorRate(tt.rate)}
+			if tt.streaming {
+				opts = append(opts, WithStreaming())
+			}
+			s, err := NewSitemapSplitter("in.xml", 10, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = s.SplitFrom(strings.NewReader(in))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("split error %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !strings.Contains(err.Error(), "1 of 4 URLs") {
+					t.Errorf("split failed with %v, want the invalid share", err)
+				}
+				return
+			}
+			if r := s.LastResult(); r.URLs != 3 || r.Skipped[SkipInvalid] != 1 {
+				t.Errorf("wrote %d URLs and dropped %d invalid, want 3 and 1", r.URLs, r.Skipped[SkipInvalid])
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package sitemapsplitter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+// ObjectInfo describes a file as stored by a sink
+type ObjectInfo struct {
+	Size   int64  // Stored size in bytes
+	SHA256 string // Hex-encoded SHA-256 digest of the content, empty if unknown
+}
+
+// StatSink is a Sink that reports the size and digest of a stored file, such
+// as an object store answering a HEAD request with checksum headers
+type StatSink interface {
+	Sink
+	Stat(name string) (ObjectInfo, error)
+}
+
+// ReadSink is a Sink that can read back the files it stored
+type ReadSink interface {
+	Sink
+	Open(name string) (io.ReadCloser, error)
+}
+
+// verifyStored checks that the file name as stored by the sink matches want.
+// A digest reported by Stat is trusted; without one the file is read back
+// when the sink allows it.
+func (s *SitemapSplitter) verifyStored(name string, want ObjectInfo) error {
+	var got ObjectInfo
+	var err error
+	ss, canStat := s.sink.(StatSink)
+	if canStat {
+		got, err = ss.Stat(name)
+	}
+	if rs, ok := s.sink.(ReadSink); ok && (!canStat || (err == nil && got.SHA256 == "")) {
+		got, err = readBack(rs, name)
+	}
+	if err != nil {
+		return fmt.Errorf("error verifying upload of %s: %v", name, err)
+	}
+
+	if got.Size != want.Size || (got.SHA256 != "" && got.SHA256 != want.SHA256) {
+		return fmt.Errorf("upload of %s does not match: stored %d bytes with digest %q, wrote %d bytes with digest %q", name, got.Size, got.SHA256, want.Size, want.SHA256)
+	}
+	return nil
+}
+
+// readBack reads the stored file name and returns its size and digest
+func readBack(rs ReadSink, name string) (ObjectInfo, error) {
+	r, err := rs.Open(name)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	defer r.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, r)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	return ObjectInfo{Size: n, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
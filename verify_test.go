@@ -0,0 +1,69 @@
+package sitemapsplitter
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// truncatingSink stores all but the last byte of each file
+type truncatingSink struct {
+	*MemorySink
+}
+
+func (t truncatingSink) Write(name string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return t.MemorySink.Write(name, strings.NewReader(string(data[:len(data)-1])))
+}
+
+// statSink reports a fixed digest for every file
+type statSink struct {
+	*MemorySink
+	digest string
+}
+
+func (s statSink) Stat(name string) (ObjectInfo, error) {
+	data, _ := s.File(name)
+	return ObjectInfo{Size: int64(len(data)), SHA256: s.digest}, nil
+}
+
+func TestUploadVerification(t *testing.T) {
+	tests := []struct {
+		name    string
+		sink    Sink
+		wantErr string
+	}{
+		{"read back", NewMemorySink(), ""},
+		{"truncated", truncatingSink{NewMemorySink()}, "does not match"},
+		{"stat digest mismatch", statSink{NewMemorySink(), "0000"}, "does not match"},
+		{"stat without digest", statSink{NewMemorySink(), ""}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			input := filepath.Join(dir, "in.xml")
+			writeSitemap(t, input, "https://example.com/a", "https://example.com/b")
+			s, err := NewSitemapSplitter(input, 1, WithSink(tt.sink), WithUploadVerification())
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = s.Split()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Split() = %v, want success", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Split() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUploadVerificationNeedsReadableSink(t *testing.T) {
+	if _, err := NewSitemapSplitter("in.xml", 1, WithUploadVerification()); err == nil {
+		t.Error("NewSitemapSplitter accepted upload verification with the file sink")
+	}
+}
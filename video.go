@@ -0,0 +1,11 @@
+package sitemapsplitter
+
+// VideoNamespace is the namespace of Google's video sitemap extension. Video
+// entries are preserved verbatim, including attributes such as
+// allow_embed or relationship, as their structure is too rich to be worth
+// typing for a split.
+const VideoNamespace = "http://www.google.com/schemas/sitemap-video/1.1"
+
+func init() {
+	RegisterExtension(RawExtension(VideoNamespace, "video"))
+}
@@ -0,0 +1,36 @@
+package sitemapsplitter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVideos(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string // Elements of the URL besides its loc
+		want []string
+	}{
+		{"fields", `<video:video><video:thumbnail_loc>https://example.com/t.jpg</video:thumbnail_loc><video:title>Grilling &amp; more</video:title><video:duration>600</video:duration></video:video>`,
+			[]string{`<video:thumbnail_loc>https://example.com/t.jpg</video:thumbnail_loc>`, `<video:title>Grilling &amp; more</video:title>`, `<video:duration>600</video:duration>`}},
+		{"attributes", `<video:video><video:player_loc allow_embed="yes">https://example.com/p</video:player_loc><video:restriction relationship="allow">IE GB</video:restriction></video:video>`,
+			[]string{`<video:player_loc allow_embed="yes">https://example.com/p</video:player_loc>`, `<video:restriction relationship="allow">IE GB</video:restriction>`}},
+		{"several videos", `<video:video><video:title>A</video:title></video:video><video:video><video:title>B</video:title></video:video>`,
+			[]string{`<video:title>A</video:title>`, `<video:title>B</video:title>`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:video="` + VideoNamespace + `"><url><loc>https://example.com/p</loc>` + tt.in + `</url></urlset>`
+			out := splitString(t, in)
+			want := append([]string{`xmlns:video="` + VideoNamespace + `"`}, tt.want...)
+			for _, w := range want {
+				if !strings.Contains(out, w) {
+					t.Errorf("output lacks %s:\n%s", w, out)
+				}
+			}
+			if again := splitString(t, out); again != out {
+				t.Errorf("second pass changed the output:\n%s\nwant:\n%s", again, out)
+			}
+		})
+	}
+}
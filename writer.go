@@ -0,0 +1,402 @@
+package sitemapsplitter
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// OutputKind selects the kinds of generated files an option applies to
+type OutputKind int
+
+const (
+	// OutputChunks covers chunk sitemaps, including the delta sitemap
+	OutputChunks OutputKind = 1 << iota
+	// OutputIndex covers the sitemap index
+	OutputIndex
+)
+
+// outputFile is a buffered output handed to the sink through a pipe, gzip
+// compressed when its name ends in .gz and encrypted with WithEncryption
+type outputFile struct {
+	pw    *io.PipeWriter
+	sent  *countingWriter // Bytes handed to the sink
+	buf   *bufio.Writer
+	enc   *encryptWriter
+	gz    *gzip.Writer
+	w     io.Writer
+	done  chan error       // Result of the sink's Write
+	name  string           // Name handed to the sink
+	sizes map[string]int64 // Receives the file's size once it is stored
+}
+
+// createOutput starts writing the file name to the sink
+func (s *SitemapSplitter) createOutput(name string) *outputFile {
+	pr, pw := io.Pipe()
+	sinkName := s.sinkName(name)
+	o := &outputFile{pw: pw, sent: &countingWriter{w: pw}, done: make(chan error, 1), name: sinkName, sizes: s.written}
+	o.buf = bufio.NewWriterSize(o.sent, 64*1024)
+	o.w = o.buf
+	if s.encryption != nil {
+		o.enc = newEncryptWriter(o.buf, s.encryption)
+		o.w = o.enc
+	}
+	if strings.HasSuffix(name, ".gz") {
+		o.gz = gzip.NewWriter(o.w)
+		o.w = o.gz
+	}
+
+	go func() {
+		if timeout := s.phaseTimeouts.Upload; timeout > 0 {
+			// The sink's reads fail once the upload runs out of time
+			t := time.AfterFunc(timeout, func() { pr.CloseWithError(phaseTimeout("upload", timeout)) })
+			defer t.Stop()
+		}
+		err := s.store(sinkName, pr)
+		if err == nil {
+			err = errSinkStopped
+		}
+		// Unblock pending writes if the sink stopped reading early
+		pr.CloseWithError(err)
+		o.done <- err
+	}()
+	return o
+}
+
+// store hands the file name to the sink, or reads and discards it in a dry
+// run so that its size is still known
+func (s *SitemapSplitter) store(name string, r io.Reader) error {
+	if s.dryRun {
+		_, err := io.Copy(io.Discard, r)
+		return err
+	}
+
+	var digest hash.Hash
+	var sent *countingWriter
+	if s.verifyUploads {
+		digest = sha256.New()
+		sent = &countingWriter{w: digest}
+		r = io.TeeReader(r, sent)
+	}
+
+	started := time.Now()
+	if err := s.sink.Write(name, r); err != nil {
+		if !errors.Is(err, errOutputAborted) {
+			s.logger.Warn("sink write failed", "phase", "upload", "name", name, "error", err)
+		}
+		return err
+	}
+	if digest != nil {
+		if err := s.verifyStored(name, ObjectInfo{Size: sent.n, SHA256: hex.EncodeToString(digest.Sum(nil))}); err != nil {
+			s.logger.Warn("upload verification failed", "phase", "upload", "name", name, "error", err)
+			return err
+		}
+	}
+	s.logger.Debug("stored file", "phase", "upload", "name", name, "duration", time.Since(started))
+	return nil
+}
+
+// sinkName returns the name the output name is stored under by the sink
+func (s *SitemapSplitter) sinkName(name string) string {
+	if s.encryption != nil {
+		return name + EncryptedSuffix
+	}
+	return name
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// errSinkStopped is returned to writes made after the sink finished reading
+var errSinkStopped = errors.New("sink stopped reading before the end of the file")
+
+func (o *outputFile) Write(p []byte) (int, error) {
+	return o.w.Write(p)
+}
+
+// Close flushes all buffered data and waits for the sink to store the file
+func (o *outputFile) Close() error {
+	if o.gz != nil {
+		if err := o.gz.Close(); err != nil {
+			o.abort()
+			return err
+		}
+	}
+	if o.enc != nil {
+		if err := o.enc.Close(); err != nil {
+			o.abort()
+			return err
+		}
+	}
+	if err := o.buf.Flush(); err != nil {
+		o.abort()
+		return err
+	}
+	o.pw.Close()
+	if err := <-o.done; err != errSinkStopped {
+		return err
+	}
+	if o.sizes != nil {
+		o.sizes[o.name] = o.sent.n
+	}
+	return nil
+}
+
+// abort tells the sink the file is incomplete and waits for it to give up
+func (o *outputFile) abort() {
+	o.pw.CloseWithError(errOutputAborted)
+	<-o.done
+}
+
+// errOutputAborted is the read error a sink sees for an incomplete file
+var errOutputAborted = errors.New("output aborted")
+
+// writeURLSet streams urlset to the sink as each of names. URLs are encoded
+// one at a time straight into buffered writers rather than marshaling the
+// whole document in memory first, and a single encoding pass feeds every
+// file (plain and gzip variants alike).
+func (s *SitemapSplitter) writeURLSet(urlset URLSet, names ...string) error {
+	return s.writeOutputs(names, func(w io.Writer) error {
+		return s.encodeURLSet(w, urlset)
+	})
+}
+
+// writeOutputs streams what encode writes to the sink as each of names
+func (s *SitemapSplitter) writeOutputs(names []string, encode func(io.Writer) error) error {
+	outputs := s.createOutputs(names)
+	if err := encode(multiOutput(outputs)); err != nil {
+		abortOutputs(outputs)
+		return err
+	}
+	return closeOutputs(outputs)
+}
+
+// createOutputs starts an output for each of names
+func (s *SitemapSplitter) createOutputs(names []string) []*outputFile {
+	outputs := make([]*outputFile, len(names))
+	for i, name := range names {
+		outputs[i] = s.createOutput(name)
+	}
+	return outputs
+}
+
+// multiOutput returns a writer duplicating its writes to all outputs
+func multiOutput(outputs []*outputFile) io.Writer {
+	writers := make([]io.Writer, len(outputs))
+	for i, o := range outputs {
+		writers[i] = o
+	}
+	return io.MultiWriter(writers...)
+}
+
+// closeOutputs flushes and closes all outputs, reporting the first error
+func closeOutputs(outputs []*outputFile) error {
+	var first error
+	for _, o := range outputs {
+		if err := o.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// abortOutputs discards all outputs without completing them
+func abortOutputs(outputs []*outputFile) {
+	for _, o := range outputs {
+		o.abort()
+	}
+}
+
+// urlsetStream writes a urlset document one URL at a time, for outputs whose
+// URLs are not known before writing starts. Because the extensions used are
+// not known either, every registered extension namespace is declared.
+type urlsetStream struct {
+	s       *SitemapSplitter
+	outputs []*outputFile
+	w       io.Writer
+	buf     bytes.Buffer
+	n       int // URLs written
+}
+
+// createURLSetStream starts the files names and writes the XML header and
+// <urlset> start tag to them
+func (s *SitemapSplitter) createURLSetStream(names ...string) (*urlsetStream, error) {
+	outputs := s.createOutputs(names)
+	st := &urlsetStream{s: s, outputs: outputs, w: multiOutput(outputs)}
+
+	start := newURLSet(nil).startElement()
+	start.Attr = append(start.Attr, registeredNamespaceAttrs()...)
+	if err := s.writeHeader(st.w, OutputChunks); err != nil {
+		abortOutputs(outputs)
+		return nil, err
+	}
+	if err := writeStartTag(st.w, start); err != nil {
+		abortOutputs(outputs)
+		return nil, err
+	}
+	return st, nil
+}
+
+// add writes u to the stream
+func (st *urlsetStream) add(u URL) error {
+	st.buf.Reset()
+	st.buf.WriteByte('\n')
+	if err := st.s.encodeURL(&st.buf, u); err != nil {
+		return err
+	}
+	st.n++
+	_, err := st.w.Write(st.buf.Bytes())
+	return err
+}
+
+// close ends the document and closes its files
+func (st *urlsetStream) close() error {
+	end := "</urlset>"
+	if st.n > 0 {
+		end = "\n" + end
+	}
+	if _, err := io.WriteString(st.w, end); err != nil {
+		abortOutputs(st.outputs)
+		return err
+	}
+	return closeOutputs(st.outputs)
+}
+
+// encodeURLSet writes the XML header and urlset to w. Each URL is encoded on
+// its own into a small buffer, so memory use is bounded by the largest URL
+// entry rather than by the chunk.
+func (s *SitemapSplitter) encodeURLSet(w io.Writer, urlset URLSet) error {
+	if err := s.writeHeader(w, OutputChunks); err != nil {
+		return err
+	}
+
+	start := urlset.startElement()
+	if err := writeStartTag(w, start); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for _, u := range urlset.URLs {
+		buf.Reset()
+		buf.WriteByte('\n')
+		if err := s.encodeURL(&buf, u); err != nil {
+			return err
+		}
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	end := "</urlset>"
+	if len(urlset.URLs) > 0 {
+		end = "\n" + end
+	}
+	_, err := io.WriteString(w, end)
+	return err
+}
+
+// encodeURL writes a single indented <url> element to buf
+func (s *SitemapSplitter) encodeURL(buf *bytes.Buffer, u URL) error {
+	offset := buf.Len()
+
+	enc := xml.NewEncoder(buf)
+	enc.Indent("  ", "  ")
+	if err := enc.Encode(u); err != nil {
+		return err
+	}
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+
+	if s.selfClosing {
+		collapsed := emptyElement.ReplaceAllFunc(buf.Bytes()[offset:], selfClose)
+		buf.Truncate(offset)
+		buf.Write(collapsed)
+	}
+	return nil
+}
+
+// emptyElement matches an open tag immediately followed by a close tag, as
+// xml.Encoder writes elements without content
+var emptyElement = regexp.MustCompile(`<([^\s>/]+)([^>]*)></([^\s>/]+)>`)
+
+// selfClose rewrites a matched empty element to the self-closing form
+func selfClose(m []byte) []byte {
+	sub := emptyElement.FindSubmatch(m)
+	if !bytes.Equal(sub[1], sub[3]) {
+		return m
+	}
+	out := append([]byte("<"), sub[1]...)
+	out = append(out, sub[2]...)
+	return append(out, "/>"...)
+}
+
+// writeStartTag writes start as an open tag
+func writeStartTag(w io.Writer, start xml.StartElement) error {
+	var buf bytes.Buffer
+	buf.WriteString("<" + start.Name.Local)
+	for _, attr := range start.Attr {
+		buf.WriteString(" " + attr.Name.Local + `="`)
+		if err := xml.EscapeText(&buf, []byte(attr.Value)); err != nil {
+			return err
+		}
+		buf.WriteByte('"')
+	}
+	buf.WriteByte('>')
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeXMLFile marshals the small document v and writes it to the sink as
+// name, gzip compressed when name ends in .gz
+func (s *SitemapSplitter) writeXMLFile(name string, kind OutputKind, v interface{}) error {
+	o := s.createOutput(name)
+	if err := s.encodeXMLFile(o, kind, v); err != nil {
+		o.abort()
+		return err
+	}
+	return o.Close()
+}
+
+// encodeXMLFile writes the XML header and the small document v to w
+func (s *SitemapSplitter) encodeXMLFile(w io.Writer, kind OutputKind, v interface{}) error {
+	xmlData, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling XML: %v", err)
+	}
+
+	if err := s.writeHeader(w, kind); err != nil {
+		return err
+	}
+	_, err = w.Write(xmlData)
+	return err
+}
+
+// writeHeader writes the XML declaration unless it is suppressed for kind
+func (s *SitemapSplitter) writeHeader(w io.Writer, kind OutputKind) error {
+	if s.omitHeader&kind != 0 {
+		return nil
+	}
+	_, err := io.WriteString(w, xml.Header)
+	return err
+}
@@ -0,0 +1,247 @@
+package sitemapsplitter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEncodeURLSetMatchesMarshalIndent(t *testing.T) {
+	tests := []struct {
+		name string
+		urls []URL
+	}{
+		{"empty", nil},
+		{"plain", []URL{{Loc: "https://example.com/a", LastMod: "2024-06-01"}, {Loc: "https://example.com/b"}}},
+		{"all fields", []URL{{Loc: "https://example.com/a", LastMod: "2024-06-01", ChangeFreq: "daily", Priority: "0.8"}}},
+		{"escaping", []URL{{Loc: "https://example.com/?a=1&b=<2>"}}},
+		{"image", []URL{{
+			Loc:        "https://example.com/a",
+			Extensions: []Extension{{Namespace: ImageNamespace, Value: Image{Loc: "https://example.com/a.jpg", Title: "A & B"}}},
+		}}},
+	}
+	s, err := NewSitemapSplitter("in.xml", 50000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			urlset := newURLSet(tt.urls)
+			want, err := xml.MarshalIndent(urlset, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got bytes.Buffer
+			if err := s.encodeURLSet(&got, urlset); err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != xml.Header+string(want) {
+				t.Errorf("encoded\n%s\nwant\n%s", got.String(), xml.Header+string(want))
+			}
+		})
+	}
+}
+
+func TestDualOutput(t *testing.T) {
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc></url><url><loc>https://example.com/b</loc></url></urlset>`
+	tests := []struct {
+		name       string
+		preferGzip bool
+		streaming  bool
+		wantLoc    string // Index entry of the first chunk
+	}{
+		{"plain", false, false, "https://example.com/in-1.xml"},
+		{"gzip", true, false, "https://example.com/in-1.xml.gz"},
+		{"streaming", true, true, "https://example.com/in-1.xml.gz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := NewMemorySink()
+			opts := []Option{WithSink(sink), WithDualOutput(tt.preferGzip)}
+			if tt.streaming {
+				opts = append(opts, WithStreaming())
+			}
+			s, err := NewSitemapSplitter("in.xml", 1, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, name := range []string{"in-1.xml", "in-2.xml"} {
+				plain, ok := sink.File(name)
+				if !ok {
+					t.Fatalf("%s not written", name)
+				}
+				compressed, ok := sink.File(name + ".gz")
+				if !ok {
+					t.Fatalf("%s.gz not written", name)
+				}
+				zr, err := gzip.NewReader(bytes.NewReader(compressed))
+				if err != nil {
+					t.Fatal(err)
+				}
+				unzipped, err := io.ReadAll(zr)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(unzipped, plain) {
+					t.Errorf("%s.gz differs from %s", name, name)
+				}
+			}
+			index, _ := sink.File("sitemap-index.xml")
+			if !strings.Contains(string(index), "<loc>"+tt.wantLoc+"</loc>") {
+				t.Errorf("index does not point at %s:\n%s", tt.wantLoc, index)
+			}
+		})
+	}
+}
+
+func TestWithoutXMLDeclaration(t *testing.T) {
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc></url></urlset>`
+	tests := []struct {
+		name      string
+		kinds     OutputKind
+		chunk     bool // Chunks keep the declaration
+		index     bool // The index keeps the declaration
+		streaming bool
+	}{
+		{"default", 0, true, true, false},
+		{"chunks", OutputChunks, false, true, false},
+		{"index", OutputIndex, true, false, false},
+		{"both", OutputChunks | OutputIndex, false, false, false},
+		{"streaming", OutputChunks | OutputIndex, false, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := NewMemorySink()
+			opts := []Option{WithSink(sink), WithStateFile(filepath.Join(t.TempDir(), "state.json")), WithDeltaSitemap("")}
+			if tt.kinds != 0 {
+				opts = append(opts, WithoutXMLDeclaration(tt.kinds))
+			}
+			if tt.streaming {
+				opts = append(opts, WithStreaming())
+			}
+			s, err := NewSitemapSplitter("in.xml", 50000, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+				t.Fatal(err)
+			}
+
+			for name, want := range map[string]bool{"in-1.xml": tt.chunk, "changed.xml": tt.chunk, "sitemap-index.xml": tt.index} {
+				data, _ := sink.File(name)
+				if got := strings.HasPrefix(string(data), "<?xml"); got != want {
+					t.Errorf("%s has a declaration: %v, want %v", name, got, want)
+				}
+				if !strings.Contains(string(data), "https://example.com/") {
+					t.Errorf("%s lost its content:\n%s", name, data)
+				}
+			}
+		})
+	}
+}
+
+func TestSelfClosingTags(t *testing.T) {
+	const mobile = "http://www.google.com/schemas/sitemap-mobile/1.0"
+	withElement := func(el Element) URL {
+		u := URL{Loc: "https://example.com/a"}
+		u.AddElement(el)
+		return u
+	}
+	tests := []struct {
+		name      string
+		url       URL
+		selfClose bool
+		want      string
+	}{
+		{"expanded", withElement(Element{Namespace: mobile, Prefix: "mobile", Name: "mobile"}), false, "<mobile:mobile></mobile:mobile>"},
+		{"self-closing", withElement(Element{Namespace: mobile, Prefix: "mobile", Name: "mobile"}), true, "<mobile:mobile/>"},
+		{"attributes", withElement(Element{Namespace: mobile, Prefix: "mobile", Name: "mobile", Attrs: []xml.Attr{{Name: xml.Name{Local: "type"}, Value: "pc,mobile"}}}), true, `<mobile:mobile type="pc,mobile"/>`},
+		{"content kept", withElement(Element{Namespace: mobile, Prefix: "mobile", Name: "mobile", Text: "yes"}), true, "<mobile:mobile>yes</mobile:mobile>"},
+		{"escaped text", URL{Loc: "https://example.com/?q=<b></b>"}, true, "<loc>https://example.com/?q=&lt;b&gt;&lt;/b&gt;</loc>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if tt.selfClose {
+				opts = append(opts, WithSelfClosingTags())
+			}
+			s, err := NewSitemapSplitter("in.xml", 50000, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := s.encodeURL(&buf, tt.url); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("encoded URL lacks %s:\n%s", tt.want, buf.String())
+			}
+		})
+	}
+}
+
+func TestGzipOutput(t *testing.T) {
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://example.com/a</loc></url><url><loc>https://example.com/b</loc></url></urlset>`
+	tests := []struct {
+		name      string
+		delta     string
+		streaming bool
+		want      []string // Stored names
+		plain     []string // Stored names that are not compressed
+	}{
+		{"chunks and index", "changed.xml", false, []string{"changed.xml", "in-1.xml.gz", "in-2.xml.gz", "sitemap-index.xml.gz"}, []string{"changed.xml"}},
+		{"compressed delta", "changed.xml.gz", false, []string{"changed.xml.gz", "in-1.xml.gz", "in-2.xml.gz", "sitemap-index.xml.gz"}, nil},
+		{"streaming", "changed.xml.gz", true, []string{"changed.xml.gz", "in-1.xml.gz", "in-2.xml.gz", "sitemap-index.xml.gz"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := NewMemorySink()
+			opts := []Option{WithSink(sink), WithGzipOutput(true), WithStateFile(filepath.Join(t.TempDir(), "state.json")), WithDeltaSitemap(tt.delta)}
+			if tt.streaming {
+				opts = append(opts, WithStreaming())
+			}
+			s, err := NewSitemapSplitter("in.xml", 1, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := sink.Names(); !slices.Equal(got, tt.want) {
+				t.Fatalf("sink stored %v, want %v", got, tt.want)
+			}
+			for _, name := range tt.want {
+				if slices.Contains(tt.plain, name) {
+					continue
+				}
+				data, _ := sink.File(name)
+				zr, err := gzip.NewReader(bytes.NewReader(data))
+				if err != nil {
+					t.Fatalf("%s is not gzip compressed: %v", name, err)
+				}
+				content, err := io.ReadAll(zr)
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+				if name == "sitemap-index.xml.gz" && !strings.Contains(string(content), "<loc>https://example.com/in-1.xml.gz</loc>") {
+					t.Errorf("index does not point at the compressed chunks:\n%s", content)
+				}
+			}
+			for _, name := range tt.plain {
+				if data, _ := sink.File(name); !strings.HasPrefix(string(data), "<?xml") {
+					t.Errorf("%s is not plain XML", name)
+				}
+			}
+		})
+	}
+}
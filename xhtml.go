@@ -0,0 +1,163 @@
+package sitemapsplitter
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// XHTMLNamespace is the namespace of <xhtml:link> elements, which describe
+// alternate versions of a page such as its translations. Every urlset
+// declares it.
+const XHTMLNamespace = "http://www.w3.org/1999/xhtml"
+
+// Alternate is an <xhtml:link> element, usually rel="alternate" with the
+// hreflang of a translated version of the page at Href
+type Alternate struct {
+	Rel      string `json:"rel"`
+	Hreflang string `json:"hreflang,omitempty"`
+	Media    string `json:"media,omitempty"`
+	Href     string `json:"href"`
+}
+
+func init() {
+	RegisterExtension(xhtmlExtension{})
+}
+
+// AddAlternate attaches an alternate link to the URL
+func (u *URL) AddAlternate(alt Alternate) {
+	u.Extensions = append(u.Extensions, Extension{Namespace: XHTMLNamespace, Value: alt})
+}
+
+// Alternates returns the alternate links of the URL
+func (u URL) Alternates() []Alternate {
+	var alternates []Alternate
+	for _, ext := range u.Extensions {
+		if alt, ok := ext.Value.(Alternate); ok {
+			alternates = append(alternates, alt)
+		}
+	}
+	return alternates
+}
+
+// xhtmlExtension decodes <xhtml:link> elements into Alternate values. Other
+// elements of the namespace are preserved verbatim.
+type xhtmlExtension struct{}
+
+func (xhtmlExtension) Namespace() string { return XHTMLNamespace }
+
+func (xhtmlExtension) Prefix() string { return "xhtml" }
+
+func (xhtmlExtension) Decode(d *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	if start.Name.Local != "link" {
+		return RawExtension(XHTMLNamespace, "xhtml").Decode(d, start)
+	}
+
+	var alt Alternate
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "rel":
+			alt.Rel = attr.Value
+		case "hreflang":
+			alt.Hreflang = attr.Value
+		case "media":
+			alt.Media = attr.Value
+		case "href":
+			alt.Href = attr.Value
+		}
+	}
+	if err := d.Skip(); err != nil {
+		return nil, err
+	}
+	return alt, nil
+}
+
+func (xhtmlExtension) Encode(e *xml.Encoder, v interface{}) error {
+	alt, ok := v.(Alternate)
+	if !ok {
+		return fmt.Errorf("unexpected %T value for namespace %q", v, XHTMLNamespace)
+	}
+
+	start := xml.StartElement{Name: xml.Name{Local: "xhtml:link"}}
+	for _, attr := range []xml.Attr{
+		{Name: xml.Name{Local: "rel"}, Value: alt.Rel},
+		{Name: xml.Name{Local: "hreflang"}, Value: alt.Hreflang},
+		{Name: xml.Name{Local: "media"}, Value: alt.Media},
+		{Name: xml.Name{Local: "href"}, Value: alt.Href},
+	} {
+		if attr.Value != "" {
+			start.Attr = append(start.Attr, attr)
+		}
+	}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+// alternateGroups orders urls so that URLs linked to each other through
+// alternate links are adjacent and returns the end index of each such
+// cluster. Clusters appear in the order of their first URL and keep the
+// input order among their members. Without WithAlternateGrouping every URL
+// is a cluster of its own.
+func (s *SitemapSplitter) alternateGroups(urls []URL) ([]URL, []int) {
+	ends := make([]int, 0, len(urls))
+	if !s.clusterAlts {
+		for i := range urls {
+			ends = append(ends, i+1)
+		}
+		return urls, ends
+	}
+
+	index := make(map[string]int, len(urls))
+	for i, u := range urls {
+		if _, ok := index[u.Loc]; !ok {
+			index[u.Loc] = i
+		}
+	}
+
+	parent := make([]int, len(urls))
+	for i := range parent {
+		parent[i] = i
+	}
+	find := func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+	for i, u := range urls {
+		for _, alt := range u.Alternates() {
+			j, ok := index[alt.Href]
+			if !ok {
+				continue
+			}
+			// The smaller index becomes the root so that a cluster's root
+			// is its first URL
+			a, b := find(i), find(j)
+			if a > b {
+				a, b = b, a
+			}
+			parent[b] = a
+		}
+	}
+
+	members := make(map[int][]int)
+	var roots []int
+	for i := range urls {
+		root := find(i)
+		if _, ok := members[root]; !ok {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], i)
+	}
+
+	ordered := make([]URL, 0, len(urls))
+	for _, root := range roots {
+		for _, i := range members[root] {
+			ordered = append(ordered, urls[i])
+		}
+		ends = append(ends, len(ordered))
+	}
+	return ordered, ends
+}
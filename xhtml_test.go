@@ -0,0 +1,50 @@
+package sitemapsplitter
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAlternatesFollowLocRewrites(t *testing.T) {
+	link := func(lang, href string) string {
+		return `<xhtml:link rel="alternate" hreflang="` + lang + `" href="` + href + `"/>`
+	}
+	in := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:xhtml="` + XHTMLNamespace + `">` +
+		`<url><loc>https://www.example.com/solo</loc></url>` +
+		`<url><loc>https://www.example.com/EN</loc>` + link("de", "https://www.example.com/DE") + `</url>` +
+		`<url><loc>https://www.example.com/DE</loc>` + link("en", "https://www.example.com/EN") + `</url></urlset>`
+
+	tests := []struct {
+		name string
+		opts []Option
+		want string // Href the German alternate of /en is written with
+	}{
+		{"canonical host", []Option{WithCanonicalHost("example.com")}, "https://example.com/DE"},
+		{"canonical host and lowercase paths", []Option{WithCanonicalHost("example.com"), WithLowercasePaths()}, "https://example.com/de"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := NewMemorySink()
+			opts := append([]Option{WithSink(sink), WithAlternateGrouping(), WithHreflangValidation()}, tt.opts...)
+			s, err := NewSitemapSplitter(filepath.Join(t.TempDir(), "in.xml"), 2, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SplitFrom(strings.NewReader(in)); err != nil {
+				t.Fatal(err)
+			}
+
+			second, _ := sink.File("in-2.xml")
+			if !strings.Contains(string(second), `href="`+tt.want+`"`) {
+				t.Errorf("alternate href not rewritten to %s:\n%s", tt.want, second)
+			}
+			if strings.Count(string(second), "<loc>") != 2 {
+				t.Errorf("alternates were split across chunks, second chunk:\n%s", second)
+			}
+			if n := s.LastResult().WarningCounts[WarnHreflangNoReturn]; n != 0 {
+				t.Errorf("%d return link warnings for reciprocal alternates", n)
+			}
+		})
+	}
+}